@@ -0,0 +1,258 @@
+// Package bidi implements the Unicode Bidirectional Algorithm (UAX #9),
+// as described in https://www.unicode.org/reports/tr9/.
+//
+// It resolves the embedding levels of a paragraph of text and
+// splits lines into directional runs, in visual order, which may
+// then be shaped separately (see `Run.Direction`).
+package bidi
+
+import (
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textlayout/unicodedata"
+	xbidi "golang.org/x/text/unicode/bidi"
+)
+
+// MaxDepth is the maximum explicit embedding level (BD2).
+const MaxDepth = 125
+
+// Level is an embedding level: even levels are
+// left-to-right, odd levels are right-to-left.
+type Level uint8
+
+// IsRTL returns true for odd levels.
+func (l Level) IsRTL() bool { return l&1 == 1 }
+
+// Direction returns the horizontal direction matching the level.
+func (l Level) Direction() harfbuzz.Direction {
+	if l.IsRTL() {
+		return harfbuzz.RightToLeft
+	}
+	return harfbuzz.LeftToRight
+}
+
+// Direction is the base direction of a paragraph.
+type Direction uint8
+
+const (
+	// Auto resolves the paragraph direction from its first
+	// strong character (rules P2 and P3), defaulting to left to right.
+	Auto Direction = iota
+	LeftToRight
+	RightToLeft
+)
+
+// Run is a sequence of characters with the same
+// embedding level.
+type Run struct {
+	Start, End int // indices of the run in the paragraph text
+	Level      Level
+}
+
+// Direction returns the direction to use when shaping the run,
+// that is the value of `harfbuzz.SegmentProperties.Direction`.
+func (r Run) Direction() harfbuzz.Direction { return r.Level.Direction() }
+
+// Paragraph stores the embedding levels resolved for a paragraph.
+//
+// Following rule P1, if the text contains paragraph separators
+// (other than a final one), each paragraph is resolved independently
+// (with the same requested direction), and `Level` reports the level of the first one.
+type Paragraph struct {
+	text    []rune
+	classes []xbidi.Class // original classes
+	levels  []Level       // resolved levels, without rule L1
+
+	// end index and base level of each paragraph
+	parts []paragraphPart
+}
+
+type paragraphPart struct {
+	end   int
+	level Level
+}
+
+// NewParagraph resolves the embedding levels of `text`,
+// using `dir` as base direction.
+func NewParagraph(text []rune, dir Direction) *Paragraph {
+	classes := make([]xbidi.Class, len(text))
+	brackets := make([]bracket, len(text))
+	for i, r := range text {
+		props, _ := xbidi.LookupRune(r)
+		classes[i] = props.Class()
+		brackets[i] = lookupBracket(r, props)
+	}
+	out := newParagraphFromClasses(classes, brackets, dir)
+	out.text = text
+	return out
+}
+
+// newParagraphFromClasses splits the input in paragraphs and resolves each of them.
+func newParagraphFromClasses(classes []xbidi.Class, brackets []bracket, dir Direction) *Paragraph {
+	out := &Paragraph{classes: classes, levels: make([]Level, len(classes))}
+	start := 0
+	for i, c := range classes {
+		if c == xbidi.B || i == len(classes)-1 {
+			level := resolveParagraph(classes[start:i+1], brackets[start:i+1], dir, out.levels[start:i+1])
+			out.parts = append(out.parts, paragraphPart{end: i + 1, level: level})
+			start = i + 1
+		}
+	}
+	if len(out.parts) == 0 { // empty text
+		out.parts = append(out.parts, paragraphPart{level: defaultLevel(dir)})
+	}
+	return out
+}
+
+// Text returns the text of the paragraph.
+func (p *Paragraph) Text() []rune { return p.text }
+
+// Level returns the paragraph embedding level.
+func (p *Paragraph) Level() Level { return p.parts[0].level }
+
+// Levels returns the resolved embedding levels, one for each input character,
+// before applying the line level rule L1 (see `LineLevels`).
+// Characters removed by rule X9 are given the level of the preceding character,
+// or the paragraph level.
+// The returned slice must not be modified.
+func (p *Paragraph) Levels() []Level { return p.levels }
+
+// paragraphLevel returns the base level of the paragraph containing the index `i`.
+func (p *Paragraph) paragraphLevel(i int) Level {
+	for _, part := range p.parts {
+		if i < part.end {
+			return part.level
+		}
+	}
+	return p.parts[len(p.parts)-1].level
+}
+
+// LineLevels returns the levels of the line text[start:end],
+// after applying rule L1.
+func (p *Paragraph) LineLevels(start, end int) []Level {
+	levels := append([]Level(nil), p.levels[start:end]...)
+
+	// trailing whitespaces (and isolate controls, and characters
+	// removed by X9) are reset to the paragraph level, starting with the line end
+	trailing := true
+	for i := end - 1; i >= start; i-- {
+		switch c := p.classes[i]; {
+		case c == xbidi.B || c == xbidi.S:
+			levels[i-start] = p.paragraphLevel(i)
+			trailing = true
+		case trailing && (c == xbidi.WS || isIsolateControl(c) || isRemovedByX9(c)):
+			levels[i-start] = p.paragraphLevel(i)
+		default:
+			trailing = false
+		}
+	}
+	return levels
+}
+
+// VisualOrder applies rule L2 to the line text[start:end] and
+// returns the logical index (relative to `start`) of each character, in visual order.
+func (p *Paragraph) VisualOrder(start, end int) []int {
+	levels := p.LineLevels(start, end)
+	order := make([]int, len(levels))
+	for i := range order {
+		order[i] = i
+	}
+	reorder(levels, func(i, j int) { order[i], order[j] = order[j], order[i] })
+	return order
+}
+
+// Runs returns the directional runs of the line text[start:end],
+// in visual order (from left to right). The characters inside
+// right-to-left runs are stored in logical order, so that
+// each run may be directly used as `harfbuzz.Buffer` input, with
+// its `Direction`.
+func (p *Paragraph) Runs(start, end int) []Run {
+	levels := p.LineLevels(start, end)
+	var runs []Run
+	for i, level := range levels {
+		if len(runs) != 0 && runs[len(runs)-1].Level == level {
+			runs[len(runs)-1].End = start + i + 1
+			continue
+		}
+		runs = append(runs, Run{Start: start + i, End: start + i + 1, Level: level})
+	}
+
+	runLevels := make([]Level, len(runs))
+	for i, run := range runs {
+		runLevels[i] = run.Level
+	}
+	reorder(runLevels, func(i, j int) { runs[i], runs[j] = runs[j], runs[i] })
+	return runs
+}
+
+// reorder implements rule L2: from the highest level down to the lowest odd level,
+// each maximal sequence of items at that level or higher is reversed.
+// `swap` is called to reverse the items, in addition to `levels`.
+func reorder(levels []Level, swap func(i, j int)) {
+	if len(levels) == 0 {
+		return
+	}
+	highest, lowestOdd := Level(0), Level(MaxDepth+2)
+	for _, l := range levels {
+		if l > highest {
+			highest = l
+		}
+		if l.IsRTL() && l < lowestOdd {
+			lowestOdd = l
+		}
+	}
+
+	for level := highest; level >= lowestOdd; level-- {
+		for i := 0; i < len(levels); i++ {
+			if levels[i] < level {
+				continue
+			}
+			start := i
+			for i < len(levels) && levels[i] >= level {
+				i++
+			}
+			for a, b := start, i-1; a < b; a, b = a+1, b-1 {
+				levels[a], levels[b] = levels[b], levels[a]
+				swap(a, b)
+			}
+		}
+	}
+}
+
+type bracketType uint8
+
+const (
+	bracketNone bracketType = iota
+	bracketOpen
+	bracketClose
+)
+
+// bracket stores the Bidi_Paired_Bracket_Type property,
+// and an identifier common to the two brackets of a pair
+type bracket struct {
+	id  rune
+	typ bracketType
+}
+
+// canonicalBracket handles the two brackets which are
+// canonically equivalent to other ones (see BD16).
+func canonicalBracket(r rune) rune {
+	switch r {
+	case 0x2329:
+		return 0x3008
+	case 0x232A:
+		return 0x3009
+	}
+	return r
+}
+
+func lookupBracket(r rune, props xbidi.Properties) bracket {
+	if !props.IsBracket() {
+		return bracket{}
+	}
+	if props.IsOpeningBracket() {
+		return bracket{id: canonicalBracket(r), typ: bracketOpen}
+	}
+	// Bidi_Paired_Bracket is the same as Bidi_Mirroring_Glyph
+	opening, _ := unicodedata.LookupMirrorChar(r)
+	return bracket{id: canonicalBracket(opening), typ: bracketClose}
+}
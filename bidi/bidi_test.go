@@ -0,0 +1,319 @@
+package bidi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	xbidi "golang.org/x/text/unicode/bidi"
+)
+
+// The official conformance files are not part of the repository: they must be
+// downloaded from https://www.unicode.org/Public/13.0.0/ucd/BidiTest.txt and
+// https://www.unicode.org/Public/13.0.0/ucd/BidiCharacterTest.txt and
+// stored in the testdata directory (see .github/workflows/test.yml).
+// Without them, the conformance tests are skipped, and only the (small)
+// samples below, using the same format, are checked.
+
+const bidiTestSample = `
+# classes test
+@Levels:	x
+@Reorder:	
+LRE; 7
+PDF; 7
+BN; 7
+
+@Levels:	x x x
+@Reorder:	
+LRE PDF BN; 7
+
+@Levels:	0
+@Reorder:	0
+L; 3
+EN; 3
+ON; 3
+WS; 3
+S; 3
+B; 3
+
+@Levels:	1
+@Reorder:	0
+R; 7
+AL; 7
+ON; 4
+WS; 4
+
+@Levels:	2
+@Reorder:	0
+L; 4
+EN; 4
+AN; 7
+
+@Levels:	0 1 1 1 0
+@Reorder:	0 3 2 1 4
+L R R R L; 3
+
+@Levels:	0 0 1 0 0
+@Reorder:	0 1 2 3 4
+L RLI R PDI L; 3
+
+@Levels:	1 1 2
+@Reorder:	2 1 0
+R WS L; 5
+
+@Levels:	x 2 2 x 0
+@Reorder:	1 2 4
+RLE L L PDF WS; 3
+`
+
+const bidiCharacterTestSample = `
+# text; direction; paragraph level; levels; order
+0061 0062 0063 0020 05D0 05D1 05D2 0020 0064 0065 0066;0;0;0 0 0 0 1 1 1 0 0 0 0;0 1 2 3 6 5 4 7 8 9 10
+0061 0062 0063 0020 05D0 05D1 05D2 0020 0064 0065 0066;1;1;2 2 2 1 1 1 1 1 2 2 2;8 9 10 7 6 5 4 3 0 1 2
+05D0 05D1 05D2 0020 0028 0061 0062 0063 0029 0020 05D3 05D4 05D5;2;1;1 1 1 1 1 2 2 2 1 1 1 1 1;12 11 10 9 8 5 6 7 4 3 2 1 0
+05D0 05D1 05D2 0020 0028 0061 0062 0063 0029 0020 05D3 05D4 05D5;0;0;1 1 1 0 0 0 0 0 0 0 1 1 1;2 1 0 3 4 5 6 7 8 9 12 11 10
+0061 0020 0028 05D1 0029 0020 0063;2;0;0 0 0 1 0 0 0;0 1 2 3 4 5 6
+0061 0020 0028 05D1 0029 0020 0063;1;1;2 1 1 1 1 1 2;6 5 4 3 2 1 0
+0628 0028 0061 0029 0628;2;1;1 1 2 1 1;4 3 2 1 0
+0628 0028 0061 0029 0628;0;0;1 0 0 0 1;0 1 2 3 4
+05D0 05D1 05D2 0020 0031 0032 0033 002C 0020 0034 0035 0036 0020 0061 0062 0063;2;1;1 1 1 1 2 2 2 1 1 2 2 2 1 2 2 2;13 14 15 12 9 10 11 8 7 4 5 6 3 2 1 0
+0623 0020 0031 0032 002E 0035 0020 00A0 0025;2;1;1 1 2 2 2 2 1 1 1;8 7 6 2 3 4 5 1 0
+202B 0061 0062 0063 202C 0020 0064 0065 0066;1;1;x 4 4 4 x 1 2 2 2;6 7 8 5 1 2 3
+2067 0061 0062 0063 2069 0020 05D0 05D1 05D2;0;0;0 2 2 2 0 0 1 1 1;0 1 2 3 4 5 8 7 6
+2068 05D0 05D1 05D2 2069 0020 0061 0062 0063;1;1;1 3 3 3 1 1 2 2 2;6 7 8 5 4 3 2 1 0
+0061 0009 05D1 0020 0063 0020 0020;1;1;2 1 1 1 2 1 1;6 5 4 3 2 1 0
+0061 0062 0063 2029 05D0 05D1 05D2;2;0;0 0 0 0 1 1 1;0 1 2 3 6 5 4
+`
+
+// openTestFile opens the given conformance file, skipping the test
+// if it has not been downloaded
+func openTestFile(t *testing.T, name string) io.Reader {
+	f, err := os.Open("testdata/" + name)
+	if os.IsNotExist(err) {
+		t.Skipf("SKIPPED: testdata/%s is missing, download it from https://www.unicode.org/Public/13.0.0/ucd/%s", name, name)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+var classNames = map[string]xbidi.Class{
+	"L": xbidi.L, "R": xbidi.R, "EN": xbidi.EN, "ES": xbidi.ES, "ET": xbidi.ET, "AN": xbidi.AN,
+	"CS": xbidi.CS, "B": xbidi.B, "S": xbidi.S, "WS": xbidi.WS, "ON": xbidi.ON, "BN": xbidi.BN,
+	"NSM": xbidi.NSM, "AL": xbidi.AL, "LRO": xbidi.LRO, "RLO": xbidi.RLO, "LRE": xbidi.LRE,
+	"RLE": xbidi.RLE, "PDF": xbidi.PDF, "LRI": xbidi.LRI, "RLI": xbidi.RLI, "FSI": xbidi.FSI, "PDI": xbidi.PDI,
+}
+
+// parseLevels returns -1 for 'x'
+func parseLevels(s string) ([]int, error) {
+	var out []int
+	for _, field := range strings.Fields(s) {
+		if field == "x" {
+			out = append(out, -1)
+			continue
+		}
+		l, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+// checkResult compares the line levels and visual order of the whole paragraph,
+// ignoring the characters removed by rule X9.
+func checkResult(p *Paragraph, levels, order []int) error {
+	n := len(p.classes)
+	gotLevels := p.LineLevels(0, n)
+	for i, l := range levels {
+		if l != -1 && int(gotLevels[i]) != l {
+			return fmt.Errorf("expected levels %v, got %v", levels, gotLevels)
+		}
+	}
+	var gotOrder []int
+	for _, index := range p.VisualOrder(0, n) {
+		if levels[index] != -1 {
+			gotOrder = append(gotOrder, index)
+		}
+	}
+	if len(gotOrder) == 0 && len(order) == 0 {
+		return nil
+	}
+	if !reflect.DeepEqual(gotOrder, order) {
+		return fmt.Errorf("expected order %v, got %v", order, gotOrder)
+	}
+	return nil
+}
+
+func TestBidiTest(t *testing.T) {
+	testBidiTest(t, openTestFile(t, "BidiTest.txt"))
+}
+
+func TestBidiTestSample(t *testing.T) {
+	testBidiTest(t, strings.NewReader(bidiTestSample))
+}
+
+// testBidiTest checks the content of `input`, using the format of BidiTest.txt
+func testBidiTest(t *testing.T, input io.Reader) {
+	scanner := bufio.NewScanner(input)
+	var (
+		levels, order []int
+		err           error
+		nbTests       int
+	)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "@Levels:"):
+			levels, err = parseLevels(strings.TrimPrefix(line, "@Levels:"))
+		case strings.HasPrefix(line, "@Reorder:"):
+			order, err = parseLevels(strings.TrimPrefix(line, "@Reorder:"))
+		case strings.HasPrefix(line, "@"):
+			continue
+		default:
+			fields := strings.Split(line, ";")
+			if len(fields) != 2 {
+				t.Fatalf("line %d: invalid line %s", lineNumber, line)
+			}
+			var classes []xbidi.Class
+			for _, name := range strings.Fields(fields[0]) {
+				class, ok := classNames[name]
+				if !ok {
+					t.Fatalf("line %d: invalid class %s", lineNumber, name)
+				}
+				classes = append(classes, class)
+			}
+			bitset, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+			if err != nil {
+				t.Fatalf("line %d: %s", lineNumber, err)
+			}
+			if len(classes) != len(levels) {
+				t.Fatalf("line %d: invalid levels count", lineNumber)
+			}
+			for i, dir := range [3]Direction{Auto, LeftToRight, RightToLeft} {
+				if bitset&(1<<i) == 0 {
+					continue
+				}
+				p := newParagraphFromClasses(classes, make([]bracket, len(classes)), dir)
+				if err := checkResult(p, levels, order); err != nil {
+					t.Fatalf("line %d (%s, direction %d): %s", lineNumber, line, dir, err)
+				}
+				nbTests++
+			}
+		}
+		if err != nil {
+			t.Fatalf("line %d: %s", lineNumber, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d tests", nbTests)
+}
+
+func TestBidiCharacterTest(t *testing.T) {
+	testBidiCharacterTest(t, openTestFile(t, "BidiCharacterTest.txt"))
+}
+
+func TestBidiCharacterTestSample(t *testing.T) {
+	testBidiCharacterTest(t, strings.NewReader(bidiCharacterTestSample))
+}
+
+// testBidiCharacterTest checks the content of `input`, using the format of BidiCharacterTest.txt
+func testBidiCharacterTest(t *testing.T, input io.Reader) {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(nil, 1<<20)
+	nbTests := 0
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, ";")
+		if len(fields) != 5 {
+			t.Fatalf("line %d: invalid line %s", lineNumber, line)
+		}
+		var text []rune
+		for _, field := range strings.Fields(fields[0]) {
+			r, err := strconv.ParseUint(field, 16, 32)
+			if err != nil {
+				t.Fatalf("line %d: %s", lineNumber, err)
+			}
+			text = append(text, rune(r))
+		}
+		dir := [3]Direction{LeftToRight, RightToLeft, Auto}[fields[1][0]-'0']
+		paragraphLevel, err := strconv.Atoi(fields[2])
+		if err != nil {
+			t.Fatalf("line %d: %s", lineNumber, err)
+		}
+		levels, err := parseLevels(fields[3])
+		if err != nil {
+			t.Fatalf("line %d: %s", lineNumber, err)
+		}
+		order, err := parseLevels(fields[4])
+		if err != nil {
+			t.Fatalf("line %d: %s", lineNumber, err)
+		}
+
+		p := NewParagraph(text, dir)
+		if int(p.Level()) != paragraphLevel {
+			t.Fatalf("line %d: expected paragraph level %d, got %d", lineNumber, paragraphLevel, p.Level())
+		}
+		if err := checkResult(p, levels, order); err != nil {
+			t.Fatalf("line %d (%s): %s", lineNumber, line, err)
+		}
+		nbTests++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%d tests", nbTests)
+}
+
+func TestRuns(t *testing.T) {
+	text := []rune("אבג abc def דהו ")
+	p := NewParagraph(text, Auto)
+	if p.Level() != 1 {
+		t.Fatalf("unexpected paragraph level %d", p.Level())
+	}
+	runs := p.Runs(0, len(text))
+	expected := []Run{
+		{Start: 11, End: 16, Level: 1},
+		{Start: 4, End: 11, Level: 2},
+		{Start: 0, End: 4, Level: 1},
+	}
+	if !reflect.DeepEqual(runs, expected) {
+		t.Fatalf("expected %v, got %v", expected, runs)
+	}
+	if runs[0].Direction() != harfbuzz.RightToLeft || runs[1].Direction() != harfbuzz.LeftToRight {
+		t.Fatal("unexpected run directions")
+	}
+
+	// trailing whitespaces of the line are reset to the paragraph level
+	runs = p.Runs(0, 8)
+	expected = []Run{
+		{Start: 7, End: 8, Level: 1},
+		{Start: 4, End: 7, Level: 2},
+		{Start: 0, End: 4, Level: 1},
+	}
+	if !reflect.DeepEqual(runs, expected) {
+		t.Fatalf("expected %v, got %v", expected, runs)
+	}
+}
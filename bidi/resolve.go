@@ -0,0 +1,607 @@
+package bidi
+
+import (
+	"sort"
+
+	xbidi "golang.org/x/text/unicode/bidi"
+)
+
+// This file implements the resolution of embedding levels
+// for one paragraph, from rule P2 to rule I2.
+
+// maximum number of bracket pairs tracked by rule BD16
+const maxPairingDepth = 63
+
+func isIsolateInitiator(c xbidi.Class) bool {
+	return c == xbidi.LRI || c == xbidi.RLI || c == xbidi.FSI
+}
+
+func isIsolateControl(c xbidi.Class) bool {
+	return isIsolateInitiator(c) || c == xbidi.PDI
+}
+
+func isRemovedByX9(c xbidi.Class) bool {
+	switch c {
+	case xbidi.LRE, xbidi.RLE, xbidi.LRO, xbidi.RLO, xbidi.PDF, xbidi.BN:
+		return true
+	}
+	return false
+}
+
+// isNI returns true for neutral and isolate formatting characters
+func isNI(c xbidi.Class) bool {
+	switch c {
+	case xbidi.B, xbidi.S, xbidi.WS, xbidi.ON, xbidi.LRI, xbidi.RLI, xbidi.FSI, xbidi.PDI:
+		return true
+	}
+	return false
+}
+
+// strongDirection maps L to L and R, AL, EN, AN to R,
+// as used by rules N0 and N1.
+func strongDirection(c xbidi.Class) (xbidi.Class, bool) {
+	switch c {
+	case xbidi.L:
+		return xbidi.L, true
+	case xbidi.R, xbidi.AL, xbidi.EN, xbidi.AN:
+		return xbidi.R, true
+	}
+	return 0, false
+}
+
+func directionOfLevel(l Level) xbidi.Class {
+	if l.IsRTL() {
+		return xbidi.R
+	}
+	return xbidi.L
+}
+
+func defaultLevel(dir Direction) Level {
+	if dir == RightToLeft {
+		return 1
+	}
+	return 0
+}
+
+// paragraph stores the temporary state used when
+// resolving one paragraph
+type paragraph struct {
+	initialClasses []xbidi.Class
+	classes        []xbidi.Class // resolved classes
+	brackets       []bracket
+	levels         []Level
+
+	// index of the matching PDI for isolate initiators,
+	// of the matching isolate initiator for PDI, or -1
+	matchingIsolate []int
+
+	level Level
+}
+
+// resolveParagraph resolves the embedding levels of one paragraph,
+// writting them into `levels`, and returns the paragraph level.
+func resolveParagraph(classes []xbidi.Class, brackets []bracket, dir Direction, levels []Level) Level {
+	p := paragraph{
+		initialClasses:  classes,
+		classes:         append([]xbidi.Class(nil), classes...),
+		brackets:        brackets,
+		levels:          levels,
+		matchingIsolate: make([]int, len(classes)),
+	}
+	p.determineMatchingIsolates()
+
+	switch dir {
+	case LeftToRight:
+		p.level = 0
+	case RightToLeft:
+		p.level = 1
+	default:
+		p.level = p.firstStrongLevel(0, len(classes))
+	}
+
+	p.resolveExplicitLevels()
+
+	for _, seq := range p.isolatingRunSequences() {
+		seq.resolveWeakTypes()
+		seq.resolvePairedBrackets()
+		seq.resolveNeutralTypes()
+		seq.resolveImplicitLevels()
+	}
+
+	p.assignLevelsToRemovedCharacters()
+
+	return p.level
+}
+
+// determineMatchingIsolates implements BD9.
+func (p *paragraph) determineMatchingIsolates() {
+	var openings []int // stack of isolate initiators
+	for i, c := range p.initialClasses {
+		p.matchingIsolate[i] = -1
+		if isIsolateInitiator(c) {
+			openings = append(openings, i)
+		} else if c == xbidi.PDI && len(openings) != 0 {
+			opening := openings[len(openings)-1]
+			openings = openings[:len(openings)-1]
+			p.matchingIsolate[opening] = i
+			p.matchingIsolate[i] = opening
+		}
+	}
+}
+
+// firstStrongLevel implements rules P2 and P3 on the [start, end[ range,
+// skipping isolated content and returning 0 if no strong character is found.
+func (p *paragraph) firstStrongLevel(start, end int) Level {
+	for i := start; i < end; i++ {
+		switch c := p.initialClasses[i]; c {
+		case xbidi.L:
+			return 0
+		case xbidi.R, xbidi.AL:
+			return 1
+		case xbidi.LRI, xbidi.RLI, xbidi.FSI:
+			// skip to the matching PDI, or the end of the paragraph
+			i = p.matchingIsolate[i]
+			if i == -1 {
+				return 0
+			}
+		}
+	}
+	return 0
+}
+
+type directionalStatus struct {
+	level    Level
+	override xbidi.Class // ON, L or R
+	isolate  bool
+}
+
+// resolveExplicitLevels implements rules X1 to X8.
+func (p *paragraph) resolveExplicitLevels() {
+	stack := make([]directionalStatus, 1, MaxDepth+2)
+	stack[0] = directionalStatus{level: p.level, override: xbidi.ON}
+
+	var overflowIsolates, overflowEmbeddings, validIsolates int
+
+	for i, c := range p.initialClasses {
+		last := stack[len(stack)-1]
+		switch c {
+		case xbidi.RLE, xbidi.LRE, xbidi.RLO, xbidi.LRO: // X2 to X5
+			isRTL := c == xbidi.RLE || c == xbidi.RLO
+			newLevel := nextLevel(last.level, isRTL)
+			if newLevel <= MaxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				status := directionalStatus{level: newLevel, override: xbidi.ON}
+				if c == xbidi.RLO {
+					status.override = xbidi.R
+				} else if c == xbidi.LRO {
+					status.override = xbidi.L
+				}
+				stack = append(stack, status)
+			} else if overflowIsolates == 0 {
+				overflowEmbeddings++
+			}
+			p.levels[i] = last.level
+		case xbidi.RLI, xbidi.LRI, xbidi.FSI: // X5a to X5c
+			p.levels[i] = last.level
+			if last.override != xbidi.ON {
+				p.classes[i] = last.override
+			}
+
+			isRTL := c == xbidi.RLI
+			if c == xbidi.FSI {
+				end := p.matchingIsolate[i]
+				if end == -1 {
+					end = len(p.initialClasses)
+				}
+				isRTL = p.firstStrongLevel(i+1, end) == 1
+			}
+			newLevel := nextLevel(last.level, isRTL)
+			if newLevel <= MaxDepth && overflowIsolates == 0 && overflowEmbeddings == 0 {
+				validIsolates++
+				stack = append(stack, directionalStatus{level: newLevel, override: xbidi.ON, isolate: true})
+			} else {
+				overflowIsolates++
+			}
+		case xbidi.PDI: // X6a
+			if overflowIsolates > 0 {
+				overflowIsolates--
+			} else if validIsolates != 0 {
+				overflowEmbeddings = 0
+				for !stack[len(stack)-1].isolate {
+					stack = stack[:len(stack)-1]
+				}
+				stack = stack[:len(stack)-1]
+				validIsolates--
+			}
+			last = stack[len(stack)-1]
+			p.levels[i] = last.level
+			if last.override != xbidi.ON {
+				p.classes[i] = last.override
+			}
+		case xbidi.PDF: // X7
+			if overflowIsolates > 0 {
+				// nothing to do
+			} else if overflowEmbeddings > 0 {
+				overflowEmbeddings--
+			} else if !last.isolate && len(stack) >= 2 {
+				stack = stack[:len(stack)-1]
+			}
+			p.levels[i] = last.level
+		case xbidi.B: // X8
+			p.levels[i] = p.level
+		case xbidi.BN:
+			p.levels[i] = last.level
+		default: // X6
+			p.levels[i] = last.level
+			if last.override != xbidi.ON {
+				p.classes[i] = last.override
+			}
+		}
+	}
+}
+
+// nextLevel returns the least odd (if `isRTL`) or even level greater than `level`
+func nextLevel(level Level, isRTL bool) Level {
+	if isRTL {
+		return (level + 1) | 1
+	}
+	return (level + 2) &^ 1
+}
+
+// levelRuns implements BD7, ignoring the characters removed by rule X9.
+func (p *paragraph) levelRuns() [][]int {
+	var (
+		runs         [][]int
+		current      []int
+		currentLevel Level
+	)
+	for i, c := range p.initialClasses {
+		if isRemovedByX9(c) {
+			continue
+		}
+		if len(current) != 0 && p.levels[i] != currentLevel {
+			runs = append(runs, current)
+			current = nil
+		}
+		current = append(current, i)
+		currentLevel = p.levels[i]
+	}
+	if len(current) != 0 {
+		runs = append(runs, current)
+	}
+	return runs
+}
+
+// isolatingRunSequences implements BD13 and rule X10.
+func (p *paragraph) isolatingRunSequences() []*isolatingRunSequence {
+	runs := p.levelRuns()
+
+	// index of the level run starting with each character
+	runForChar := make(map[int]int, len(runs))
+	for i, run := range runs {
+		runForChar[run[0]] = i
+	}
+
+	var out []*isolatingRunSequence
+	for _, run := range runs {
+		first := run[0]
+		if p.initialClasses[first] == xbidi.PDI && p.matchingIsolate[first] != -1 {
+			// this run is part of a sequence starting with the matching initiator
+			continue
+		}
+		var indices []int
+		for {
+			indices = append(indices, run...)
+			last := run[len(run)-1]
+			if !isIsolateInitiator(p.initialClasses[last]) {
+				break
+			}
+			pdi := p.matchingIsolate[last]
+			if pdi == -1 {
+				break
+			}
+			next, ok := runForChar[pdi]
+			if !ok {
+				break
+			}
+			run = runs[next]
+		}
+		out = append(out, p.newIsolatingRunSequence(indices))
+	}
+	return out
+}
+
+type isolatingRunSequence struct {
+	p *paragraph
+
+	indices  []int         // indices into the paragraph
+	classes  []xbidi.Class // resolved classes, for each index
+	level    Level
+	sos, eos xbidi.Class
+}
+
+func (p *paragraph) newIsolatingRunSequence(indices []int) *isolatingRunSequence {
+	seq := &isolatingRunSequence{
+		p:       p,
+		indices: indices,
+		classes: make([]xbidi.Class, len(indices)),
+		level:   p.levels[indices[0]],
+	}
+	for i, index := range indices {
+		seq.classes[i] = p.classes[index]
+	}
+
+	// sos: compare with the level of the preceding character
+	prevLevel := p.level
+	for i := indices[0] - 1; i >= 0; i-- {
+		if !isRemovedByX9(p.initialClasses[i]) {
+			prevLevel = p.levels[i]
+			break
+		}
+	}
+	seq.sos = directionOfLevel(maxLevel(prevLevel, seq.level))
+
+	// eos: compare with the level of the following character
+	last := indices[len(indices)-1]
+	nextLevel := p.level
+	if !isIsolateInitiator(p.initialClasses[last]) {
+		for i := last + 1; i < len(p.initialClasses); i++ {
+			if !isRemovedByX9(p.initialClasses[i]) {
+				nextLevel = p.levels[i]
+				break
+			}
+		}
+	}
+	seq.eos = directionOfLevel(maxLevel(nextLevel, p.levels[last]))
+
+	return seq
+}
+
+func maxLevel(a, b Level) Level {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// resolveWeakTypes implements rules W1 to W7.
+func (s *isolatingRunSequence) resolveWeakTypes() {
+	// W1
+	prev := s.sos
+	for i, c := range s.classes {
+		if c == xbidi.NSM {
+			s.classes[i] = prev
+			if isIsolateControl(prev) {
+				s.classes[i] = xbidi.ON
+			}
+		}
+		prev = s.classes[i]
+	}
+
+	// W2 and W3
+	lastStrong := s.sos
+	for i, c := range s.classes {
+		switch c {
+		case xbidi.EN:
+			if lastStrong == xbidi.AL {
+				s.classes[i] = xbidi.AN
+			}
+		case xbidi.L, xbidi.R:
+			lastStrong = c
+		case xbidi.AL:
+			lastStrong = c
+			s.classes[i] = xbidi.R
+		}
+	}
+
+	// W4
+	for i := 1; i < len(s.classes)-1; i++ {
+		before, after := s.classes[i-1], s.classes[i+1]
+		switch s.classes[i] {
+		case xbidi.ES:
+			if before == xbidi.EN && after == xbidi.EN {
+				s.classes[i] = xbidi.EN
+			}
+		case xbidi.CS:
+			if before == xbidi.EN && after == xbidi.EN {
+				s.classes[i] = xbidi.EN
+			} else if before == xbidi.AN && after == xbidi.AN {
+				s.classes[i] = xbidi.AN
+			}
+		}
+	}
+
+	// W5
+	for i := 0; i < len(s.classes); i++ {
+		if s.classes[i] != xbidi.ET {
+			continue
+		}
+		start := i
+		for i < len(s.classes) && s.classes[i] == xbidi.ET {
+			i++
+		}
+		if (start > 0 && s.classes[start-1] == xbidi.EN) || (i < len(s.classes) && s.classes[i] == xbidi.EN) {
+			for j := start; j < i; j++ {
+				s.classes[j] = xbidi.EN
+			}
+		}
+		i-- // i is not ET
+	}
+
+	// W6
+	for i, c := range s.classes {
+		if c == xbidi.ES || c == xbidi.ET || c == xbidi.CS {
+			s.classes[i] = xbidi.ON
+		}
+	}
+
+	// W7
+	lastStrong = s.sos
+	for i, c := range s.classes {
+		switch c {
+		case xbidi.EN:
+			if lastStrong == xbidi.L {
+				s.classes[i] = xbidi.L
+			}
+		case xbidi.L, xbidi.R:
+			lastStrong = c
+		}
+	}
+}
+
+type bracketPair struct {
+	opening, closing int // indices into the sequence
+}
+
+// locateBrackets implements BD16
+func (s *isolatingRunSequence) locateBrackets() []bracketPair {
+	type opening struct {
+		id    rune
+		index int
+	}
+	var (
+		stack []opening
+		pairs []bracketPair
+	)
+	for i, index := range s.indices {
+		br := s.p.brackets[index]
+		if br.typ == bracketNone || s.classes[i] != xbidi.ON {
+			continue
+		}
+		switch br.typ {
+		case bracketOpen:
+			if len(stack) == maxPairingDepth {
+				// stop processing for the remainder of the sequence
+				sort.Slice(pairs, func(i, j int) bool { return pairs[i].opening < pairs[j].opening })
+				return pairs
+			}
+			stack = append(stack, opening{id: br.id, index: i})
+		case bracketClose:
+			for j := len(stack) - 1; j >= 0; j-- {
+				if stack[j].id == br.id {
+					pairs = append(pairs, bracketPair{opening: stack[j].index, closing: i})
+					stack = stack[:j]
+					break
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].opening < pairs[j].opening })
+	return pairs
+}
+
+// resolvePairedBrackets implements rule N0.
+func (s *isolatingRunSequence) resolvePairedBrackets() {
+	embedding := directionOfLevel(s.level)
+	for _, pair := range s.locateBrackets() {
+		var foundEmbedding, foundOpposite bool
+		for i := pair.opening + 1; i < pair.closing; i++ {
+			dir, ok := strongDirection(s.classes[i])
+			if !ok {
+				continue
+			}
+			if dir == embedding {
+				foundEmbedding = true
+				break
+			}
+			foundOpposite = true
+		}
+
+		var newClass xbidi.Class
+		if foundEmbedding { // N0 b
+			newClass = embedding
+		} else if foundOpposite { // N0 c
+			context := s.sos
+			for i := pair.opening - 1; i >= 0; i-- {
+				if dir, ok := strongDirection(s.classes[i]); ok {
+					context = dir
+					break
+				}
+			}
+			if context != embedding {
+				newClass = context
+			} else {
+				newClass = embedding
+			}
+		} else { // N0 d
+			continue
+		}
+
+		s.setBracketClass(pair.opening, newClass)
+		s.setBracketClass(pair.closing, newClass)
+	}
+}
+
+// setBracketClass also updates the NSMs following the bracket
+func (s *isolatingRunSequence) setBracketClass(i int, class xbidi.Class) {
+	s.classes[i] = class
+	for i++; i < len(s.indices); i++ {
+		if s.p.initialClasses[s.indices[i]] != xbidi.NSM {
+			break
+		}
+		s.classes[i] = class
+	}
+}
+
+// resolveNeutralTypes implements rules N1 and N2.
+func (s *isolatingRunSequence) resolveNeutralTypes() {
+	embedding := directionOfLevel(s.level)
+	for i := 0; i < len(s.classes); i++ {
+		if !isNI(s.classes[i]) {
+			continue
+		}
+		start := i
+		for i < len(s.classes) && isNI(s.classes[i]) {
+			i++
+		}
+
+		before := s.sos
+		if start > 0 {
+			before, _ = strongDirection(s.classes[start-1])
+		}
+		after := s.eos
+		if i < len(s.classes) {
+			after, _ = strongDirection(s.classes[i])
+		}
+
+		newClass := embedding // N2
+		if before == after {  // N1
+			newClass = before
+		}
+		for j := start; j < i; j++ {
+			s.classes[j] = newClass
+		}
+		i-- // i is not NI
+	}
+}
+
+// resolveImplicitLevels implements rules I1 and I2,
+// and writes the result into the paragraph levels
+func (s *isolatingRunSequence) resolveImplicitLevels() {
+	for i, index := range s.indices {
+		level := s.p.levels[index]
+		switch c := s.classes[i]; {
+		case !level.IsRTL() && c == xbidi.R:
+			level++
+		case !level.IsRTL() && (c == xbidi.AN || c == xbidi.EN):
+			level += 2
+		case level.IsRTL() && (c == xbidi.L || c == xbidi.EN || c == xbidi.AN):
+			level++
+		}
+		s.p.levels[index] = level
+	}
+}
+
+// assignLevelsToRemovedCharacters gives the characters removed
+// by rule X9 the level of the preceding character, or the paragraph level.
+func (p *paragraph) assignLevelsToRemovedCharacters() {
+	for i, c := range p.initialClasses {
+		if !isRemovedByX9(c) {
+			continue
+		}
+		if i == 0 {
+			p.levels[i] = p.level
+		} else {
+			p.levels[i] = p.levels[i-1]
+		}
+	}
+}
@@ -1,8 +1,6 @@
 // Package fonts provides supports for parsing
 // several font formats (postscript, bitmap and truetype)
 // and provides a common API, inspired by freetype.
-//
-// It does not currently support CIDType1 fonts.
 package fonts
 
 import "math"
@@ -0,0 +1,137 @@
+// Package fontscan provides a database of the fonts installed on a system,
+// able to answer fontconfig-like queries.
+//
+// The fonts are scanned once, and a summary of each face is stored in an
+// index, which may be saved on disk, so that only new or modified
+// files have to be scanned again.
+//
+// The faces of a family may then be selected using the
+// CSS font matching algorithm (see `MatchCSS`).
+package fontscan
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// indexVersion is incremented when the index format changes,
+// so that outdated indexes are ignored.
+const indexVersion = 1
+
+// indexedFile stores the faces of one font file
+type indexedFile struct {
+	// Size and ModTime (in Unix nanoseconds) are used
+	// to detect modified files
+	Size    int64
+	ModTime int64
+
+	Faces []Footprint // empty for invalid files
+}
+
+// Database stores the faces found in a set of directories.
+// It is not safe for concurrent modifications, but concurrent
+// queries are supported.
+type Database struct {
+	files map[string]indexedFile // keyed by file path
+}
+
+// NewDatabase returns an empty database. Use `Scan`
+// to fill it.
+func NewDatabase() *Database {
+	return &Database{files: map[string]indexedFile{}}
+}
+
+// serialized form of the database
+type index struct {
+	Version uint32
+	Files   map[string]indexedFile
+}
+
+// LoadDatabase reads an index previously written by `Save`.
+// An index with an outdated format is ignored, and an empty
+// database is returned, so that the fonts are scanned again.
+func LoadDatabase(filename string) (*Database, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var idx index
+	if err = gob.NewDecoder(f).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("invalid font index %s: %s", filename, err)
+	}
+	if idx.Version != indexVersion || idx.Files == nil {
+		return NewDatabase(), nil
+	}
+	return &Database{files: idx.Files}, nil
+}
+
+// Save writes the database index into `filename`.
+func (db *Database) Save(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(f).Encode(index{Version: indexVersion, Files: db.files})
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("writing font index: %s", err)
+	}
+	return f.Close()
+}
+
+// Scan walks the directories `dirs` (see `DefaultFontDirectories`)
+// and updates the database so that it exactly reflects their content:
+// new font files and files whose size or modification time has changed are scanned (in parallel),
+// and files which have been removed are dropped.
+// Directories which do not exist are ignored, and invalid font files are
+// indexed without faces.
+func (db *Database) Scan(dirs ...string) error {
+	_, err := db.scan(dirs)
+	return err
+}
+
+// scan returns the number of font files actually scanned
+func (db *Database) scan(dirs []string) (int, error) {
+	files, err := walkFontFiles(dirs)
+	if err != nil {
+		return 0, err
+	}
+
+	newFiles := make(map[string]indexedFile, len(files))
+	var toScan []fontFile
+	for _, file := range files {
+		if indexed, ok := db.files[file.path]; ok && indexed.Size == file.size && indexed.ModTime == file.modTime {
+			newFiles[file.path] = indexed
+			continue
+		}
+		toScan = append(toScan, file)
+	}
+
+	for i, faces := range scanFiles(toScan) {
+		file := toScan[i]
+		newFiles[file.path] = indexedFile{Size: file.size, ModTime: file.modTime, Faces: faces}
+	}
+
+	db.files = newFiles
+	return len(toScan), nil
+}
+
+// Footprints returns the faces stored in the database,
+// sorted by file path and face index.
+func (db *Database) Footprints() []Footprint {
+	paths := make([]string, 0, len(db.files))
+	for path := range db.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var out []Footprint
+	for _, path := range paths {
+		out = append(out, db.files[path].Faces...)
+	}
+	return out
+}
@@ -0,0 +1,203 @@
+package fontscan
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	bitmapdata "github.com/benoitkugler/textlayout-testdata/bitmap"
+	tttestdata "github.com/benoitkugler/textlayout-testdata/truetype"
+	type1data "github.com/benoitkugler/textlayout-testdata/type1"
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+// copyFile copies the file `name` from `src` to the directory `dir`
+func copyFile(t *testing.T, src fs.FS, name, dir string) string {
+	b, err := fs.ReadFile(src, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err = os.WriteFile(path, b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setupFontDir creates a temporary directory with a few font files
+func setupFontDir(t *testing.T) string {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"DejaVuSerif.ttf", "Roboto-BoldItalic.ttf", "Castoro-Regular.ttf", "Castoro-Italic.ttf", "ToyTTC.ttc"} {
+		copyFile(t, tttestdata.Files, name, dir)
+	}
+	copyFile(t, type1data.Files, "CalligrapherRegular.pfb", sub)
+	copyFile(t, bitmapdata.Files, "4x6.pcf", sub)
+	// not a font file
+	if err := os.WriteFile(filepath.Join(dir, "readme.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// invalid font file
+	if err := os.WriteFile(filepath.Join(sub, "invalid.ttf"), []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestScan(t *testing.T) {
+	dir := setupFontDir(t)
+	db := NewDatabase()
+	scanned, err := db.scan([]string{dir, filepath.Join(dir, "not-existing")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned != 8 {
+		t.Fatalf("expected 8 scanned files, got %d", scanned)
+	}
+
+	families := map[string]bool{}
+	for _, fp := range db.Footprints() {
+		families[fp.Family] = true
+		if fp.Family != "" && len(fp.Runes) == 0 {
+			t.Errorf("missing runes for %v", fp.ID)
+		}
+	}
+	for _, family := range []string{"DejaVu Serif", "Roboto", "Castoro", "Calligrapher"} {
+		if !families[family] {
+			t.Errorf("missing family %s in %v", family, families)
+		}
+	}
+
+	// incremental scan
+	if scanned, _ = db.scan([]string{dir}); scanned != 0 {
+		t.Fatalf("expected no scanned files, got %d", scanned)
+	}
+	future := time.Now().Add(time.Hour)
+	if err = os.Chtimes(filepath.Join(dir, "DejaVuSerif.ttf"), future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Remove(filepath.Join(dir, "ToyTTC.ttc")); err != nil {
+		t.Fatal(err)
+	}
+	before := len(db.Footprints())
+	if scanned, _ = db.scan([]string{dir}); scanned != 1 {
+		t.Fatalf("expected one scanned file, got %d", scanned)
+	}
+	if after := len(db.Footprints()); after >= before {
+		t.Fatalf("removed file still in the database (%d faces)", after)
+	}
+}
+
+func TestScanUnreadable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	dir := setupFontDir(t)
+	locked := filepath.Join(dir, "locked")
+	if err := os.Mkdir(locked, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	copyFile(t, tttestdata.Files, "DejaVuSerif.ttf", locked)
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0o755) })
+
+	db := NewDatabase()
+	scanned, err := db.scan([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scanned != 8 {
+		t.Fatalf("expected 8 scanned files, got %d", scanned)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	dir := setupFontDir(t)
+	db := NewDatabase()
+	if err := db.Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	indexFile := filepath.Join(t.TempDir(), "fonts.index")
+	if err := db.Save(indexFile); err != nil {
+		t.Fatal(err)
+	}
+
+	db2, err := LoadDatabase(indexFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(db.Footprints(), db2.Footprints()) {
+		t.Fatal("database not preserved by Save/Load")
+	}
+	if scanned, _ := db2.scan([]string{dir}); scanned != 0 {
+		t.Fatalf("expected no scanned files, got %d", scanned)
+	}
+
+	if err = os.WriteFile(indexFile, []byte("invalid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadDatabase(indexFile); err == nil {
+		t.Fatal("expected error for invalid index")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := setupFontDir(t)
+	db := NewDatabase()
+	if err := db.Scan(dir); err != nil {
+		t.Fatal(err)
+	}
+	file := func(ids []fonts.FaceID) []string {
+		var out []string
+		for _, id := range ids {
+			out = append(out, filepath.Base(id.File))
+		}
+		return out
+	}
+
+	for _, test := range []struct {
+		query    Query
+		expected []string
+	}{
+		{Query{Families: []string{"castoro"}, Style: fonts.StyleItalic}, []string{"Castoro-Italic.ttf", "Castoro-Regular.ttf"}},
+		{Query{Families: []string{"Castoro"}, Style: fonts.StyleNormal}, []string{"Castoro-Regular.ttf", "Castoro-Italic.ttf"}},
+		{Query{Families: []string{"Unknown family", "serif"}}, []string{"DejaVuSerif.ttf"}},
+		{Query{Families: []string{"Roboto", "DejaVuSerif"}}, []string{"Roboto-BoldItalic.ttf", "DejaVuSerif.ttf"}},
+		{Query{Families: []string{"Roboto", "DejaVu Serif"}, Runes: []rune("Ωա")}, []string{"DejaVuSerif.ttf"}},
+		{Query{Families: []string{"Castoro"}, Runes: []rune{0x10FFFF}}, nil},
+	} {
+		if got := file(db.Query(test.query)); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("for %v, expected %v, got %v", test.query, test.expected, got)
+		}
+	}
+}
+
+func TestQueryAspectRanks(t *testing.T) {
+	for _, test := range []struct {
+		query         Query
+		better, worse Footprint
+	}{
+		// lighter weights are checked after the weights up to 500
+		{Query{Weight: 400}, Footprint{Weight: 450}, Footprint{Weight: 350}},
+		{Query{Weight: 300}, Footprint{Weight: 200}, Footprint{Weight: 400}},
+		{Query{Style: fonts.StyleItalic}, Footprint{Style: fonts.StyleOblique}, Footprint{Style: fonts.StyleNormal}},
+		// stretch is matched before style
+		{
+			Query{Style: fonts.StyleItalic, Stretch: fonts.StretchCondensed},
+			Footprint{Style: fonts.StyleNormal, Stretch: fonts.StretchCondensed},
+			Footprint{Style: fonts.StyleItalic, Stretch: fonts.StretchNormal},
+		},
+	} {
+		if !lessRanks(test.query.aspectRanks(test.better), test.query.aspectRanks(test.worse)) {
+			t.Errorf("for %v, expected %v before %v", test.query, test.better, test.worse)
+		}
+	}
+}
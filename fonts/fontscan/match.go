@@ -0,0 +1,373 @@
+package fontscan
+
+import (
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+// This file implements the font matching algorithm defined in
+// CSS Fonts Module Level 4, section 5.2 (https://www.w3.org/TR/css-fonts-4/#font-style-matching),
+// used to select one face of a family.
+
+var (
+	tagWght = truetype.MustNewTag("wght")
+	tagWdth = truetype.MustNewTag("wdth")
+	tagItal = truetype.MustNewTag("ital")
+	tagSlnt = truetype.MustNewTag("slnt")
+)
+
+// defaultObliqueAngle is the angle used for 'oblique' without angle.
+const defaultObliqueAngle = 14
+
+// CSSFace is one face of a family, candidate for CSS matching.
+type CSSFace struct {
+	Descriptor fonts.FontDescriptor
+	// Variations is the 'fvar' table of variable fonts,
+	// used to extend the weight, stretch and style supported by the face.
+	// It is empty for static fonts.
+	Variations truetype.TableFvar
+}
+
+// CSSAspect is the face description requested by the CSS
+// properties font-style, font-weight and font-stretch.
+// Zero values are interpreted as 'normal'.
+type CSSAspect struct {
+	Style fonts.Style
+	// ObliqueAngle is the angle requested with `fonts.StyleOblique`, in degrees,
+	// with positive values for a clockwise slant (the usual orientation).
+	// Zero means the default angle of 14°.
+	ObliqueAngle float32
+	Weight       fonts.Weight
+	Stretch      fonts.Stretch
+}
+
+// MatchCSS selects the face of `faces` (the faces of one family) best matching `aspect`,
+// narrowing the candidates by stretch, then style, then weight, following the
+// fallback orders of the CSS specification.
+// For variable faces, the returned variations should be applied to the face
+// (see `truetype.SetVariations`) to obtain the requested aspect; they are empty for static faces.
+// MatchCSS returns -1 if `faces` is empty.
+func MatchCSS(faces []CSSFace, aspect CSSAspect) (int, []truetype.Variation) {
+	if len(faces) == 0 {
+		return -1, nil
+	}
+	if aspect.Weight == 0 {
+		aspect.Weight = fonts.WeightNormal
+	}
+	if aspect.Stretch == 0 {
+		aspect.Stretch = fonts.StretchNormal
+	}
+	if aspect.Style == 0 {
+		aspect.Style = fonts.StyleNormal
+	}
+	if aspect.ObliqueAngle == 0 {
+		aspect.ObliqueAngle = defaultObliqueAngle
+	}
+
+	candidates := make([]cssCandidate, len(faces))
+	for i, face := range faces {
+		candidates[i] = newCSSCandidate(face)
+	}
+	remaining := make([]int, len(faces))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	// font-stretch, in percentages
+	remaining = narrow(remaining, func(i int) cssRank {
+		rank, value := stretchRank(candidates[i].stretch, float32(aspect.Stretch)*100)
+		candidates[i].stretchValue = value
+		return rank
+	})
+	// font-style
+	remaining = narrow(remaining, func(i int) cssRank {
+		return candidates[i].styleRank(aspect)
+	})
+	// font-weight
+	remaining = narrow(remaining, func(i int) cssRank {
+		rank, value := weightRank(candidates[i].weight, float32(aspect.Weight))
+		candidates[i].weightValue = value
+		return rank
+	})
+
+	index := remaining[0]
+	return index, candidates[index].variations(faces[index].Variations)
+}
+
+// valueRange is an inclusive range of values supported by a face
+type valueRange struct {
+	min, max float32
+}
+
+func (vr valueRange) contains(v float32) bool { return vr.min <= v && v <= vr.max }
+
+// cssRank is the position in a fallback order: groups
+// are checked in order, then by increasing distance
+type cssRank struct {
+	group    int
+	distance float32
+}
+
+func (r cssRank) less(other cssRank) bool {
+	if r.group != other.group {
+		return r.group < other.group
+	}
+	return r.distance < other.distance
+}
+
+// narrow keeps the candidates with the best rank, preserving their order
+func narrow(candidates []int, rank func(i int) cssRank) []int {
+	ranks := make([]cssRank, len(candidates))
+	best := 0
+	for j, i := range candidates {
+		ranks[j] = rank(i)
+		if ranks[j].less(ranks[best]) {
+			best = j
+		}
+	}
+	var out []int
+	for j, i := range candidates {
+		if !ranks[best].less(ranks[j]) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// below returns the closest value of `vr` lower than or equal to `v`, if any
+func (vr valueRange) below(v float32) (float32, bool) {
+	if vr.min > v {
+		return 0, false
+	}
+	if vr.max < v {
+		return vr.max, true
+	}
+	return v, true
+}
+
+// above returns the closest value of `vr` greater than or equal to `v`, if any
+func (vr valueRange) above(v float32) (float32, bool) {
+	if vr.max < v {
+		return 0, false
+	}
+	if vr.min > v {
+		return vr.min, true
+	}
+	return v, true
+}
+
+func abs(v float32) float32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// stretchRank implements the font-stretch fallback order: for desired values up to 100%,
+// narrower widths are checked first, wider ones otherwise.
+func stretchRank(supported valueRange, desired float32) (cssRank, float32) {
+	if desired <= 100 {
+		if v, ok := supported.below(desired); ok {
+			return cssRank{0, desired - v}, v
+		}
+		return cssRank{1, supported.min - desired}, supported.min
+	}
+	if v, ok := supported.above(desired); ok {
+		return cssRank{0, v - desired}, v
+	}
+	return cssRank{1, desired - supported.max}, supported.max
+}
+
+// weightRank implements the font-weight fallback order
+func weightRank(supported valueRange, desired float32) (cssRank, float32) {
+	switch {
+	case desired < 400:
+		if v, ok := supported.below(desired); ok {
+			return cssRank{0, desired - v}, v
+		}
+		return cssRank{1, supported.min - desired}, supported.min
+	case desired > 500:
+		if v, ok := supported.above(desired); ok {
+			return cssRank{0, v - desired}, v
+		}
+		return cssRank{1, desired - supported.max}, supported.max
+	default:
+		// weights between the desired one and 500 are checked first, in ascending order,
+		// then lower weights in descending order, then weights above 500 in ascending order
+		if v, ok := supported.above(desired); ok && v <= 500 {
+			return cssRank{0, v - desired}, v
+		}
+		if v, ok := supported.below(desired); ok {
+			return cssRank{1, desired - v}, v
+		}
+		return cssRank{2, supported.min - desired}, supported.min
+	}
+}
+
+// angleRank implements the fallback order for oblique angles,
+// returning a group between 0 and 2.
+func angleRank(supported valueRange, desired float32) (cssRank, float32) {
+	switch {
+	case desired >= 11:
+		if v, ok := supported.above(desired); ok {
+			return cssRank{0, v - desired}, v
+		}
+		if supported.max > 0 {
+			return cssRank{1, desired - supported.max}, supported.max
+		}
+		return cssRank{2, -supported.max}, supported.max
+	case desired >= 0:
+		if v, ok := supported.below(desired); ok && v >= 0 {
+			return cssRank{0, desired - v}, v
+		}
+		if supported.min > desired {
+			return cssRank{1, supported.min - desired}, supported.min
+		}
+		return cssRank{2, -supported.max}, supported.max
+	case desired > -11:
+		if v, ok := supported.above(desired); ok && v <= 0 {
+			return cssRank{0, v - desired}, v
+		}
+		if supported.max < desired {
+			return cssRank{1, desired - supported.max}, supported.max
+		}
+		return cssRank{2, supported.min}, supported.min
+	default:
+		if v, ok := supported.below(desired); ok {
+			return cssRank{0, desired - v}, v
+		}
+		if supported.min < 0 {
+			return cssRank{1, supported.min - desired}, supported.min
+		}
+		return cssRank{2, supported.min}, supported.min
+	}
+}
+
+// cssCandidate stores the aspects supported by a face,
+// and the values selected during matching
+type cssCandidate struct {
+	weight, stretch valueRange // stretch is in percentages
+	normal, italic  bool
+	oblique         *valueRange // supported oblique angles, if any
+
+	weightValue, stretchValue float32
+	selectedStyle             fonts.Style
+	angleValue                float32 // for StyleOblique
+}
+
+func newCSSCandidate(face CSSFace) cssCandidate {
+	out := newStaticCandidate(face.Descriptor.Aspect())
+
+	for _, axis := range face.Variations.Axis {
+		switch axis.Tag {
+		case tagWght:
+			out.weight = valueRange{axis.Minimum, axis.Maximum}
+		case tagWdth:
+			out.stretch = valueRange{axis.Minimum, axis.Maximum}
+		case tagItal:
+			out.italic = out.italic || axis.Maximum >= 1
+			out.normal = out.normal || axis.Minimum <= 0
+		case tagSlnt:
+			// the slnt axis uses counter-clockwise angles
+			angles := valueRange{-axis.Maximum, -axis.Minimum}
+			out.oblique = &angles
+			out.normal = out.normal || angles.contains(0)
+		}
+	}
+	return out
+}
+
+// newStaticCandidate returns the candidate for a face with the given (fixed) aspect
+func newStaticCandidate(style fonts.Style, weight fonts.Weight, stretch fonts.Stretch) cssCandidate {
+	if weight == 0 {
+		weight = fonts.WeightNormal
+	}
+	if stretch == 0 {
+		stretch = fonts.StretchNormal
+	}
+	out := cssCandidate{
+		weight:  valueRange{float32(weight), float32(weight)},
+		stretch: valueRange{float32(stretch) * 100, float32(stretch) * 100},
+	}
+	switch style {
+	case fonts.StyleItalic:
+		out.italic = true
+	case fonts.StyleOblique:
+		out.oblique = &valueRange{defaultObliqueAngle, defaultObliqueAngle}
+	default:
+		out.normal = true
+	}
+	return out
+}
+
+// styleRank implements the font-style fallback order and
+// records the selected style
+func (c *cssCandidate) styleRank(aspect CSSAspect) cssRank {
+	// the position of the italic, oblique and normal faces in the fallback order
+	var italicGroup, obliqueGroup, normalGroup int
+	angle := aspect.ObliqueAngle
+	switch aspect.Style {
+	case fonts.StyleItalic:
+		italicGroup, obliqueGroup, normalGroup = 0, 1, 4
+		angle = defaultObliqueAngle
+	case fonts.StyleOblique:
+		obliqueGroup, italicGroup, normalGroup = 0, 3, 4
+	default:
+		normalGroup, obliqueGroup, italicGroup = 0, 1, 4
+		angle = 0
+	}
+
+	best := cssRank{group: 5}
+	if c.italic && italicGroup < best.group {
+		best = cssRank{group: italicGroup}
+		c.selectedStyle = fonts.StyleItalic
+	}
+	if c.normal && normalGroup < best.group {
+		best = cssRank{group: normalGroup}
+		c.selectedStyle = fonts.StyleNormal
+	}
+	if c.oblique != nil {
+		rank, value := angleRank(*c.oblique, angle)
+		rank.group += obliqueGroup
+		if rank.less(best) {
+			best = rank
+			c.selectedStyle = fonts.StyleOblique
+			c.angleValue = value
+		}
+	}
+	return best
+}
+
+// variations returns the variations to apply to obtain
+// the selected aspect, or nil for static fonts
+func (c *cssCandidate) variations(fvar truetype.TableFvar) []truetype.Variation {
+	var out []truetype.Variation
+	for _, axis := range fvar.Axis {
+		var value float32
+		switch axis.Tag {
+		case tagWght:
+			value = c.weightValue
+		case tagWdth:
+			value = c.stretchValue
+		case tagItal:
+			value = 0
+			if c.selectedStyle == fonts.StyleItalic {
+				value = 1
+			}
+		case tagSlnt:
+			value = 0
+			if c.selectedStyle == fonts.StyleOblique {
+				value = -c.angleValue
+			}
+		default:
+			continue
+		}
+		if value < axis.Minimum {
+			value = axis.Minimum
+		} else if value > axis.Maximum {
+			value = axis.Maximum
+		}
+		out = append(out, truetype.Variation{Tag: axis.Tag, Value: value})
+	}
+	return out
+}
@@ -0,0 +1,115 @@
+package fontscan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+type testDescriptor struct {
+	style   fonts.Style
+	weight  fonts.Weight
+	stretch fonts.Stretch
+}
+
+func (testDescriptor) Family() string                { return "Test" }
+func (testDescriptor) AdditionalStyle() string       { return "" }
+func (testDescriptor) LoadCmap() (fonts.Cmap, error) { return fonts.CmapSimple{}, nil }
+func (fd testDescriptor) Aspect() (fonts.Style, fonts.Weight, fonts.Stretch) {
+	return fd.style, fd.weight, fd.stretch
+}
+
+func staticFaces(aspects ...testDescriptor) []CSSFace {
+	out := make([]CSSFace, len(aspects))
+	for i, a := range aspects {
+		out[i] = CSSFace{Descriptor: a}
+	}
+	return out
+}
+
+func TestMatchCSSStatic(t *testing.T) {
+	const (
+		normal  = fonts.StyleNormal
+		italic  = fonts.StyleItalic
+		oblique = fonts.StyleOblique
+	)
+	for _, test := range []struct {
+		faces    []CSSFace
+		aspect   CSSAspect
+		expected int
+	}{
+		// weight
+		{staticFaces(testDescriptor{weight: 300}, testDescriptor{weight: 600}, testDescriptor{weight: 700}), CSSAspect{Weight: 400}, 0},
+		{staticFaces(testDescriptor{weight: 300}, testDescriptor{weight: 600}, testDescriptor{weight: 700}), CSSAspect{Weight: 500}, 0},
+		{staticFaces(testDescriptor{weight: 300}, testDescriptor{weight: 600}, testDescriptor{weight: 700}), CSSAspect{Weight: 550}, 1},
+		{staticFaces(testDescriptor{weight: 300}, testDescriptor{weight: 600}, testDescriptor{weight: 700}), CSSAspect{Weight: 650}, 2},
+		{staticFaces(testDescriptor{weight: 300}, testDescriptor{weight: 600}, testDescriptor{weight: 700}), CSSAspect{Weight: 200}, 0},
+		{staticFaces(testDescriptor{weight: 100}, testDescriptor{weight: 500}, testDescriptor{weight: 900}), CSSAspect{Weight: 400}, 1},
+		{staticFaces(testDescriptor{weight: 100}, testDescriptor{weight: 900}), CSSAspect{Weight: 450}, 0},
+		// style
+		{staticFaces(testDescriptor{style: normal}, testDescriptor{style: italic}), CSSAspect{Style: oblique}, 1},
+		{staticFaces(testDescriptor{style: normal}, testDescriptor{style: oblique}), CSSAspect{Style: italic}, 1},
+		{staticFaces(testDescriptor{style: italic}, testDescriptor{style: oblique}), CSSAspect{Style: normal}, 1},
+		{staticFaces(testDescriptor{style: italic}, testDescriptor{}), CSSAspect{}, 1},
+		// stretch
+		{staticFaces(testDescriptor{stretch: fonts.StretchCondensed}, testDescriptor{stretch: fonts.StretchExpanded}), CSSAspect{}, 0},
+		{staticFaces(testDescriptor{stretch: fonts.StretchCondensed}, testDescriptor{stretch: fonts.StretchExpanded}), CSSAspect{Stretch: fonts.StretchSemiExpanded}, 1},
+		// stretch is matched before style and weight
+		{staticFaces(testDescriptor{stretch: fonts.StretchCondensed, weight: 700}, testDescriptor{weight: 300, style: italic}), CSSAspect{Weight: 700}, 1},
+		// style is matched before weight
+		{staticFaces(testDescriptor{style: italic, weight: 400}, testDescriptor{weight: 700}), CSSAspect{Weight: 400}, 1},
+	} {
+		if got, _ := MatchCSS(test.faces, test.aspect); got != test.expected {
+			t.Errorf("for %v and %v, expected %d, got %d", test.faces, test.aspect, test.expected, got)
+		}
+	}
+
+	if got, _ := MatchCSS(nil, CSSAspect{}); got != -1 {
+		t.Errorf("expected -1 for empty faces, got %d", got)
+	}
+}
+
+func TestMatchCSSVariable(t *testing.T) {
+	variable := CSSFace{
+		Descriptor: testDescriptor{weight: 400},
+		Variations: truetype.TableFvar{Axis: []truetype.VarAxis{
+			{Tag: tagWght, Minimum: 100, Default: 400, Maximum: 900},
+			{Tag: tagWdth, Minimum: 75, Default: 100, Maximum: 100},
+			{Tag: tagSlnt, Minimum: -20, Default: 0, Maximum: 0},
+		}},
+	}
+	variations := func(wght, wdth, slnt float32) []truetype.Variation {
+		return []truetype.Variation{{Tag: tagWght, Value: wght}, {Tag: tagWdth, Value: wdth}, {Tag: tagSlnt, Value: slnt}}
+	}
+
+	for _, test := range []struct {
+		aspect   CSSAspect
+		expected []truetype.Variation
+	}{
+		{CSSAspect{}, variations(400, 100, 0)},
+		{CSSAspect{Weight: 650}, variations(650, 100, 0)},
+		{CSSAspect{Weight: 950, Stretch: fonts.StretchSemiCondensed}, variations(900, 87.5, 0)},
+		{CSSAspect{Stretch: fonts.StretchUltraCondensed}, variations(400, 75, 0)},
+		{CSSAspect{Stretch: fonts.StretchExpanded}, variations(400, 100, 0)},
+		{CSSAspect{Style: fonts.StyleOblique, ObliqueAngle: 10}, variations(400, 100, -10)},
+		{CSSAspect{Style: fonts.StyleOblique, ObliqueAngle: 30}, variations(400, 100, -20)},
+		{CSSAspect{Style: fonts.StyleItalic}, variations(400, 100, -14)},
+	} {
+		index, got := MatchCSS([]CSSFace{variable}, test.aspect)
+		if index != 0 || !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("for %v, expected %v, got %d %v", test.aspect, test.expected, index, got)
+		}
+	}
+
+	// an italic face is preferred to an oblique one
+	italic := CSSFace{Descriptor: testDescriptor{style: fonts.StyleItalic}}
+	if index, vars := MatchCSS([]CSSFace{variable, italic}, CSSAspect{Style: fonts.StyleItalic}); index != 1 || vars != nil {
+		t.Errorf("expected static italic face, got %d %v", index, vars)
+	}
+	// but the variable face is used for normal text
+	if index, _ := MatchCSS([]CSSFace{variable, italic}, CSSAspect{}); index != 0 {
+		t.Errorf("expected variable face, got %d", index)
+	}
+}
@@ -0,0 +1,172 @@
+package fontscan
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+// genericFamilies maps generic family names (as used in CSS)
+// to a list of common families, in order of preference
+var genericFamilies = map[string][]string{
+	"serif": {
+		"DejaVu Serif", "Noto Serif", "Liberation Serif", "Times New Roman",
+		"Times", "Nimbus Roman", "FreeSerif", "Georgia",
+	},
+	"sans-serif": {
+		"DejaVu Sans", "Noto Sans", "Liberation Sans", "Arial", "Helvetica",
+		"Nimbus Sans", "FreeSans", "Roboto", "Segoe UI",
+	},
+	"monospace": {
+		"DejaVu Sans Mono", "Noto Sans Mono", "Liberation Mono", "Courier New",
+		"Courier", "Nimbus Mono PS", "FreeMono", "Menlo", "Consolas",
+	},
+	"cursive": {
+		"Comic Sans MS", "URW Chancery L", "Z003", "Apple Chancery",
+	},
+	"fantasy": {
+		"Impact", "Papyrus", "Luminari",
+	},
+	"emoji": {
+		"Noto Color Emoji", "Apple Color Emoji", "Segoe UI Emoji", "Twemoji",
+	},
+	"math": {
+		"STIX Two Math", "Latin Modern Math", "Cambria Math", "DejaVu Math TeX Gyre",
+	},
+}
+
+func init() {
+	genericFamilies["sans"] = genericFamilies["sans-serif"]
+	genericFamilies["mono"] = genericFamilies["monospace"]
+}
+
+// normalizeFamily ignores case and spaces, as fontconfig does
+func normalizeFamily(family string) string {
+	return strings.ToLower(strings.Join(strings.Fields(family), ""))
+}
+
+// Query describes the faces to select in a `Database`.
+// Zero values are interpreted as "any value".
+type Query struct {
+	// Families is a list of families, in order of preference.
+	// Generic families (serif, sans-serif, monospace, cursive, fantasy, emoji, math)
+	// are expanded to a list of common families.
+	// If empty, every family is accepted.
+	Families []string
+
+	Style   fonts.Style
+	Weight  fonts.Weight
+	Stretch fonts.Stretch
+
+	// Runes must all be supported by the matching faces.
+	Runes []rune
+}
+
+// expandFamilies returns the normalized family names,
+// with generic families expanded, without duplicates
+func (q Query) expandFamilies() []string {
+	var (
+		out  []string
+		seen = map[string]bool{}
+	)
+	add := func(family string) {
+		if family = normalizeFamily(family); !seen[family] {
+			seen[family] = true
+			out = append(out, family)
+		}
+	}
+	for _, family := range q.Families {
+		if generic, ok := genericFamilies[strings.ToLower(strings.TrimSpace(family))]; ok {
+			for _, f := range generic {
+				add(f)
+			}
+			continue
+		}
+		add(family)
+	}
+	return out
+}
+
+// aspectRanks ranks `fp` against the query aspect, following the fallback
+// orders of the CSS matching (see `MatchCSS`): by stretch, then style, then weight.
+// Zero values of the query are ignored.
+func (q Query) aspectRanks(fp Footprint) [3]cssRank {
+	c := newStaticCandidate(fp.Style, fp.Weight, fp.Stretch)
+	var out [3]cssRank
+	if q.Stretch != 0 {
+		out[0], _ = stretchRank(c.stretch, float32(q.Stretch)*100)
+	}
+	if q.Style != 0 {
+		out[1] = c.styleRank(CSSAspect{Style: q.Style, ObliqueAngle: defaultObliqueAngle})
+	}
+	if q.Weight != 0 {
+		out[2], _ = weightRank(c.weight, float32(q.Weight))
+	}
+	return out
+}
+
+// lessRanks compares the ranks returned by `aspectRanks`
+func lessRanks(r1, r2 [3]cssRank) bool {
+	for i := range r1 {
+		if r1[i] != r2[i] {
+			return r1[i].less(r2[i])
+		}
+	}
+	return false
+}
+
+func (fp Footprint) supports(runes []rune) bool {
+	for _, r := range runes {
+		if !fp.Runes.Contains(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Query returns the faces matching `q`, ordered from the best match
+// to the worst: faces are first sorted by family, following the order of
+// `q.Families`, then by proximity with the requested stretch, style and weight,
+// as in `MatchCSS`.
+// Faces not supporting all the `q.Runes` are not returned.
+func (db *Database) Query(q Query) []fonts.FaceID {
+	families := q.expandFamilies()
+	familyRank := make(map[string]int, len(families))
+	for i, family := range families {
+		familyRank[family] = i
+	}
+
+	type candidate struct {
+		id    fonts.FaceID
+		rank  int
+		ranks [3]cssRank
+	}
+	var candidates []candidate
+	for _, fp := range db.Footprints() {
+		rank := 0
+		if len(families) != 0 {
+			var ok bool
+			if rank, ok = familyRank[normalizeFamily(fp.Family)]; !ok {
+				continue
+			}
+		}
+		if !fp.supports(q.Runes) {
+			continue
+		}
+		candidates = append(candidates, candidate{id: fp.ID, rank: rank, ranks: q.aspectRanks(fp)})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].rank != candidates[j].rank {
+			return candidates[i].rank < candidates[j].rank
+		}
+		return lessRanks(candidates[i].ranks, candidates[j].ranks)
+	})
+
+	out := make([]fonts.FaceID, len(candidates))
+	for i, c := range candidates {
+		out[i] = c.id
+	}
+	return out
+}
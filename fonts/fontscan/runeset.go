@@ -0,0 +1,64 @@
+package fontscan
+
+import (
+	"sort"
+
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+// RuneRange is an inclusive range of runes.
+type RuneRange struct {
+	Start, End rune
+}
+
+// RuneSet is a compact representation of the runes supported by a face,
+// as a sorted list of disjoint ranges.
+type RuneSet []RuneRange
+
+// newRuneSet builds the set of the runes mapped by `cmap`.
+func newRuneSet(cmap fonts.Cmap) RuneSet {
+	var runes []rune
+	for it := cmap.Iter(); it.Next(); {
+		r, _ := it.Char()
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	var out RuneSet
+	for _, r := range runes {
+		if L := len(out); L != 0 && out[L-1].End+1 >= r {
+			if r > out[L-1].End {
+				out[L-1].End = r
+			}
+			continue
+		}
+		out = append(out, RuneRange{Start: r, End: r})
+	}
+	return out
+}
+
+// Contains returns true if `r` is in the set.
+func (rs RuneSet) Contains(r rune) bool {
+	// binary search
+	for i, j := 0, len(rs); i < j; {
+		h := i + (j-i)/2
+		entry := rs[h]
+		if r < entry.Start {
+			j = h
+		} else if entry.End < r {
+			i = h + 1
+		} else {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of runes in the set.
+func (rs RuneSet) Len() int {
+	var out int
+	for _, ra := range rs {
+		out += int(ra.End-ra.Start) + 1
+	}
+	return out
+}
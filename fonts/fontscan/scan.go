@@ -0,0 +1,186 @@
+package fontscan
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/bitmap"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+	"github.com/benoitkugler/textlayout/fonts/type1"
+)
+
+// DefaultFontDirectories returns the usual locations of
+// the system and user fonts, for the current platform.
+// Directories which do not exist are still returned.
+func DefaultFontDirectories() []string {
+	home, _ := os.UserHomeDir()
+	switch runtime.GOOS {
+	case "windows":
+		dirs := []string{filepath.Join(os.Getenv("WINDIR"), "Fonts")}
+		if local := os.Getenv("LOCALAPPDATA"); local != "" {
+			dirs = append(dirs, filepath.Join(local, "Microsoft", "Windows", "Fonts"))
+		}
+		return dirs
+	case "darwin", "ios":
+		return []string{
+			"/System/Library/Fonts",
+			"/Library/Fonts",
+			filepath.Join(home, "Library", "Fonts"),
+		}
+	default:
+		dirs := []string{
+			"/usr/share/fonts",
+			"/usr/local/share/fonts",
+			filepath.Join(home, ".fonts"),
+			filepath.Join(home, ".local", "share", "fonts"),
+		}
+		if data := os.Getenv("XDG_DATA_HOME"); data != "" {
+			dirs = append(dirs, filepath.Join(data, "fonts"))
+		}
+		return dirs
+	}
+}
+
+// Footprint is a summary of a face, as stored in the database index.
+type Footprint struct {
+	ID fonts.FaceID
+
+	Family          string
+	AdditionalStyle string
+	Style           fonts.Style
+	Weight          fonts.Weight
+	Stretch         fonts.Stretch
+
+	// Runes is the set of the runes supported by the face.
+	Runes RuneSet
+}
+
+type scanner = func(fonts.Resource) ([]fonts.FontDescriptor, error)
+
+// scannerFor returns the function used to scan the file `path`,
+// based on its extension, or nil if the file is not a supported font file.
+func scannerFor(path string) scanner {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".gz" { // compressed bitmap fonts
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(path, filepath.Ext(path))))
+		if ext != ".pcf" {
+			return nil
+		}
+	}
+	switch ext {
+	case ".ttf", ".otf", ".ttc", ".otc", ".woff", ".dfont", ".otb":
+		return truetype.ScanFont
+	case ".pfb", ".pfa", ".t1":
+		return type1.ScanFont
+	case ".pcf":
+		return bitmap.ScanFont
+	default:
+		return nil
+	}
+}
+
+// scanFile returns the footprints of the faces in the font file at `path`.
+func scanFile(path string, scan scanner) ([]Footprint, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	descriptors, err := scan(file)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Footprint, len(descriptors))
+	for i, fd := range descriptors {
+		style, weight, stretch := fd.Aspect()
+		out[i] = Footprint{
+			ID:              fonts.FaceID{File: path, Index: uint16(i)},
+			Family:          fd.Family(),
+			AdditionalStyle: fd.AdditionalStyle(),
+			Style:           style,
+			Weight:          weight,
+			Stretch:         stretch,
+		}
+		// the cmap is loaded last, since some formats use the
+		// current position of the file
+		if cmap, err := fd.LoadCmap(); err == nil {
+			out[i].Runes = newRuneSet(cmap)
+		}
+	}
+	return out, nil
+}
+
+// fontFile is a font file found in the scanned directories
+type fontFile struct {
+	path    string
+	size    int64
+	modTime int64 // Unix nanoseconds
+	scan    scanner
+}
+
+// walkFontFiles returns the font files in `dirs` and their sub-directories,
+// ignoring directories which do not exist or can't be read, and unreadable files.
+func walkFontFiles(dirs []string) ([]fontFile, error) {
+	var (
+		out  []fontFile
+		seen = map[string]bool{}
+	)
+	for _, dir := range dirs {
+		err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if d != nil && d.IsDir() { // unreadable directory
+					return fs.SkipDir
+				}
+				return nil // not existing or unreadable entry
+			}
+			if d.IsDir() {
+				return nil
+			}
+			scan := scannerFor(path)
+			if scan == nil || seen[path] {
+				return nil
+			}
+			info, err := os.Stat(path) // follow symbolic links
+			if err != nil || !info.Mode().IsRegular() {
+				return nil // broken link
+			}
+			seen[path] = true
+			out = append(out, fontFile{path: path, size: info.Size(), modTime: info.ModTime().UnixNano(), scan: scan})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// scanFiles scans `files` in parallel and returns their footprints,
+// in the same order. Invalid files are given an empty list.
+func scanFiles(files []fontFile) [][]Footprint {
+	out := make([][]Footprint, len(files))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				out[i], _ = scanFile(files[i].path, files[i].scan)
+			}
+		}()
+	}
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return out
+}
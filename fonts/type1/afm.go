@@ -4,6 +4,7 @@
 package type1
 
 import (
+	"math"
 	"strings"
 
 	"github.com/benoitkugler/textlayout/fonts"
@@ -61,6 +62,10 @@ type AFMFont struct {
 
 	StdHw int
 	StdVw int
+
+	// true when CapHeight and XHeight are provided
+	// by the file (and not default values)
+	hasCapHeight, hasXHeight bool
 }
 
 // CharSet returns a string listing the character names defined in the font subset.
@@ -76,3 +81,68 @@ func (f AFMFont) CharSet() string {
 	}
 	return v.String()
 }
+
+// afmMetrics stores the metrics provided by an AFM file,
+// resolved against the glyphs of a Font.
+type afmMetrics struct {
+	advances map[fonts.GID]Fl
+	kerns    map[[2]fonts.GID]int16
+
+	bbox                [4]Fl // llx, lly, urx, ury
+	ascender, descender Fl
+	capHeight, xHeight  Fl
+	// CapHeight and XHeight are optional
+	hasCapHeight, hasXHeight bool
+
+	underlinePosition, underlineThickness Fl
+}
+
+// AttachAFM uses the metrics of `afm` (typically parsed from the .afm file
+// associated to the font) for the horizontal advances, the font extents, the
+// line metrics and the kerning of the font.
+// Glyphs are matched by name. For Multiple Master fonts, the AFM advances
+// are only used for the default instance.
+// The AFM metrics, always expressed in 1/1000 em, are scaled to the
+// units of the font (see `Font.Upem`).
+func (f *Font) AttachAFM(afm AFMFont) {
+	glyphs := make(map[string]fonts.GID, len(f.charstrings))
+	for gid, charstring := range f.charstrings {
+		glyphs[charstring.name] = fonts.GID(gid)
+	}
+
+	scale := Fl(f.Upem()) / 1000
+	out := afmMetrics{
+		advances:           make(map[fonts.GID]Fl, len(afm.CharMetrics)),
+		kerns:              make(map[[2]fonts.GID]int16),
+		bbox:               [4]Fl{afm.Llx * scale, afm.Lly * scale, afm.Urx * scale, afm.Ury * scale},
+		ascender:           afm.Ascender * scale,
+		descender:          afm.Descender * scale,
+		capHeight:          afm.CapHeight * scale,
+		xHeight:            Fl(afm.XHeight) * scale,
+		hasCapHeight:       afm.hasCapHeight,
+		hasXHeight:         afm.hasXHeight,
+		underlinePosition:  Fl(afm.UnderlinePosition) * scale,
+		underlineThickness: Fl(afm.UnderlineThickness) * scale,
+	}
+	// some AFM files do not provide Ascender and Descender
+	if out.ascender == 0 && out.descender == 0 {
+		out.ascender, out.descender = out.bbox[3], out.bbox[1]
+	}
+	for name, metric := range afm.CharMetrics {
+		if gid, ok := glyphs[name]; ok {
+			out.advances[gid] = Fl(metric.Width) * scale
+		}
+	}
+	for first, pairs := range afm.KernPairs {
+		left, ok := glyphs[first]
+		if !ok {
+			continue
+		}
+		for _, pair := range pairs {
+			if right, ok := glyphs[pair.SndChar]; ok {
+				out.kerns[[2]fonts.GID{left, right}] = int16(math.Round(float64(Fl(pair.KerningDistance) * scale)))
+			}
+		}
+	}
+	f.afm = &out
+}
@@ -129,8 +129,10 @@ func (f *AFMFont) parse(source io.Reader) error {
 			f.encodingScheme, err = readToken(tok, 1)
 		case "CapHeight":
 			f.CapHeight, err = readFloatToken(tok, 1)
+			f.hasCapHeight = true
 		case "XHeight":
 			f.XHeight, err = readIntToken(tok, 1)
+			f.hasXHeight = true
 		case "Ascender":
 			f.Ascender, err = readFloatToken(tok, 1)
 		case "Descender":
@@ -3,6 +3,7 @@ package type1
 import (
 	"errors"
 	"fmt"
+	"math"
 
 	ps "github.com/benoitkugler/textlayout/fonts/psinterpreter"
 )
@@ -28,6 +29,8 @@ type type1CharstringParser struct {
 	inFlex bool // alter the behavior of moveto opcodes
 
 	leftBearing, advance ps.Point
+
+	weights []Fl // weight vector of Multiple Master fonts, used by the blend other subrs
 }
 
 func (type1CharstringParser) Context() ps.PsContext { return ps.Type1Charstring }
@@ -164,9 +167,11 @@ func (met *type1CharstringParser) otherSub(state *ps.Machine) error {
 	}
 	index := state.ArgStack.Pop() // index
 	nbArgs := state.ArgStack.Pop()
-	state.ArgStack.PopN(nbArgs)
+	if err := state.ArgStack.PopN(nbArgs); err != nil {
+		return err
+	}
 
-	// we only support the Flex feature
+	// we only support the Flex and blend features
 	switch index {
 	case 0: // end flex
 		met.inFlex = false
@@ -209,6 +214,28 @@ func (met *type1CharstringParser) otherSub(state *ps.Machine) error {
 			return fmt.Errorf("invalid number of arguments for StartFlex other sub: %d", nbArgs)
 		}
 		// implemented in the moveto op codes
+	case 14, 15, 16, 17, 18: // blend 1, 2, 3, 4 or 6 values
+		nbMasters := int32(len(met.weights))
+		if nbMasters == 0 {
+			return errors.New("blend other sub used in a non Multiple Master font")
+		}
+		nbPoints := [...]int32{1, 2, 3, 4, 6}[index-14]
+		if nbArgs != nbPoints*nbMasters {
+			return fmt.Errorf("invalid number of arguments for blend other sub: %d", nbArgs)
+		}
+		// the arguments are the values for the first master, followed by
+		// the deltas for the other masters: compute
+		// 	a0 + (a1-a0)*w1 + ... + (ak-a0)*wk
+		values := state.ArgStack.Vals[state.ArgStack.Top : state.ArgStack.Top+nbArgs]
+		deltas := values[nbPoints:]
+		for i := range values[:nbPoints] {
+			v := Fl(values[i])
+			for _, weight := range met.weights[1:] {
+				v += Fl(deltas[0]) * weight
+				deltas = deltas[1:]
+			}
+			values[i] = int32(math.Round(float64(v)))
+		}
 	default:
 		// not handled
 	}
@@ -0,0 +1,687 @@
+package type1
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"unicode/utf16"
+
+	tk "github.com/benoitkugler/pstokenizer"
+	"github.com/benoitkugler/textlayout/fonts"
+	ps "github.com/benoitkugler/textlayout/fonts/psinterpreter"
+)
+
+// this file implements CID-keyed Type 1 fonts (CIDFontType 0)
+// See the Adobe Technical Note #5014 "Adobe CMap and CIDFont Files Specification"
+
+var _ fonts.Face = (*CIDFont)(nil)
+
+// CIDSystemInfo identifies the character collection
+// used by a CID font.
+type CIDSystemInfo struct {
+	Registry   string
+	Ordering   string
+	Supplement int
+}
+
+// FontDict is one of the Type 1 font dictionaries
+// of a CID font (see `CIDFont.FDArray`).
+type FontDict struct {
+	FontName   string
+	FontMatrix []Fl
+
+	subrs [][]byte // local subroutines, decrypted
+	lenIV int      // number of random bytes at start of charstrings
+
+	// scale from the glyph space of the dict to
+	// the font units of the CID font
+	scaleX, scaleY Fl
+}
+
+type cidGlyph struct {
+	data []byte // decrypted charstring, nil for missing glyphs
+	fd   int    // index into FDArray
+}
+
+// CIDFont exposes the content of a CID-keyed Type 1 font (CIDFontType 0),
+// whose glyphs are shared between several Type 1 font dictionaries.
+//
+// Glyphs are identified by their CID, that is, a GID is a CID.
+// By default, the font has no Unicode cmap, since the
+// meaning of CIDs is defined by an external character collection
+// (see `CIDSystemInfo`): use `LoadCMap` to provide one.
+type CIDFont struct {
+	cmap fonts.CmapSimple // see LoadCMap
+
+	CIDSystemInfo CIDSystemInfo
+	CIDFontName   string
+	FontBBox      []Fl
+	FontMatrix    []Fl // top level matrix, applied after the one of each FontDict
+	FDArray       []FontDict
+
+	glyphs []cidGlyph // indexed by CID
+
+	fonts.PSInfo
+
+	UIDBase int
+}
+
+// ParseCIDFont parses a CID-keyed Type 1 font file (CIDFontType 0),
+// in binary or hexadecimal format.
+func ParseCIDFont(file fonts.Resource) (*CIDFont, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	out, err := parseCIDFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CID font file: %s", err)
+	}
+	return out, nil
+}
+
+// isCIDFont returns true if the header of `file` indicates
+// a CID-keyed font.
+func isCIDFont(file fonts.Resource) bool {
+	var header [512]byte
+	n, _ := file.ReadAt(header[:], 0)
+	return bytes.Contains(header[:n], []byte("Resource-CIDFont")) ||
+		bytes.Contains(header[:n], []byte("/CIDFontType"))
+}
+
+func parseCIDFont(data []byte) (*CIDFont, error) {
+	const startData = "StartData"
+	index := bytes.Index(data, []byte(startData))
+	if index == -1 {
+		return nil, errors.New("missing StartData operator")
+	}
+
+	tokens, err := tk.Tokenize(data[:index])
+	if err != nil {
+		return nil, err
+	}
+	// (Binary) <length> StartData or (Hex) <length> StartData
+	if len(tokens) < 2 || tokens[len(tokens)-1].Kind != tk.Integer || tokens[len(tokens)-2].Kind != tk.String {
+		return nil, errors.New("invalid StartData arguments")
+	}
+	isHex := string(tokens[len(tokens)-2].Value) == "Hex"
+	length, _ := tokens[len(tokens)-1].Int()
+
+	// exactly one whitespace after StartData
+	start := index + len(startData) + 1
+	if start > len(data) {
+		return nil, errors.New("missing binary data")
+	}
+	binary := data[start:]
+	if isHex {
+		// the length may refer to the hexadecimal characters
+		binary = hexToBinary(binary)
+		if length > len(binary) {
+			length = len(binary)
+		}
+	}
+	if length < 0 || length > len(binary) {
+		return nil, fmt.Errorf("invalid binary data length %d", length)
+	}
+	binary = binary[:length]
+
+	// the top level dictionary may be split by 'end' operators
+	dict := psDict{}
+	for i := 0; i < len(tokens); {
+		var sub psDict
+		sub, i = parsePSDict(tokens, i)
+		for key, value := range sub {
+			dict[key] = value
+		}
+	}
+	return newCIDFont(dict, binary)
+}
+
+// psDict is a (simplified) PostScript dictionary.
+// Values are either []tk.Token (for simple values, arrays and procedures),
+// psDict (for nested dictionaries), or []psDict (for arrays of dictionaries)
+type psDict map[string]interface{}
+
+// parsePSDict reads the 'key value def' entries until an 'end' operator (or EOF),
+// ignoring the other tokens.
+// It returns the index of the token following 'end'
+func parsePSDict(tokens []tk.Token, i int) (psDict, int) {
+	out := psDict{}
+	for i < len(tokens) {
+		token := tokens[i]
+		if token.IsOther("end") {
+			return out, i + 1
+		}
+		if token.Kind != tk.Name {
+			i++
+			continue
+		}
+		value, j := parsePSValue(tokens, i+1)
+		for j < len(tokens) && (tokens[j].IsOther("readonly") || tokens[j].IsOther("noaccess") ||
+			tokens[j].IsOther("executeonly") || tokens[j].IsOther("bind")) {
+			j++
+		}
+		if value == nil || j >= len(tokens) || !tokens[j].IsOther("def") {
+			i++ // not an entry: just skip the name
+			continue
+		}
+		out[string(token.Value)] = value
+		i = j + 1
+	}
+	return out, i
+}
+
+// parsePSValue returns nil if no value is found
+func parsePSValue(tokens []tk.Token, i int) (interface{}, int) {
+	if i >= len(tokens) {
+		return nil, i
+	}
+	token := tokens[i]
+	switch token.Kind {
+	case tk.Integer:
+		if i+1 < len(tokens) && tokens[i+1].IsOther("dict") {
+			// <n> dict [dup] begin ... end
+			j := i + 2
+			if j < len(tokens) && tokens[j].IsOther("dup") {
+				j++
+			}
+			if j >= len(tokens) || !tokens[j].IsOther("begin") {
+				return nil, i
+			}
+			return parsePSDict(tokens, j+1)
+		}
+		if i+1 < len(tokens) && tokens[i+1].IsOther("array") {
+			// <n> array dup <index> <dict> put ...
+			n, _ := token.Int()
+			if n < 0 || n > len(tokens) {
+				return nil, i
+			}
+			out := make([]psDict, n)
+			j := i + 2
+			for j+2 < len(tokens) && tokens[j].IsOther("dup") && tokens[j+1].Kind == tk.Integer {
+				index, _ := tokens[j+1].Int()
+				value, next := parsePSValue(tokens, j+2)
+				dict, ok := value.(psDict)
+				if !ok || index < 0 || index >= n {
+					return nil, i
+				}
+				out[index] = dict
+				for j = next; j < len(tokens) && !tokens[j].IsOther("put"); j++ {
+				}
+				j++
+			}
+			return out, j
+		}
+	case tk.StartArray, tk.StartProc:
+		end := tk.EndArray
+		if token.Kind == tk.StartProc {
+			end = tk.EndProc
+		}
+		depth := 0
+		for j := i; j < len(tokens); j++ {
+			switch tokens[j].Kind {
+			case token.Kind:
+				depth++
+			case end:
+				depth--
+				if depth == 0 {
+					return tokens[i : j+1], j + 1
+				}
+			}
+		}
+		return nil, i
+	}
+	return tokens[i : i+1], i + 1
+}
+
+// returns the integer at `key`, or `defaut`
+func (dict psDict) int(key string, defaut int) int {
+	if value, ok := dict[key].([]tk.Token); ok && len(value) != 0 {
+		if v, err := value[0].Int(); err == nil {
+			return v
+		}
+	}
+	return defaut
+}
+
+// returns the string or name at `key`
+func (dict psDict) string(key string) string {
+	if value, ok := dict[key].([]tk.Token); ok && len(value) != 0 {
+		return string(value[0].Value)
+	}
+	return ""
+}
+
+func (dict psDict) numbers(key string) []Fl {
+	value, ok := dict[key].([]tk.Token)
+	// some fonts use procedures instead of arrays
+	if !ok || len(value) == 0 || (value[0].Kind != tk.StartArray && value[0].Kind != tk.StartProc) {
+		return nil
+	}
+	var p parser
+	out, _ := p.arrayToNumbers(value)
+	return out
+}
+
+// readBigEndian reads `size` bytes at `offset`, or returns false
+func readBigEndian(data []byte, offset, size int) (int, bool) {
+	if offset < 0 || offset+size > len(data) {
+		return 0, false
+	}
+	var out int
+	for _, b := range data[offset : offset+size] {
+		out = out<<8 | int(b)
+	}
+	return out, true
+}
+
+// readOffsets reads the `count`+1 offsets of `size` bytes at `offset`
+// and returns the data they delimit
+func readOffsets(data []byte, offset, count, size int) ([][]byte, error) {
+	if count == 0 {
+		return nil, nil
+	}
+	if size < 1 || size > 4 {
+		return nil, fmt.Errorf("invalid offset size %d", size)
+	}
+	// avoid overflows in the bounds check
+	if count < 0 || offset < 0 || offset > len(data) || count > (len(data)-offset)/size-1 {
+		return nil, fmt.Errorf("invalid offsets count %d", count)
+	}
+	offsets := make([]int, count+1)
+	for i := range offsets {
+		var ok bool
+		offsets[i], ok = readBigEndian(data, offset+i*size, size)
+		if !ok {
+			return nil, errors.New("invalid offsets (EOF)")
+		}
+	}
+	out := make([][]byte, count)
+	for i := range out {
+		start, end := offsets[i], offsets[i+1]
+		if start > end || end > len(data) {
+			return nil, fmt.Errorf("invalid offsets %d, %d", start, end)
+		}
+		out[i] = data[start:end]
+	}
+	return out, nil
+}
+
+func newCIDFont(dict psDict, binary []byte) (*CIDFont, error) {
+	if fontType := dict.int("CIDFontType", 0); fontType != 0 {
+		return nil, fmt.Errorf("unsupported CIDFontType %d", fontType)
+	}
+	var out CIDFont
+	out.CIDFontName = dict.string("CIDFontName")
+	out.FontBBox = dict.numbers("FontBBox")
+	out.FontMatrix = dict.numbers("FontMatrix")
+	out.UIDBase = dict.int("UIDBase", 0)
+	if info, ok := dict["CIDSystemInfo"].(psDict); ok {
+		out.CIDSystemInfo = CIDSystemInfo{
+			Registry:   info.string("Registry"),
+			Ordering:   info.string("Ordering"),
+			Supplement: info.int("Supplement", 0),
+		}
+	}
+	if info, ok := dict["FontInfo"].(psDict); ok {
+		tokens := map[string][]tk.Token{}
+		for key, value := range info {
+			if v, ok := value.([]tk.Token); ok && len(v) != 0 {
+				tokens[key] = v
+			}
+		}
+		var p parser
+		out.PSInfo = p.readFontInfo(tokens)
+	}
+	out.PSInfo.FontName = out.CIDFontName
+
+	fdArray, _ := dict["FDArray"].([]psDict)
+	if len(fdArray) == 0 {
+		return nil, errors.New("missing FDArray")
+	}
+	out.FDArray = make([]FontDict, len(fdArray))
+	for i, fdDict := range fdArray {
+		fd := &out.FDArray[i]
+		fd.FontName = fdDict.string("FontName")
+		fd.FontMatrix = fdDict.numbers("FontMatrix")
+
+		private, _ := fdDict["Private"].(psDict)
+		fd.lenIV = private.int("lenIV", 4)
+		if fd.lenIV < -1 { // -1 means no encryption
+			return nil, fmt.Errorf("invalid lenIV %d for font dict %d", fd.lenIV, i)
+		}
+		subrs, err := readOffsets(binary, private.int("SubrMapOffset", 0),
+			private.int("SubrCount", 0), private.int("SDBytes", 0))
+		if err != nil {
+			return nil, fmt.Errorf("invalid subroutines for font dict %d: %s", i, err)
+		}
+		fd.subrs = make([][]byte, len(subrs))
+		for j, subr := range subrs {
+			// decryption is done in place, and the data may be shared
+			fd.subrs[j] = decrypt(append([]byte(nil), subr...), CHARSTRING_KEY, fd.lenIV)
+		}
+	}
+	out.setFontDictScales()
+
+	// CIDMap
+	fdBytes, gdBytes := dict.int("FDBytes", 1), dict.int("GDBytes", 0)
+	cidCount := dict.int("CIDCount", 0)
+	if cidCount <= 0 || gdBytes <= 0 || fdBytes < 0 || fdBytes > 4 || gdBytes > 4 {
+		return nil, errors.New("invalid CIDMap parameters")
+	}
+	mapOffset, entrySize := dict.int("CIDMapOffset", 0), fdBytes+gdBytes
+	// avoid overflows in the bounds check
+	if mapOffset < 0 || mapOffset > len(binary) || cidCount > (len(binary)-mapOffset)/entrySize-1 {
+		return nil, errors.New("invalid CIDMap (EOF)")
+	}
+	out.glyphs = make([]cidGlyph, cidCount)
+	for cid := range out.glyphs {
+		entry := mapOffset + cid*entrySize
+		fd, _ := readBigEndian(binary, entry, fdBytes)
+		start, _ := readBigEndian(binary, entry+fdBytes, gdBytes)
+		end, _ := readBigEndian(binary, entry+entrySize+fdBytes, gdBytes)
+		if start >= end || end > len(binary) || fd >= len(out.FDArray) {
+			continue // missing glyph
+		}
+		data := append([]byte(nil), binary[start:end]...)
+		out.glyphs[cid] = cidGlyph{data: decrypt(data, CHARSTRING_KEY, out.FDArray[fd].lenIV), fd: fd}
+	}
+
+	out.cmap = fonts.CmapSimple{}
+	return &out, nil
+}
+
+// matrixScales returns the horizontal and vertical
+// scales of the product of the given matrices
+func matrixScales(fdMatrix, topMatrix []Fl) (sx, sy Fl) {
+	sx, sy = 0.001, 0.001 // default for Type1 fonts
+	if len(fdMatrix) >= 4 {
+		sx, sy = fdMatrix[0], fdMatrix[3]
+	}
+	if len(topMatrix) >= 4 {
+		sx, sy = sx*topMatrix[0], sy*topMatrix[3]
+	}
+	return sx, sy
+}
+
+// the font units are defined by the first font dict:
+// scale the glyphs of the other ones
+func (f *CIDFont) setFontDictScales() {
+	refX, refY := matrixScales(f.FDArray[0].FontMatrix, f.FontMatrix)
+	for i := range f.FDArray {
+		fd := &f.FDArray[i]
+		fd.scaleX, fd.scaleY = 1, 1
+		if sx, sy := matrixScales(fd.FontMatrix, f.FontMatrix); refX != 0 && refY != 0 {
+			fd.scaleX, fd.scaleY = sx/refX, sy/refY
+		}
+	}
+}
+
+func (f *CIDFont) PostscriptInfo() (fonts.PSInfo, bool) { return f.PSInfo, true }
+
+func (f *CIDFont) PoscriptName() string { return f.CIDFontName }
+
+func (f *CIDFont) LoadSummary() (fonts.FontSummary, error) {
+	styleName := f.PSInfo.Weight
+	if styleName == "" {
+		styleName = "Regular"
+	}
+	return fonts.FontSummary{
+		IsItalic:          f.PSInfo.ItalicAngle != 0,
+		IsBold:            f.PSInfo.Weight == "Bold" || f.PSInfo.Weight == "Black",
+		Familly:           f.PSInfo.FamilyName,
+		Style:             styleName,
+		HasScalableGlyphs: true,
+	}, nil
+}
+
+func (CIDFont) LoadBitmaps() []fonts.BitmapSize { return nil }
+
+// NumGlyphs returns the number of CIDs of the font, including
+// the missing ones.
+func (f *CIDFont) NumGlyphs() int { return len(f.glyphs) }
+
+// Cmap returns the Unicode cmap loaded by `LoadCMap`,
+// or an empty cmap.
+func (f *CIDFont) Cmap() (fonts.Cmap, fonts.CmapEncoding) {
+	if len(f.cmap) == 0 {
+		return f.cmap, fonts.EncOther
+	}
+	return f.cmap, fonts.EncUnicode
+}
+
+func (f *CIDFont) NominalGlyph(ch rune) (fonts.GID, bool) {
+	out, ok := f.cmap[ch]
+	return out, ok
+}
+
+// LoadCMap reads a CMap file and uses it to build the Unicode
+// cmap of the font, replacing the existing one.
+// Two kinds of CMaps are supported:
+//   - Unicode to CID mappings (like 'UniJIS-UTF16-H'), using the
+//     'cidchar' and 'cidrange' sections, where codes are
+//     UTF-16BE or UTF-32BE encoded,
+//   - CID to Unicode mappings (like 'Adobe-Japan1-UCS2'), using the
+//     'bfchar' and 'bfrange' sections.
+//
+// Other sections, and the 'usecmap' operator, are ignored.
+// CIDs which are not in the font are not added.
+func (f *CIDFont) LoadCMap(file fonts.Resource) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	tokens, err := tk.Tokenize(data)
+	if err != nil {
+		return fmt.Errorf("invalid CMap file: %s", err)
+	}
+	cmap := fonts.CmapSimple{}
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind != tk.Other {
+			continue
+		}
+		switch op := string(tokens[i].Value); op {
+		case "begincidchar", "begincidrange", "beginbfchar", "beginbfrange":
+			entrySize := 2
+			if op == "begincidrange" || op == "beginbfrange" {
+				entrySize = 3
+			}
+			j := i + 1
+			for j+entrySize <= len(tokens) && tokens[j].Kind == tk.StringHex {
+				if last := tokens[j+entrySize-1]; last.Kind == tk.StartArray {
+					// array of destinations in bfrange: not supported, skip it
+					for j += entrySize; j < len(tokens) && tokens[j].Kind != tk.EndArray; j++ {
+					}
+					j++
+					continue
+				}
+				f.addCMapEntry(cmap, op, tokens[j:j+entrySize])
+				j += entrySize
+			}
+			i = j
+		}
+	}
+	f.cmap = cmap
+	return nil
+}
+
+// decodeCMapCode returns the Unicode code point
+// stored in `code` (UTF-16BE or UTF-32BE encoded)
+func decodeCMapCode(code []byte) (rune, bool) {
+	switch len(code) {
+	case 1:
+		return rune(code[0]), true
+	case 2:
+		return rune(code[0])<<8 | rune(code[1]), true
+	case 4:
+		r1, r2 := rune(code[0])<<8|rune(code[1]), rune(code[2])<<8|rune(code[3])
+		if utf16.IsSurrogate(r1) {
+			return utf16.DecodeRune(r1, r2), true
+		}
+		return r1<<16 | r2, true
+	default:
+		return 0, false
+	}
+}
+
+func (f *CIDFont) addCMapEntry(cmap fonts.CmapSimple, op string, entry []tk.Token) {
+	add := func(r rune, cid int) {
+		if cid >= 0 && cid < len(f.glyphs) && f.glyphs[cid].data != nil {
+			cmap[r] = fonts.GID(cid)
+		}
+	}
+	switch op {
+	case "begincidchar": // <unicode> cid
+		r, ok := decodeCMapCode(entry[0].Value)
+		cid, err := entry[1].Int()
+		if ok && err == nil {
+			add(r, cid)
+		}
+	case "begincidrange": // <unicode start> <unicode end> cid
+		start, ok1 := decodeCMapCode(entry[0].Value)
+		end, ok2 := decodeCMapCode(entry[1].Value)
+		cid, err := entry[2].Int()
+		if !ok1 || !ok2 || err != nil || end-start > 0xFFFF {
+			return
+		}
+		for r := start; r <= end; r++ {
+			add(r, cid+int(r-start))
+		}
+	case "beginbfchar": // <cid> <unicode>
+		cid, ok1 := readBigEndian(entry[0].Value, 0, len(entry[0].Value))
+		r, ok2 := decodeCMapCode(entry[1].Value)
+		if ok1 && ok2 && entry[1].Kind == tk.StringHex {
+			add(r, cid)
+		}
+	case "beginbfrange": // <cid start> <cid end> <unicode start>
+		start, ok1 := readBigEndian(entry[0].Value, 0, len(entry[0].Value))
+		end, ok2 := readBigEndian(entry[1].Value, 0, len(entry[1].Value))
+		r, ok3 := decodeCMapCode(entry[2].Value)
+		if !ok1 || !ok2 || !ok3 || entry[1].Kind != tk.StringHex ||
+			entry[2].Kind != tk.StringHex || end-start > 0xFFFF {
+			return
+		}
+		for cid := start; cid <= end; cid++ {
+			add(r+rune(cid-start), cid)
+		}
+	}
+}
+
+// loadGlyph returns the outlines, bounds and advance of the glyph with CID `cid`,
+// expressed in font units.
+func (f *CIDFont) loadGlyph(cid fonts.GID) ([]fonts.Segment, ps.PathBounds, int32, error) {
+	if int(cid) >= len(f.glyphs) || f.glyphs[cid].data == nil {
+		return nil, ps.PathBounds{}, 0, fmt.Errorf("invalid CID %d", cid)
+	}
+	glyph := f.glyphs[cid]
+	fd := f.FDArray[glyph.fd]
+
+	var (
+		psi    ps.Machine
+		parser type1CharstringParser
+	)
+	if err := psi.Run(glyph.data, fd.subrs, nil, &parser); err != nil {
+		return nil, ps.PathBounds{}, 0, err
+	}
+	if parser.seac != nil {
+		return nil, ps.PathBounds{}, 0, errors.New("seac operator is not allowed in CID fonts")
+	}
+
+	segments, bounds, advance := parser.cs.Segments, parser.cs.Bounds, parser.advance.X
+	if fd.scaleX != 1 || fd.scaleY != 1 {
+		for i := range segments {
+			args := segments[i].ArgsSlice()
+			for j := range args {
+				args[j].X *= fd.scaleX
+				args[j].Y *= fd.scaleY
+			}
+		}
+		scale := func(v int32, s Fl) int32 { return int32(math.Round(float64(Fl(v) * s))) }
+		bounds.Min = ps.Point{X: scale(bounds.Min.X, fd.scaleX), Y: scale(bounds.Min.Y, fd.scaleY)}
+		bounds.Max = ps.Point{X: scale(bounds.Max.X, fd.scaleX), Y: scale(bounds.Max.Y, fd.scaleY)}
+		advance = scale(advance, fd.scaleX)
+	}
+	return segments, bounds, advance, nil
+}
+
+// font metrics
+
+var _ fonts.FaceMetrics = (*CIDFont)(nil)
+
+// Upem is computed from the FontMatrix of the first font dict,
+// and the top level FontMatrix.
+func (f *CIDFont) Upem() uint16 {
+	sx, sy := matrixScales(f.FDArray[0].FontMatrix, f.FontMatrix)
+	return upemFromScales(sx, sy)
+}
+
+// GlyphName always returns an empty string, since
+// the glyphs of a CID font have no name.
+func (f *CIDFont) GlyphName(gid fonts.GID) string { return "" }
+
+func (f *CIDFont) LineMetric(metric fonts.LineMetric) (float32, bool) {
+	return lineMetric(f.PSInfo, metric)
+}
+
+func (f *CIDFont) FontHExtents() (fonts.FontExtents, bool) {
+	return fontHExtents(f.FontBBox, f.Upem())
+}
+
+// FontVExtents returns zero values.
+func (f *CIDFont) FontVExtents() (fonts.FontExtents, bool) {
+	return fonts.FontExtents{}, false
+}
+
+// HorizontalAdvance returns the advance of the glyph with CID `gid`,
+// in font units, or 0 for invalid or missing glyphs.
+func (f *CIDFont) HorizontalAdvance(gid fonts.GID) float32 {
+	_, _, adv, err := f.loadGlyph(gid)
+	if err != nil {
+		return 0
+	}
+	return float32(adv)
+}
+
+func (f *CIDFont) VerticalAdvance(gid fonts.GID) float32 { return 0 }
+
+// GlyphHOrigin always return 0,0,true
+func (CIDFont) GlyphHOrigin(fonts.GID) (x, y int32, found bool) {
+	return 0, 0, true
+}
+
+// GlyphVOrigin always return 0,0,false
+func (CIDFont) GlyphVOrigin(fonts.GID) (x, y int32, found bool) {
+	return 0, 0, false
+}
+
+func (f *CIDFont) GlyphExtents(glyph fonts.GID, _, _ uint16) (fonts.GlyphExtents, bool) {
+	_, bbox, _, err := f.loadGlyph(glyph)
+	if err != nil {
+		return fonts.GlyphExtents{}, false
+	}
+	return bbox.ToExtents(), true
+}
+
+func (CIDFont) NormalizeVariations(coords []float32) []float32 { return coords }
+
+var _ fonts.FaceRenderer = (*CIDFont)(nil)
+
+// GlyphData returns the outlines of the given glyph.
+// The returned value is either a fonts.GlyphOutline or nil if an error
+// occured.
+func (f *CIDFont) GlyphData(gid fonts.GID, _, _ uint16) fonts.GlyphData {
+	segments, _, _, err := f.loadGlyph(gid)
+	if err != nil {
+		return nil
+	}
+	return fonts.GlyphOutline{Segments: segments}
+}
@@ -0,0 +1,333 @@
+package type1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+
+	testdata "github.com/benoitkugler/textlayout-testdata/type1"
+	"github.com/benoitkugler/textlayout/fonts"
+	ps "github.com/benoitkugler/textlayout/fonts/psinterpreter"
+)
+
+// encrypt is the inverse of decrypt, with 4 random bytes
+func encrypt(plain []byte, r uint16) []byte {
+	const (
+		c1 uint16 = 52845
+		c2 uint16 = 22719
+	)
+	out := make([]byte, 0, len(plain)+4)
+	for _, p := range append([]byte{0, 0, 0, 0}, plain...) {
+		c := p ^ byte(r>>8)
+		r = (uint16(c)+r)*c1 + c2
+		out = append(out, c)
+	}
+	return out
+}
+
+// buildCIDFont returns a CID font file using the glyphs and subroutines
+// of `font`, with two font dicts: even CIDs use the first one (not encrypted),
+// odd CIDs use the second one (encrypted, with a smaller scale)
+func buildCIDFont(font *Font, asHex bool) []byte {
+	const fdBytes, gdBytes, sdBytes = 1, 4, 4
+	cidCount := len(font.charstrings)
+
+	// layout: CIDMap, SubrMap for FD 0 and 1, subrs for FD 0 and 1, charstrings
+	subrMapOffset0 := (cidCount + 1) * (fdBytes + gdBytes)
+	subrMapOffset1 := subrMapOffset0 + (len(font.subrs)+1)*sdBytes
+	dataOffset := subrMapOffset1 + (len(font.subrs)+1)*sdBytes
+
+	var cidMap, subrMap0, subrMap1, data []byte
+	putOffset := func(buf []byte) []byte {
+		var tmp [4]byte
+		binary.BigEndian.PutUint32(tmp[:], uint32(dataOffset+len(data)))
+		return append(buf, tmp[:]...)
+	}
+	for _, subr := range font.subrs {
+		subrMap0 = putOffset(subrMap0)
+		data = append(data, subr...)
+	}
+	subrMap0 = putOffset(subrMap0)
+	for _, subr := range font.subrs {
+		subrMap1 = putOffset(subrMap1)
+		data = append(data, encrypt(subr, CHARSTRING_KEY)...)
+	}
+	subrMap1 = putOffset(subrMap1)
+	for cid, glyph := range font.charstrings {
+		cidMap = append(cidMap, byte(cid%2))
+		cidMap = putOffset(cidMap)
+		if cid%2 == 0 {
+			data = append(data, glyph.data...)
+		} else {
+			data = append(data, encrypt(glyph.data, CHARSTRING_KEY)...)
+		}
+	}
+	cidMap = append(cidMap, 0)
+	cidMap = putOffset(cidMap)
+
+	binaryData := append(append(append(cidMap, subrMap0...), subrMap1...), data...)
+
+	var header strings.Builder
+	fmt.Fprintf(&header, `%%!PS-Adobe-3.0 Resource-CIDFont
+%%%%DocumentNeededResources: ProcSet (CIDInit)
+/CIDInit /ProcSet findresource begin
+20 dict begin
+/CIDFontName /Test-CID def
+/CIDFontType 0 def
+/CIDSystemInfo 3 dict dup begin
+  /Registry (Adobe) def
+  /Ordering (Identity) def
+  /Supplement 0 def
+end def
+/FontBBox {%g %g %g %g} bind def
+/FontMatrix [1 0 0 1 0 0] def
+/FontInfo 2 dict dup begin
+  /FamilyName (Test) def
+  /Weight (Bold) def
+end readonly def
+/UIDBase 12345 def
+/CIDMapOffset 0 def
+/FDBytes %d def
+/GDBytes %d def
+/CIDCount %d def
+/FDArray 2 array
+dup 0
+%%ADOBeginFontDict
+4 dict begin
+  /FontName /Test-CID-Even def
+  /FontMatrix [0.001 0 0 0.001 0 0] def
+  /Private 5 dict dup begin
+    /lenIV -1 def
+    /SubrMapOffset %d def
+    /SDBytes %d def
+    /SubrCount %d def
+  end def
+end put
+dup 1
+%%ADOBeginFontDict
+4 dict begin
+  /FontName /Test-CID-Odd def
+  /FontMatrix [0.0005 0 0 0.0005 0 0] def
+  /Private 5 dict dup begin
+    /SubrMapOffset %d def
+    /SDBytes %d def
+    /SubrCount %d def
+  end def
+end put
+def
+`, font.FontBBox[0], font.FontBBox[1], font.FontBBox[2], font.FontBBox[3],
+		fdBytes, gdBytes, cidCount,
+		subrMapOffset0, sdBytes, len(font.subrs),
+		subrMapOffset1, sdBytes, len(font.subrs))
+
+	if asHex {
+		encoded := hex.EncodeToString(binaryData)
+		fmt.Fprintf(&header, "(Hex) %d StartData\n%s", len(encoded), encoded)
+	} else {
+		fmt.Fprintf(&header, "(Binary) %d StartData\n%s", len(binaryData), binaryData)
+	}
+	header.WriteString("\n%%EndData\n%%EndResource\n")
+	return []byte(header.String())
+}
+
+func TestParseCIDFont(t *testing.T) {
+	b, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, asHex := range []bool{false, true} {
+		faces, err := Load(bytes.NewReader(buildCIDFont(ref, asHex)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		font, ok := faces[0].(*CIDFont)
+		if !ok {
+			t.Fatalf("unexpected face type %T", faces[0])
+		}
+
+		if font.CIDFontName != "Test-CID" || font.PoscriptName() != "Test-CID" {
+			t.Fatalf("unexpected font name %s", font.CIDFontName)
+		}
+		if exp := (CIDSystemInfo{Registry: "Adobe", Ordering: "Identity"}); font.CIDSystemInfo != exp {
+			t.Fatalf("unexpected system info %v", font.CIDSystemInfo)
+		}
+		if font.UIDBase != 12345 || font.PSInfo.FamilyName != "Test" {
+			t.Fatalf("unexpected font infos %d %v", font.UIDBase, font.PSInfo)
+		}
+		if summary, _ := font.LoadSummary(); !summary.IsBold {
+			t.Fatal("expected bold font")
+		}
+		if len(font.FDArray) != 2 || font.FDArray[1].FontName != "Test-CID-Odd" {
+			t.Fatalf("unexpected font dicts %v", font.FDArray)
+		}
+		if font.Upem() != 1000 || font.NumGlyphs() != len(ref.charstrings) {
+			t.Fatalf("unexpected upem or number of glyphs: %d %d", font.Upem(), font.NumGlyphs())
+		}
+		if _, enc := font.Cmap(); enc != fonts.EncOther {
+			t.Fatalf("unexpected cmap encoding %d", enc)
+		}
+
+		var nbGlyphs int
+		for cid := range ref.charstrings {
+			gid := fonts.GID(cid)
+			_, refBounds, refAdvance, err := ref.loadGlyph(gid, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var parser type1CharstringParser
+			if err := runCharstring(ref, gid, &parser); err != nil {
+				t.Fatal(err)
+			}
+
+			_, bounds, advance, err := font.loadGlyph(gid)
+			if parser.seac != nil {
+				if err == nil {
+					t.Fatal("expected error for seac operator")
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			nbGlyphs++
+
+			if cid%2 == 0 {
+				if advance != refAdvance || bounds != refBounds {
+					t.Fatalf("glyph %d: expected %d %v, got %d %v", cid, refAdvance, refBounds, advance, bounds)
+				}
+				if data, ok := font.GlyphData(gid, 0, 0).(fonts.GlyphOutline); !ok || (len(data.Segments) == 0) != (bounds == ps.PathBounds{}) {
+					t.Fatalf("glyph %d: missing outlines", cid)
+				}
+			} else {
+				if exp := int32(math.Round(float64(refAdvance) / 2)); advance != exp {
+					t.Fatalf("glyph %d: expected advance %d, got %d", cid, exp, advance)
+				}
+				if exp := int32(math.Round(float64(refBounds.Max.Y) / 2)); bounds.Max.Y != exp {
+					t.Fatalf("glyph %d: expected max y %d, got %d", cid, exp, bounds.Max.Y)
+				}
+			}
+		}
+		if nbGlyphs == 0 {
+			t.Fatal("no glyph tested")
+		}
+		if font.HorizontalAdvance(fonts.GID(len(ref.charstrings))) != 0 {
+			t.Fatal("expected 0 advance for invalid CID")
+		}
+	}
+}
+
+func runCharstring(font *Font, gid fonts.GID, parser *type1CharstringParser) error {
+	var psi ps.Machine
+	return psi.Run(font.charstrings[gid].data, font.subrs, nil, parser)
+}
+
+func TestLoadCMap(t *testing.T) {
+	b, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := ParseCIDFont(bytes.NewReader(buildCIDFont(ref, false)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	unicodeToCID := `%!PS-Adobe-3.0 Resource-CMap
+/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CMapName /Test-UTF16-H def
+/CMapType 1 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 begincidchar
+<0041> 10
+<D83DDE00> 11
+endcidchar
+1 begincidrange
+<0061> <0063> 20
+endcidrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`
+	if err := font.LoadCMap(strings.NewReader(unicodeToCID)); err != nil {
+		t.Fatal(err)
+	}
+	expected := fonts.CmapSimple{'A': 10, '😀': 11, 'a': 20, 'b': 21, 'c': 22}
+	if _, enc := font.Cmap(); enc != fonts.EncUnicode {
+		t.Fatalf("unexpected encoding %d", enc)
+	}
+	for r, cid := range expected {
+		if got, ok := font.NominalGlyph(r); !ok || got != cid {
+			t.Fatalf("rune %q: expected %d, got %d", r, cid, got)
+		}
+	}
+
+	cidToUnicode := `begincmap
+1 beginbfchar
+<000A> <0041>
+endbfchar
+2 beginbfrange
+<0014> <0016> <0061>
+<0017> <0018> [<0078> <0079>]
+endbfrange
+endcmap`
+	if err := font.LoadCMap(strings.NewReader(cidToUnicode)); err != nil {
+		t.Fatal(err)
+	}
+	delete(expected, '😀')
+	if len(font.cmap) != len(expected) {
+		t.Fatalf("unexpected cmap %v", font.cmap)
+	}
+	for r, cid := range expected {
+		if got, ok := font.NominalGlyph(r); !ok || got != cid {
+			t.Fatalf("rune %q: expected %d, got %d", r, cid, got)
+		}
+	}
+}
+
+func TestParseCIDFontInvalid(t *testing.T) {
+	b, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := buildCIDFont(ref, false)
+
+	for _, change := range []struct{ pattern, replacement string }{
+		{`/SubrCount \d+ def`, "/SubrCount -1 def"},
+		{`/SubrCount \d+ def`, "/SubrCount 4611686018427387903 def"},
+		{`/SDBytes \d+ def`, "/SDBytes 0 def"},
+		{`/SDBytes \d+ def`, "/SDBytes 9 def"},
+		{`/SubrMapOffset \d+ def`, "/SubrMapOffset -4 def"},
+		{`/CIDCount \d+ def`, "/CIDCount -1 def"},
+		{`/CIDCount \d+ def`, "/CIDCount 4611686018427387903 def"},
+		{`/CIDMapOffset \d+ def`, "/CIDMapOffset 4611686018427387903 def"},
+		{`/GDBytes \d+ def`, "/GDBytes 0 def"},
+		{`/lenIV -1 def`, "/lenIV -5 def"},
+	} {
+		re := regexp.MustCompile(change.pattern)
+		font := re.ReplaceAll(valid, []byte(change.replacement))
+		if _, err := ParseCIDFont(bytes.NewReader(font)); err == nil {
+			t.Fatalf("expected error for %s", change.replacement)
+		}
+	}
+}
@@ -15,7 +15,11 @@ func (f *Font) Upem() uint16 {
 	if len(f.FontMatrix) < 4 {
 		return 1000 // typical value for Type1 fonts
 	}
-	xx, yy := math.Abs(float64(f.FontMatrix[0])), math.Abs(float64(f.FontMatrix[3]))
+	return upemFromScales(f.FontMatrix[0], f.FontMatrix[3])
+}
+
+func upemFromScales(sx, sy Fl) uint16 {
+	xx, yy := math.Abs(float64(sx)), math.Abs(float64(sy))
 	var (
 		upemX uint16 = 1000
 		upemY        = upemX
@@ -39,29 +43,56 @@ func (f *Font) GlyphName(gid fonts.GID) string {
 	return f.charstrings[gid].name
 }
 
+// LineMetric returns the underline metrics of the font, and, when
+// an AFM file providing them has been attached, its CapHeight and XHeight.
 func (f *Font) LineMetric(metric fonts.LineMetric) (float32, bool) {
+	if f.afm != nil {
+		switch metric {
+		case fonts.UnderlinePosition:
+			return f.afm.underlinePosition, true
+		case fonts.UnderlineThickness:
+			return f.afm.underlineThickness, true
+		case fonts.CapHeight:
+			return f.afm.capHeight, f.afm.hasCapHeight
+		case fonts.XHeight:
+			return f.afm.xHeight, f.afm.hasXHeight
+		}
+	}
+	return lineMetric(f.PSInfo, metric)
+}
+
+func lineMetric(info fonts.PSInfo, metric fonts.LineMetric) (float32, bool) {
 	switch metric {
 	case fonts.UnderlinePosition:
-		return float32(f.PSInfo.UnderlinePosition), true
+		return float32(info.UnderlinePosition), true
 	case fonts.UnderlineThickness:
-		return float32(f.PSInfo.UnderlineThickness), true
+		return float32(info.UnderlineThickness), true
 	default:
 		// CapHeight and XHeight are stored in .afm files
 		return 0, false
 	}
 }
 
+// FontHExtents uses the Ascender and Descender of the AFM file, if
+// attached, or the FontBBox of the font.
 func (f *Font) FontHExtents() (fonts.FontExtents, bool) {
+	if f.afm != nil {
+		return fontHExtents([]Fl{f.afm.bbox[0], f.afm.descender, f.afm.bbox[2], f.afm.ascender}, f.Upem())
+	}
+	return fontHExtents(f.FontBBox, f.Upem())
+}
+
+func fontHExtents(fontBBox []Fl, upem uint16) (fonts.FontExtents, bool) {
 	var extents fonts.FontExtents
-	if len(f.FontBBox) < 4 {
+	if len(fontBBox) < 4 {
 		return extents, false
 	}
-	yMin, yMax := f.FontBBox[1], f.FontBBox[3]
+	yMin, yMax := fontBBox[1], fontBBox[3]
 	// following freetype here
 	extents.Ascender = float32(yMax)
 	extents.Descender = float32(yMin)
 
-	extents.LineGap = float32(f.Upem()) * 1.2
+	extents.LineGap = float32(upem) * 1.2
 	if extents.LineGap < extents.Ascender-extents.Descender {
 		extents.LineGap = extents.Ascender - extents.Descender
 	}
@@ -84,9 +115,15 @@ func (f *Font) NominalGlyph(ch rune) (fonts.GID, bool) {
 
 // HorizontalAdvance returns the advance of the glyph with index `index`
 // The return value is expressed in font units.
-// 0 is returned for invalid index values and for invalid
+// When an AFM file has been attached, its advances are used.
+// Otherwise, 0 is returned for invalid index values and for invalid
 // charstring glyph data.
 func (f *Font) HorizontalAdvance(gid fonts.GID) float32 {
+	if f.afm != nil && f.blend.coords == nil {
+		if adv, ok := f.afm.advances[gid]; ok {
+			return adv
+		}
+	}
 	_, _, adv, err := f.loadGlyph(gid, false)
 	if err != nil {
 		return 0
@@ -96,6 +133,15 @@ func (f *Font) HorizontalAdvance(gid fonts.GID) float32 {
 
 func (f *Font) VerticalAdvance(gid fonts.GID) float32 { return 0 }
 
+// KernPair returns the horizontal kerning for the given pair, in font units,
+// as defined in the AFM file attached with `AttachAFM`, or zero.
+func (f *Font) KernPair(left, right fonts.GID) int16 {
+	if f.afm == nil {
+		return 0
+	}
+	return f.afm.kerns[[2]fonts.GID{left, right}]
+}
+
 // GlyphHOrigin always return 0,0,true
 func (Font) GlyphHOrigin(fonts.GID) (x, y int32, found bool) {
 	return 0, 0, true
@@ -114,8 +160,6 @@ func (f *Font) GlyphExtents(glyph fonts.GID, _, _ uint16) (fonts.GlyphExtents, b
 	return bbox.ToExtents(), true
 }
 
-func (Font) NormalizeVariations(coords []float32) []float32 { return coords }
-
 var _ fonts.FaceRenderer = (*Font)(nil)
 
 // GlyphData returns the outlines of the given glyph.
@@ -0,0 +1,276 @@
+package type1
+
+import (
+	"errors"
+	"fmt"
+
+	tk "github.com/benoitkugler/pstokenizer"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+// this file implements Multiple Master fonts, following freetype (src/type1/t1load.c)
+// See the Adobe Technical Note #5015 "Type 1 Font Format Supplement"
+
+var _ truetype.FaceVariable = (*Font)(nil)
+
+// maximum number of axes of a Multiple Master font
+const maxMMAxes = 4
+
+type designMapPoint struct {
+	design, normalized Fl
+}
+
+// blend stores the Multiple Master informations of a font.
+// The number of masters is 2^(number of axes), and the masters
+// are assumed to be at the corners of the design space.
+type blend struct {
+	designPositions [][]Fl             // [master][axis], BlendDesignPositions
+	designMap       [][]designMapPoint // [axis], BlendDesignMap
+	defaultWeights  []Fl               // WeightVector
+
+	weights []Fl      // current weights, one per master
+	coords  []float32 // current blend coordinates, in [0,1], or nil for the default instance
+}
+
+func (b *blend) isMM() bool { return len(b.designPositions) != 0 }
+
+// readBlendInfo reads the Multiple Master entries of the /FontInfo dictionary.
+func (p *parser) readBlendInfo(fontInfo map[string][]tk.Token, font *Font) error {
+	if value, ok := fontInfo["BlendAxisTypes"]; ok {
+		font.BlendAxisTypes = nil
+		for _, token := range value {
+			if token.Kind == tk.Name {
+				font.BlendAxisTypes = append(font.BlendAxisTypes, string(token.Value))
+			}
+		}
+	}
+	if value, ok := fontInfo["BlendDesignPositions"]; ok {
+		for _, position := range splitArray(value) {
+			numbers, err := p.arrayToNumbers(position)
+			if err != nil {
+				return fmt.Errorf("invalid BlendDesignPositions: %s", err)
+			}
+			font.blend.designPositions = append(font.blend.designPositions, numbers)
+		}
+	}
+	if value, ok := fontInfo["BlendDesignMap"]; ok {
+		for _, axis := range splitArray(value) {
+			var axisMap []designMapPoint
+			for _, point := range splitArray(axis) {
+				numbers, err := p.arrayToNumbers(point)
+				if err != nil || len(numbers) != 2 {
+					return fmt.Errorf("invalid BlendDesignMap: %v", point)
+				}
+				axisMap = append(axisMap, designMapPoint{design: numbers[0], normalized: numbers[1]})
+			}
+			font.blend.designMap = append(font.blend.designMap, axisMap)
+		}
+	}
+	return nil
+}
+
+// splitArray returns the elements of `value`, which must
+// be an array (including its delimiters) of arrays.
+func splitArray(value []tk.Token) (out [][]tk.Token) {
+	if len(value) < 2 {
+		return nil
+	}
+	depth, start := 0, 0
+	for i, token := range value[1 : len(value)-1] {
+		switch token.Kind {
+		case tk.StartArray:
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case tk.EndArray:
+			depth--
+			if depth == 0 {
+				out = append(out, value[start:i+2])
+			}
+		}
+	}
+	return out
+}
+
+// checkBlend validates the Multiple Master informations,
+// and setup the default instance.
+func (f *Font) checkBlend() error {
+	b := &f.blend
+	if !b.isMM() {
+		return nil
+	}
+	nbAxes := len(b.designPositions[0])
+	if nbAxes == 0 || nbAxes > maxMMAxes || len(b.designPositions) != 1<<nbAxes {
+		return fmt.Errorf("unsupported Multiple Master design (%d masters)", len(b.designPositions))
+	}
+	for _, position := range b.designPositions {
+		if len(position) != nbAxes {
+			return errors.New("invalid BlendDesignPositions")
+		}
+	}
+	if len(f.BlendAxisTypes) != nbAxes {
+		return fmt.Errorf("invalid number of BlendAxisTypes: %d", len(f.BlendAxisTypes))
+	}
+	if b.designMap == nil {
+		// identity mapping
+		b.designMap = make([][]designMapPoint, nbAxes)
+		for i := range b.designMap {
+			b.designMap[i] = []designMapPoint{{0, 0}, {1, 1}}
+		}
+	}
+	if len(b.designMap) != nbAxes {
+		return fmt.Errorf("invalid number of BlendDesignMap: %d", len(b.designMap))
+	}
+	for _, axisMap := range b.designMap {
+		if len(axisMap) < 2 {
+			return errors.New("invalid BlendDesignMap")
+		}
+	}
+	if b.defaultWeights == nil {
+		// default to the first master
+		b.defaultWeights = make([]Fl, len(b.designPositions))
+		b.defaultWeights[0] = 1
+	}
+	if len(b.defaultWeights) != len(b.designPositions) {
+		return fmt.Errorf("invalid length for WeightVector: %d", len(b.defaultWeights))
+	}
+	b.weights = b.defaultWeights
+	return nil
+}
+
+// defaultCoords returns the blend coordinates matching the default weights.
+func (b *blend) defaultCoords() []float32 {
+	out := make([]float32, len(b.designMap))
+	for master, weight := range b.defaultWeights {
+		for axis := range out {
+			if master&(1<<axis) != 0 {
+				out[axis] += weight
+			}
+		}
+	}
+	return out
+}
+
+// setCoords updates the weight vector
+func (b *blend) setCoords(coords []float32) {
+	if coords == nil {
+		b.weights, b.coords = b.defaultWeights, nil
+		return
+	}
+	b.coords = make([]float32, len(b.designMap))
+	copy(b.coords, coords)
+	b.weights = make([]Fl, len(b.designPositions))
+	for master := range b.weights {
+		weight := Fl(1)
+		for axis, coord := range b.coords {
+			if coord < 0 {
+				coord = 0
+			} else if coord > 1 {
+				coord = 1
+			}
+			if master&(1<<axis) == 0 {
+				coord = 1 - coord
+			}
+			weight *= coord
+		}
+		b.weights[master] = weight
+	}
+}
+
+// interpolate the piecewise linear mapping defined by `points`,
+// from `in(point)` to `out(point)`
+func interpolateMap(points []designMapPoint, v Fl, in, out func(designMapPoint) Fl) Fl {
+	if v <= in(points[0]) {
+		return out(points[0])
+	}
+	for i := 1; i < len(points); i++ {
+		p0, p1 := points[i-1], points[i]
+		if v <= in(p1) {
+			if in(p1) == in(p0) {
+				return out(p1)
+			}
+			return out(p0) + (v-in(p0))*(out(p1)-out(p0))/(in(p1)-in(p0))
+		}
+	}
+	return out(points[len(points)-1])
+}
+
+func designOf(p designMapPoint) Fl     { return p.design }
+func normalizedOf(p designMapPoint) Fl { return p.normalized }
+
+// axisTag returns the OpenType tag matching the axis type
+func axisTag(axisType string) truetype.Tag {
+	switch axisType {
+	case "Weight":
+		return truetype.MustNewTag("wght")
+	case "Width":
+		return truetype.MustNewTag("wdth")
+	case "OpticalSize":
+		return truetype.MustNewTag("opsz")
+	default:
+		name := []byte(axisType + "    ")
+		return truetype.NewTag(name[0], name[1], name[2], name[3])
+	}
+}
+
+// Variations returns the axes of a Multiple Master font, in design units,
+// or an empty table for other fonts.
+// The axes are identified by the OpenType tags matching the
+// BlendAxisTypes entry ('wght', 'wdth', 'opsz'), or by the first four letters of
+// the axis type.
+func (f *Font) Variations() truetype.TableFvar {
+	if !f.blend.isMM() {
+		return truetype.TableFvar{}
+	}
+	defaultCoords := f.blend.defaultCoords()
+	var out truetype.TableFvar
+	instance := truetype.VarInstance{Coords: make([]float32, len(f.blend.designMap))}
+	for i, axisMap := range f.blend.designMap {
+		axis := truetype.VarAxis{
+			Tag:     axisTag(f.BlendAxisTypes[i]),
+			Minimum: axisMap[0].design,
+			Maximum: axisMap[len(axisMap)-1].design,
+			Default: interpolateMap(axisMap, defaultCoords[i], normalizedOf, designOf),
+		}
+		instance.Coords[i] = axis.Default
+		out.Axis = append(out.Axis, axis)
+	}
+	out.Instances = []truetype.VarInstance{instance}
+	return out
+}
+
+// NormalizeVariations converts design coordinates to blend coordinates,
+// in [0, 1], using the BlendDesignMap of a Multiple Master font.
+// It is a no-op for other fonts.
+// Note that, contrary to OpenType fonts, the default instance
+// is not mapped to 0.
+func (f *Font) NormalizeVariations(coords []float32) []float32 {
+	if !f.blend.isMM() {
+		return coords
+	}
+	out := make([]float32, len(coords))
+	for i, coord := range coords {
+		if i >= len(f.blend.designMap) {
+			break
+		}
+		out[i] = interpolateMap(f.blend.designMap[i], coord, designOf, normalizedOf)
+	}
+	return out
+}
+
+// SetVarCoordinates selects the instance of a Multiple Master font,
+// using blend coordinates (see `NormalizeVariations`).
+// Passing nil restores the default instance, defined by the WeightVector
+// of the font.
+// It is a no-op for other fonts.
+func (f *Font) SetVarCoordinates(coords []float32) {
+	if !f.blend.isMM() {
+		return
+	}
+	f.blend.setCoords(coords)
+}
+
+// VarCoordinates returns the current blend coordinates, or nil
+// for the default instance.
+func (f *Font) VarCoordinates() []float32 { return f.blend.coords }
@@ -0,0 +1,174 @@
+package type1
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	testdata "github.com/benoitkugler/textlayout-testdata/type1"
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+)
+
+// charstringBuilder encodes Type1 charstrings
+type charstringBuilder []byte
+
+func (cs *charstringBuilder) numbers(vs ...int32) *charstringBuilder {
+	for _, v := range vs {
+		if -107 <= v && v <= 107 {
+			*cs = append(*cs, byte(v+139))
+		} else {
+			var tmp [4]byte
+			binary.BigEndian.PutUint32(tmp[:], uint32(v))
+			*cs = append(append(*cs, 255), tmp[:]...)
+		}
+	}
+	return cs
+}
+
+func (cs *charstringBuilder) op(code byte) *charstringBuilder {
+	*cs = append(*cs, code)
+	return cs
+}
+
+func (cs *charstringBuilder) escOp(code byte) *charstringBuilder {
+	*cs = append(*cs, 12, code)
+	return cs
+}
+
+// callothersubr with its arguments
+func (cs *charstringBuilder) otherSub(index int32, args ...int32) *charstringBuilder {
+	return cs.numbers(args...).numbers(int32(len(args)), index).escOp(16)
+}
+
+// buildMMFont returns a Multiple Master font with one axis (Weight, from 200 to 900),
+// two masters and a default instance at 375.
+// The glyph 'a' is a rectangle, whose width and advance depend on the weight:
+// at 200, the advance is 500 and the width 100; at 900, the advance is 800 and the width 200.
+func buildMMFont() []byte {
+	var notdef, a charstringBuilder
+	notdef.numbers(0, 250).op(13).op(14)                        // hsbw endchar
+	a.otherSub(15, 50, 500, 10, 300).escOp(17).escOp(17).op(13) // blended hsbw
+	a.numbers(0, 0).op(21)                                      // rmoveto
+	a.otherSub(14, 100, 100).escOp(17).numbers(0).op(5)         // blended rlineto
+	a.numbers(0, 100).op(5).op(9).op(14)                        // rlineto closepath endchar
+
+	var private bytes.Buffer
+	private.WriteString(`dup /Private 8 dict dup begin
+/RD{string currentfile exch readstring pop}executeonly def
+/ND{noaccess def}executeonly def
+/NP{noaccess put}executeonly def
+/lenIV -1 def
+/NDV {dup 200 sub 700 div} bind def
+/Subrs 0 array
+ND
+2 index /CharStrings 2 dict dup begin
+`)
+	for _, glyph := range []struct {
+		name string
+		cs   []byte
+	}{{".notdef", notdef}, {"a", a}} {
+		fmt.Fprintf(&private, "/%s %d RD %s ND\n", glyph.name, len(glyph.cs), glyph.cs)
+	}
+	private.WriteString("end\nend\nreadonly put\nnoaccess put\ndup /FontName get exch definefont pop\nmark currentfile closefile\n")
+
+	return []byte(`%!PS-AdobeFont-1.0: TestMM 001.000
+12 dict begin
+/FontInfo 6 dict dup begin
+/FamilyName (TestMM) readonly def
+/Weight (All) readonly def
+/BlendDesignPositions [[0][1]] def
+/BlendDesignMap [[[200 0][900 1]]] def
+/BlendAxisTypes [/Weight] def
+end readonly def
+/FontName /TestMM def
+/Encoding StandardEncoding def
+/PaintType 0 def
+/FontType 1 def
+/WeightVector [0.75 0.25] def
+/$Blend {0.25 mul exch 0.75 mul add} bind def
+/FontMatrix [0.001 0 0 0.001 0 0] readonly def
+/FontBBox {0 0 1000 1000} readonly def
+/Blend 3 dict dup begin
+/FontBBox {{0 0} {0 0} {1000 1000} {1000 1000}} def
+/Private 14 dict def
+end def
+currentdict end
+currentfile eexec
+` + hex.EncodeToString(encrypt(private.Bytes(), eexecKey)) + "\n")
+}
+
+func TestMultipleMaster(t *testing.T) {
+	font, err := Parse(bytes.NewReader(buildMMFont()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fvar := font.Variations()
+	if len(fvar.Axis) != 1 || len(fvar.Instances) != 1 {
+		t.Fatalf("unexpected variations %v", fvar)
+	}
+	exp := truetype.VarAxis{Tag: truetype.MustNewTag("wght"), Minimum: 200, Default: 375, Maximum: 900}
+	if fvar.Axis[0] != exp {
+		t.Fatalf("expected axis %v, got %v", exp, fvar.Axis[0])
+	}
+
+	gid, ok := font.NominalGlyph('a')
+	if !ok {
+		t.Fatal("missing glyph")
+	}
+	check := func(advance, width float32) {
+		t.Helper()
+		if adv := font.HorizontalAdvance(gid); adv != advance {
+			t.Fatalf("expected advance %g, got %g", advance, adv)
+		}
+		ext, _ := font.GlyphExtents(gid, 0, 0)
+		if ext.Width != width || ext.Height != -100 {
+			t.Fatalf("expected width %g, got %v", width, ext)
+		}
+		if outline := font.GlyphData(gid, 0, 0).(fonts.GlyphOutline); len(outline.Segments) == 0 {
+			t.Fatalf("unexpected outline %v", outline)
+		}
+	}
+
+	check(575, 125) // default instance
+	if font.VarCoordinates() != nil {
+		t.Fatal("expected default coordinates")
+	}
+
+	truetype.SetVariations(font, []truetype.Variation{{Tag: exp.Tag, Value: 900}})
+	if coords := font.VarCoordinates(); len(coords) != 1 || coords[0] != 1 {
+		t.Fatalf("unexpected coordinates %v", coords)
+	}
+	check(800, 200)
+
+	truetype.SetVariations(font, []truetype.Variation{{Tag: exp.Tag, Value: 200}})
+	check(500, 100)
+
+	font.SetVarCoordinates([]float32{0.5})
+	check(650, 150)
+
+	font.SetVarCoordinates(nil)
+	check(575, 125)
+}
+
+func TestNotMultipleMaster(t *testing.T) {
+	data, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Variations().Axis) != 0 {
+		t.Fatal("unexpected variations")
+	}
+	gid, _ := b.NominalGlyph('a')
+	adv := b.HorizontalAdvance(gid)
+	b.SetVarCoordinates([]float32{0.5})
+	if b.VarCoordinates() != nil || b.HorizontalAdvance(gid) != adv {
+		t.Fatal("unexpected variations")
+	}
+}
@@ -15,7 +15,15 @@ var _ fonts.Face = (*Font)(nil)
 
 // Load implements fonts.FontLoader. When the error is `nil`,
 // one (and only one) font is returned.
+// CID-keyed fonts are returned as *CIDFont, other fonts as *Font.
 func Load(file fonts.Resource) (fonts.Faces, error) {
+	if isCIDFont(file) {
+		f, err := ParseCIDFont(file)
+		if err != nil {
+			return nil, err
+		}
+		return fonts.Faces{f}, nil
+	}
 	f, err := Parse(file)
 	if err != nil {
 		return nil, err
@@ -34,6 +42,9 @@ func Parse(pfb fonts.Resource) (*Font, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid .pfb font file: %s", err)
 	}
+	if err = font.checkBlend(); err != nil {
+		return nil, fmt.Errorf("invalid .pfb font file: %s", err)
+	}
 
 	// we follow freetype by placing the .notdef glyph at GID 0
 	// this is not visible from the outside since the cmap will be
@@ -45,6 +56,22 @@ func Parse(pfb fonts.Resource) (*Font, error) {
 	return &font, nil
 }
 
+// ParseWithAFM parses an Adobe Type 1 font file (see `Parse`)
+// and its associated Adobe font metric file (see `ParseAFMFile`),
+// and uses the latter to provide the metrics and the kerning of the font.
+func ParseWithAFM(pfb, afm fonts.Resource) (*Font, error) {
+	font, err := Parse(pfb)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := ParseAFMFile(afm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid .afm font file: %s", err)
+	}
+	font.AttachAFM(metrics)
+	return font, nil
+}
+
 type charstring struct {
 	name string
 	data []byte
@@ -70,6 +97,14 @@ type Font struct {
 	PaintType int
 	FontType  int
 	UniqueID  int
+
+	// BlendAxisTypes is only defined for Multiple Master fonts,
+	// in which case it has one name (like "Weight") per axis.
+	BlendAxisTypes []string
+
+	blend blend // see Variations and SetVarCoordinates
+
+	afm *afmMetrics // optional, see AttachAFM
 }
 
 func (f *Font) PostscriptInfo() (fonts.PSInfo, bool) { return f.PSInfo, true }
@@ -179,7 +214,7 @@ func (f *Font) loadGlyph(index fonts.GID, inSeac bool) ([]fonts.Segment, ps.Path
 
 	var (
 		psi    ps.Machine
-		parser type1CharstringParser
+		parser = type1CharstringParser{weights: f.blend.weights}
 	)
 	err := psi.Run(f.charstrings[index].data, f.subrs, nil, &parser)
 	if err != nil {
@@ -260,6 +260,9 @@ func (p *parser) parseASCII(bytes []byte) (Font, error) {
 				return out, err
 			}
 			out.PSInfo = p.readFontInfo(dict)
+			err = p.readBlendInfo(dict, &out)
+		case "Blend": // Multiple Master fonts
+			err = p.skipDict()
 		case "Metrics":
 			_, err = p.readSimpleDict()
 		case "Encoding":
@@ -316,6 +319,8 @@ func (p *parser) readSimpleValue(key string, font *Font) error {
 		font.FontMatrix, err = p.arrayToNumbers(value)
 	case "FontBBox":
 		font.FontBBox, err = p.arrayToNumbers(value)
+	case "WeightVector": // Multiple Master fonts
+		font.blend.defaultWeights, err = p.arrayToNumbers(value)
 	}
 	return err
 }
@@ -495,6 +500,38 @@ func (p *parser) readSimpleDict() (map[string][]tk.Token, error) {
 	return dict, nil
 }
 
+// Skips a dictionary, which may contain nested dictionaries,
+// such as the /Blend dictionary of Multiple Master fonts.
+func (p *parser) skipDict() error {
+	if _, err := p.read(tk.Integer); err != nil {
+		return err
+	}
+	if err := p.readWithName(tk.Other, "dict"); err != nil {
+		return err
+	}
+	if _, err := p.readMaybe(tk.Other, "dup"); err != nil {
+		return err
+	}
+	if err := p.readWithName(tk.Other, "begin"); err != nil {
+		return err
+	}
+	for depth := 1; depth > 0; {
+		token, err := p.lexer.nextToken()
+		if err != nil {
+			return err
+		}
+		if token.Kind == 0 {
+			return errors.New("unexpected end of dictionary")
+		}
+		if token.IsOther("begin") {
+			depth++
+		} else if token.IsOther("end") {
+			depth--
+		}
+	}
+	return p.readDef()
+}
+
 // Reads a simple value from a dictionary.
 func (p *parser) readDictValue() ([]tk.Token, error) {
 	value, err := p.readValue()
@@ -1007,6 +1044,10 @@ func (p *parser) readCharStrings(lenIV int) ([]charstring, error) {
 
 // Reads the sequence "noaccess def" or equivalent.
 func (p *parser) readDef() error {
+	// procedures may be bound (found in Multiple Master fonts)
+	if _, err := p.readMaybe(tk.Other, "bind"); err != nil {
+		return err
+	}
 	if _, err := p.readMaybe(tk.Other, "readonly"); err != nil {
 		return err
 	}
@@ -3,6 +3,7 @@ package type1
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	tokenizer "github.com/benoitkugler/pstokenizer"
@@ -190,3 +191,123 @@ func TestScanDescription(t *testing.T) {
 		}
 	}
 }
+
+// calligrapherAFM provides metrics for a few glyphs of CalligrapherRegular.pfb,
+// without XHeight
+const calligrapherAFM = `StartFontMetrics 4.1
+FontName Calligrapher-Regular
+FullName Calligrapher Regular
+FamilyName Calligrapher
+Weight Thin
+ItalicAngle 0
+IsFixedPitch false
+FontBBox -173 -234 1328 899
+UnderlinePosition -190
+UnderlineThickness 20
+EncodingScheme AdobeStandardEncoding
+CapHeight 722
+Ascender 729
+Descender -217
+StartCharMetrics 3
+C 65 ; WX 743 ; N A ; B -23 -14 777 722 ;
+C 72 ; WX 756 ; N H ; B 70 -17 707 728 ;
+C 86 ; WX 753 ; N V ; B -28 -20 816 729 ;
+EndCharMetrics
+StartKernData
+StartKernPairs 2
+KPX A V -74
+KPX V A -62
+EndKernPairs
+EndKernData
+EndFontMetrics
+`
+
+func TestAttachAFM(t *testing.T) {
+	pfb, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	font, err := Parse(bytes.NewReader(pfb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gidA, _ := font.NominalGlyph('A')
+	gidV, _ := font.NominalGlyph('V')
+	if font.KernPair(gidA, gidV) != 0 {
+		t.Fatal("unexpected kerning without AFM")
+	}
+	if _, ok := font.LineMetric(fonts.CapHeight); ok {
+		t.Fatal("unexpected cap height without AFM")
+	}
+
+	font, err = ParseWithAFM(bytes.NewReader(pfb), strings.NewReader(calligrapherAFM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if adv := font.HorizontalAdvance(gidA); adv != 743 {
+		t.Fatalf("expected AFM advance, got %g", adv)
+	}
+	if kern := font.KernPair(gidA, gidV); kern != -74 {
+		t.Fatalf("expected AFM kerning, got %d", kern)
+	}
+	if kern := font.KernPair(gidV, gidA); kern != -62 {
+		t.Fatalf("expected AFM kerning, got %d", kern)
+	}
+	for metric, exp := range map[fonts.LineMetric]float32{
+		fonts.UnderlinePosition:  -190,
+		fonts.UnderlineThickness: 20,
+		fonts.CapHeight:          722,
+	} {
+		if v, ok := font.LineMetric(metric); !ok || v != exp {
+			t.Fatalf("metric %d: expected %g, got %g", metric, exp, v)
+		}
+	}
+	if _, ok := font.LineMetric(fonts.XHeight); ok {
+		t.Fatal("unexpected x height missing from the AFM")
+	}
+	extents, _ := font.FontHExtents()
+	if extents.Ascender != 729 || extents.Descender != -217 {
+		t.Fatalf("unexpected extents %v", extents)
+	}
+}
+
+func TestAttachAFMScaled(t *testing.T) {
+	pfb, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	font, err := Parse(bytes.NewReader(pfb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	afm, err := ParseAFMFile(strings.NewReader(calligrapherAFM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the AFM metrics are in 1/1000 em, whatever the font units
+	font.FontMatrix = []Fl{0.0005, 0, 0, 0.0005, 0, 0}
+	font.AttachAFM(afm)
+	if font.Upem() != 2000 {
+		t.Fatalf("unexpected upem %d", font.Upem())
+	}
+
+	gidA, _ := font.NominalGlyph('A')
+	gidV, _ := font.NominalGlyph('V')
+	if adv := font.HorizontalAdvance(gidA); adv != 1486 {
+		t.Fatalf("expected scaled AFM advance, got %g", adv)
+	}
+	if kern := font.KernPair(gidA, gidV); kern != -148 {
+		t.Fatalf("expected scaled AFM kerning, got %d", kern)
+	}
+	if v, _ := font.LineMetric(fonts.CapHeight); v != 1444 {
+		t.Fatalf("expected scaled cap height, got %g", v)
+	}
+	if v, _ := font.LineMetric(fonts.UnderlinePosition); v != -380 {
+		t.Fatalf("expected scaled underline position, got %g", v)
+	}
+	extents, _ := font.FontHExtents()
+	if extents.Ascender != 1458 || extents.Descender != -434 {
+		t.Fatalf("unexpected extents %v", extents)
+	}
+}
@@ -56,13 +56,13 @@ const (
 	acMeasureSol
 	/// not implemented
 	acMeasureEol
-	/// Amount this slot can stretch (not implemented)
+	/// Amount this slot can stretch (see `Segment.Justify`)
 	acJStretch
-	/// Amount this slot can shrink (not implemented)
+	/// Amount this slot can shrink
 	_
-	/// Granularity by which this slot can stretch or shrink (not implemented)
+	/// Granularity by which this slot can stretch or shrink
 	_
-	/// Justification weight for this glyph (not implemented)
+	/// Justification weight for this glyph
 	_
 	/// Amount this slot mush shrink or stretch in design units
 	acJWidth
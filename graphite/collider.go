@@ -205,7 +205,8 @@ func sqr(v float32) float32         { return v * v }
 type zones struct {
 	exclusions []exclusion
 
-	debugs []zoneDebug // always empty when debug is disabled
+	env    *colliderEnv // non nil when tracing
+	debugs []zoneDebug  // always empty when tracing is disabled
 
 	marginLen, marginWeight, pos, posm float32
 }
@@ -225,7 +226,7 @@ func (zo *zones) initialise(xmin, xmax, marginLen, marginWeight, a0 float32, isX
 	zo.exclusions = append(zo.exclusions, ex)
 	zo.exclusions[0].open = true
 
-	if debugMode >= 2 {
+	if zo.env != nil {
 		zo.debugs = zo.debugs[:0]
 	}
 }
@@ -257,8 +258,8 @@ func insertExclusion(s []exclusion, i int, x exclusion) []exclusion {
 }
 
 func (zo *zones) insert(e exclusion) {
-	if debugMode >= 2 {
-		zo.debugs = append(zo.debugs, zoneDebug{excl: e, isDel: false, env: tr.colliderEnv})
+	if zo.env != nil {
+		zo.debugs = append(zo.debugs, zoneDebug{excl: e, isDel: false, env: *zo.env})
 	}
 
 	e.x = max(e.x, zo.pos)
@@ -328,9 +329,9 @@ func (zo *zones) insert(e exclusion) {
 }
 
 func (zo *zones) remove(x, xm float32) {
-	if debugMode >= 2 {
+	if zo.env != nil {
 		e := exclusion{x: x, xm: xm}
-		zo.debugs = append(zo.debugs, zoneDebug{excl: e, isDel: true, env: tr.colliderEnv})
+		zo.debugs = append(zo.debugs, zoneDebug{excl: e, isDel: true, env: *zo.env})
 	}
 
 	x = max(x, zo.pos)
@@ -453,6 +454,13 @@ func (sc *shiftCollider) initSlot(seg *Segment, aSlot *Slot, limit rect, margin,
 	}
 	bb := glyph.bbox
 	sb := glyph.boxes.slant
+	var env *colliderEnv
+	if seg.trace != nil {
+		env = &seg.trace.colliderEnv
+	}
+	for i := range sc.ranges {
+		sc.ranges[i].env = env
+	}
 	// float sx = aSlot.Position.x + currShift.x;
 	// float sy = aSlot.Position.y + currShift.y;
 	if currOffset.X != 0. || currOffset.Y != 0. {
@@ -691,8 +699,8 @@ func (sc *shiftCollider) mergeSlot(seg *Segment, slot *Slot, cslot *slotCollisio
 			orderFlags = orderFlags ^ ((((orderFlags >> 1) & orderFlags) & 0x15) * 3)
 		}
 
-		if debugMode >= 2 {
-			tr.colliderEnv.sl = slot
+		if seg.trace != nil {
+			seg.trace.colliderEnv.sl = slot
 		}
 
 		// Process main bounding octabox.
@@ -750,8 +758,8 @@ func (sc *shiftCollider) mergeSlot(seg *Segment, slot *Slot, cslot *slotCollisio
 				lmargin = sc.margin / iSQRT2
 			}
 
-			if debugMode >= 2 {
-				tr.colliderEnv.val = -1
+			if seg.trace != nil {
+				seg.trace.colliderEnv.val = -1
 			}
 
 			if orderFlags != 0 {
@@ -768,31 +776,31 @@ func (sc *shiftCollider) mergeSlot(seg *Segment, slot *Slot, cslot *slotCollisio
 
 					// region 1
 					// DBGTAG(1x) means the regions are up and right
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -11
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -11
 					}
 					sc.addBoxSlope(true, rect{Position{xminf, r2Yedge}, Position{r1Xedge, ypinf}},
 						tbb, tsb, org, 0, seqAboveWt, true, i)
 					// region 2
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -12
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -12
 					}
 					sc.removeBox(rect{Position{xminf, yminf}, Position{r3Xedge, r2Yedge}}, tbb, tsb, org, i)
 					// region 3, which end is zero is irrelevant since m weight is 0
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -13
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -13
 					}
 					sc.addBoxSlope(true, rect{Position{r3Xedge, yminf}, Position{xpinf, r2Yedge - seqValignHt}},
 						tbb, tsb, org, seqBelowWt, 0, true, i)
 					// region 4
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -14
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -14
 					}
 					sc.addBoxSlope(false, rect{Position{sx + bb.bl.X, r2Yedge}, Position{xpinf, r2Yedge + seqValignHt}},
 						tbb, tsb, org, 0, seqValignWt, true, i)
 					// region 5
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -15
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -15
 					}
 					sc.addBoxSlope(false, rect{Position{sx + bb.bl.X, r2Yedge - seqValignHt}, Position{xpinf, r2Yedge}},
 						tbb, tsb, org, seqBelowWt, seqValignWt, false, i)
@@ -802,63 +810,63 @@ func (sc *shiftCollider) mergeSlot(seg *Segment, slot *Slot, cslot *slotCollisio
 					r2Yedge := 0.5*(bb.bl.Y+bb.tr.Y) + sy
 					// DBGTAG(2x) means the regions are up and right
 					// region 1
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -21
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -21
 					}
 					sc.addBoxSlope(true, rect{Position{r1Xedge, yminf}, Position{xpinf, r2Yedge}},
 						tbb, tsb, org, 0, seqAboveWt, false, i)
 					// region 2
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -22
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -22
 					}
 					sc.removeBox(rect{Position{r3Xedge, r2Yedge}, Position{xpinf, ypinf}}, tbb, tsb, org, i)
 					// region 3
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -23
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -23
 					}
 					sc.addBoxSlope(true, rect{Position{xminf, r2Yedge - seqValignHt}, Position{r3Xedge, ypinf}},
 						tbb, tsb, org, seqBelowWt, 0, false, i)
 					// region 4
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -24
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -24
 					}
 					sc.addBoxSlope(false, rect{Position{xminf, r2Yedge}, Position{sx + bb.tr.X, r2Yedge + seqValignHt}},
 						tbb, tsb, org, 0, seqValignWt, true, i)
 					// region 5
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -25
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -25
 					}
 					sc.addBoxSlope(false, rect{
 						Position{xminf, r2Yedge - seqValignHt},
 						Position{sx + bb.tr.X, r2Yedge},
 					}, tbb, tsb, org, seqBelowWt, seqValignWt, false, i)
 				case seqOrderNOABOVE: // enforce neighboring glyph being above
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -31
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -31
 					}
 					sc.removeBox(rect{
 						Position{bb.bl.X - tbb.tr.X + sx, sy + bb.tr.Y},
 						Position{bb.tr.X - tbb.bl.X + sx, ypinf},
 					}, tbb, tsb, org, i)
 				case seqOrderNOBELOW: // enforce neighboring glyph being below
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -32
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -32
 					}
 					sc.removeBox(rect{
 						Position{bb.bl.X - tbb.tr.X + sx, yminf},
 						Position{bb.tr.X - tbb.bl.X + sx, sy + bb.bl.Y},
 					}, tbb, tsb, org, i)
 				case seqOrderNOLEFT: // enforce neighboring glyph being to the left
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -33
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -33
 					}
 					sc.removeBox(rect{
 						Position{xminf, bb.bl.Y - tbb.tr.Y + sy},
 						Position{bb.bl.X - tbb.tr.X + sx, bb.tr.Y - tbb.bl.Y + sy},
 					}, tbb, tsb, org, i)
 				case seqOrderNORIGHT: // enforce neighboring glyph being to the right
-					if debugMode >= 2 {
-						tr.colliderEnv.val = -34
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = -34
 					}
 					sc.removeBox(rect{
 						Position{bb.tr.X - tbb.bl.X + sx, bb.bl.Y - tbb.tr.Y + sy},
@@ -904,8 +912,8 @@ func (sc *shiftCollider) mergeSlot(seg *Segment, slot *Slot, cslot *slotCollisio
 						continue
 					}
 
-					if debugMode >= 2 {
-						tr.colliderEnv.val = j
+					if seg.trace != nil {
+						seg.trace.colliderEnv.val = j
 					}
 
 					if omin > otmax {
@@ -924,8 +932,8 @@ func (sc *shiftCollider) mergeSlot(seg *Segment, slot *Slot, cslot *slotCollisio
 				}
 			} else { // no sub-boxes
 
-				if debugMode >= 2 {
-					tr.colliderEnv.val = -1
+				if seg.trace != nil {
+					seg.trace.colliderEnv.val = -1
 				}
 
 				*collides = true
@@ -967,8 +975,8 @@ func (sc *shiftCollider) resolve(seg *Segment) (Position, bool) {
 	var resultPos Position
 	bestAxis := -1
 
-	if debugMode >= 2 {
-		tr.addCollisionMove(sc, seg)
+	if seg.trace != nil {
+		seg.trace.addCollisionMove(sc, seg)
 	}
 
 	isCol := true
@@ -989,8 +997,8 @@ func (sc *shiftCollider) resolve(seg *Segment) (Position, bool) {
 		tmp, bestCost := sc.ranges[i].closest(0)
 		bestPos = tmp - tbase // Get the best relative position
 
-		if debugMode >= 2 {
-			tr.addCollisionVector(sc, seg, i, tbase, bestCost, bestPos)
+		if seg.trace != nil {
+			seg.trace.addCollisionVector(sc, seg, i, tbase, bestCost, bestPos)
 		}
 
 		if bestCost >= 0.0 {
@@ -1013,8 +1021,8 @@ func (sc *shiftCollider) resolve(seg *Segment) (Position, bool) {
 		}
 	} // end of loop over 4 directions
 
-	if debugMode >= 2 {
-		tr.endCollisionMove(resultPos, bestAxis, isCol)
+	if seg.trace != nil {
+		seg.trace.endCollisionMove(resultPos, bestAxis, isCol)
 	}
 
 	return resultPos, isCol
@@ -1173,7 +1181,8 @@ func (kc *kernCollider) initSlot(seg *Segment, aSlot *Slot, limit rect, margin f
 	}
 	numSlices = len(kc.edges)
 
-	if debugMode >= 2 {
+	kc.seg = nil
+	if seg.trace != nil {
 		kc.seg = seg
 		kc.slotNear = make([]*Slot, numSlices)
 		kc.nearEdges = make([]float32, numSlices)
@@ -1270,7 +1279,7 @@ func (kc *kernCollider) mergeSlot(seg *Segment, slot *Slot, currShift Position,
 				collides = true
 			}
 
-			if debugMode >= 2 {
+			if kc.seg != nil {
 				// Debugging - remember the closest neighboring edge for this slice.
 				if m > rtl*kc.nearEdges[i] {
 					kc.slotNear[i] = slot
@@ -1296,8 +1305,8 @@ func (kc *kernCollider) resolve(isRTL bool) Position {
 	resultNeeded := pick(isRTL, -1, 1) * kc.mingap
 	result := min(kc.limit.tr.X-kc.offsetPrev.X, max(resultNeeded, kc.limit.bl.X-kc.offsetPrev.X))
 
-	if debugMode >= 2 {
-		tr.addKern(kc, kc.seg, result, resultNeeded)
+	if kc.seg != nil {
+		kc.seg.trace.addKern(kc, kc.seg, result, resultNeeded)
 	}
 
 	return Position{result, 0.}
@@ -3,10 +3,13 @@
 package graphite
 
 import (
+	"sync"
+
 	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/fonts/truetype"
 )
 
+// debugMode enables printing to stdout, and is only used in tests
 const debugMode = 0
 
 type (
@@ -110,18 +113,22 @@ type glyphBoxes struct {
 }
 
 type glyph struct {
+	octabox *octaboxMetrics // may be nil
 	attrs   attributeSet
-	boxes   glyphBoxes
-	advance struct{ x, y int16 }
-	bbox    rect
+	advance Position
+
+	// bbox and boxes are computed on demand, see GraphiteFace.getGlyph
+	extentsOnce sync.Once
+	boxes       glyphBoxes
+	bbox        rect
 }
 
-func (g glyph) getMetric(metric uint8) int32 {
+func (g *glyph) getMetric(metric uint8) int32 {
 	switch metric {
 	case kgmetLsb:
 		return int32(g.bbox.bl.X)
 	case kgmetRsb:
-		return int32(g.advance.x) - int32(g.bbox.tr.X)
+		return int32(g.advance.X) - int32(g.bbox.tr.X)
 	case kgmetBbTop:
 		return int32(g.bbox.tr.Y)
 	case kgmetBbBottom:
@@ -135,24 +142,64 @@ func (g glyph) getMetric(metric uint8) int32 {
 	case kgmetBbWidth:
 		return int32(g.bbox.tr.X - g.bbox.bl.X)
 	case kgmetAdvWidth:
-		return int32(g.advance.x)
+		return int32(g.advance.X)
 	case kgmetAdvHeight:
-		return int32(g.advance.y)
+		return int32(g.advance.Y)
 	default:
 		return 0
 	}
 }
 
 // FontOptions allows to specify a scale to get position
-// in user units rather than in font units.
+// in user units rather than in font units, and optionally
+// hinted advances (see `NewFontOptionsWithAdvance`).
 type FontOptions struct {
-	scale float32 // scales from design units to ppm
-	// isHinted bool
+	face *GraphiteFace // the face the options are built for
+	// hinted advances, in user units, indexed by glyph (nil if not hinted)
+	advances []float32
+	scale    float32 // scales from design units to ppm
 }
 
 // NewFontOptions builds options from the given points per em.
 func NewFontOptions(ppem uint16, face *GraphiteFace) *FontOptions {
-	return &FontOptions{scale: float32(ppem) / float32(face.Upem())}
+	return &FontOptions{face: face, scale: float32(ppem) / float32(face.Upem())}
+}
+
+// NewFontOptionsWithAdvance is the same as `NewFontOptions`, but
+// uses `advance` to get the horizontal advance of the glyphs, in user units,
+// instead of scaling the advances of the font. It is typically used to provide hinted
+// (for instance, rounded to a whole number of pixels) advances.
+// `advance` is called once per glyph of the font, in this function.
+//
+// The hinted advances are used for the final positioning of the slots, and,
+// converted back to design units, when positioning the slots for the rules
+// and the collision avoidance, so that the collision shifts match the final positions.
+// It is the equivalent of the `gr_make_font_with_advance_fn` function of the reference
+// implementation.
+func NewFontOptionsWithAdvance(ppem uint16, face *GraphiteFace, advance func(gid GID) float32) *FontOptions {
+	out := NewFontOptions(ppem, face)
+	out.advances = make([]float32, face.numGlyphs)
+	for gid := range out.advances {
+		out.advances[gid] = advance(GID(gid))
+	}
+	return out
+}
+
+// hintedAdvance returns the (scaled) horizontal advance of a slot with design
+// advance `advance`: if hinted advances are provided, the difference between the slot
+// and the glyph design advances is scaled, and added to the hinted advance.
+func (font *FontOptions) hintedAdvance(gid GID, advance float32) float32 {
+	if int(gid) < len(font.advances) {
+		if glyph := font.face.getGlyph(gid); glyph != nil {
+			return (advance-glyph.advance.X)*font.scale + font.advances[gid]
+		}
+	}
+	return advance * font.scale
+}
+
+// designAdvance is the same as `hintedAdvance`, but expressed in design units.
+func (font *FontOptions) designAdvance(gid GID, advance float32) float32 {
+	return font.hintedAdvance(gid, advance) / font.scale
 }
 
 var _ fonts.FaceMetrics = GraphiteFace{}
@@ -160,6 +207,8 @@ var _ fonts.FaceMetrics = GraphiteFace{}
 // GraphiteFace contains the specific OpenType tables
 // used by the Graphite engine.
 // It also wraps the common OpenType metrics record.
+// A face may be shared by concurrent calls to `Shape`, as long as
+// the variation coordinates of the underlying font are not modified meanwhile.
 type GraphiteFace struct {
 	fonts.FaceMetrics
 
@@ -175,6 +224,13 @@ type GraphiteFace struct {
 	numAttributes uint16 //  number of glyph attributes per glyph
 
 	ascent, descent int32
+
+	// glyph metrics depend on the variation coordinates
+	// of the font: they are updated when required
+	varCoords []float32
+	numGlyphs int // not including pseudo glyphs
+
+	logger *faceLogger // optional, see StartLogging
 }
 
 // LoadGraphite read graphite tables from the given OpenType font.
@@ -190,7 +246,6 @@ func LoadGraphite(font *truetype.Font) (*GraphiteFace, error) {
 	out.cmap, _ = font.Cmap()
 	out.names = font.Names
 
-	htmx, glyphs := font.Hmtx, font.Glyf
 	tables := font.Graphite
 
 	out.sill, err = parseTableSill(tables.Sill)
@@ -219,35 +274,83 @@ func LoadGraphite(font *truetype.Font) (*GraphiteFace, error) {
 		return nil, err
 	}
 
-	out.preprocessGlyphsAttributes(glyphs, htmx, attrs)
+	out.numGlyphs = font.NumGlyphs
+	out.preprocessGlyphsAttributes(attrs)
 
 	return &out, nil
 }
 
-// process the 'glyf', 'htmx' and 'glat' tables to extract relevant info.
-func (f *GraphiteFace) preprocessGlyphsAttributes(glyphs truetype.TableGlyf, htmx truetype.TableHVmtx,
-	attrs tableGlat,
-) {
-	// take into account pseudo glyphs (len(glyphs) <= len(attrs))
-	L := len(glyphs)
-
+// process the 'glat' table and the glyphs metrics to extract relevant info.
+func (f *GraphiteFace) preprocessGlyphsAttributes(attrs tableGlat) {
 	f.glyphs = make([]glyph, len(attrs))
-
 	for gid, attr := range attrs {
 		dst := &f.glyphs[gid]
-		if gid < L {
-			dst.advance.x = htmx[gid].Advance
-			data := glyphs[gid]
+		dst.attrs = attr.attributes
+		dst.octabox = attr.octaboxMetrics
+	}
+	f.updateGlyphsMetrics()
+}
+
+// updateGlyphsMetrics fetches the advances of the glyphs from the font,
+// so that any glyph format (TrueType or CFF outlines)
+// and the current variation coordinates are supported.
+// The glyphs are replaced by new ones, whose bounding boxes are
+// lazily computed by `getGlyph`.
+func (f *GraphiteFace) updateGlyphsMetrics() {
+	if varFont, ok := f.FaceMetrics.(truetype.FaceVariable); ok {
+		f.varCoords = append(f.varCoords[:0], varFont.VarCoordinates()...)
+	}
+	glyphs := make([]glyph, len(f.glyphs))
+	for gid := range glyphs {
+		dst := &glyphs[gid]
+		dst.attrs, dst.octabox = f.glyphs[gid].attrs, f.glyphs[gid].octabox
+		// take into account pseudo glyphs (numGlyphs <= len(f.glyphs))
+		if gid < f.numGlyphs {
+			dst.advance.X = f.FaceMetrics.HorizontalAdvance(GID(gid))
+		}
+	}
+	f.glyphs = glyphs
+}
+
+// computeExtents fetches the bounding box of the glyph from the font,
+// which, for CFF outlines, requires to run its charstring.
+func (f *GraphiteFace) computeExtents(gid GID, dst *glyph) {
+	// take into account pseudo glyphs (numGlyphs <= len(f.glyphs))
+	if int(gid) < f.numGlyphs {
+		if ext, ok := f.FaceMetrics.GlyphExtents(gid, 0, 0); ok {
 			dst.bbox = rect{
-				bl: Position{float32(data.Xmin), float32(data.Ymin)},
-				tr: Position{float32(data.Xmax), float32(data.Ymax)},
+				bl: Position{ext.XBearing, ext.YBearing + ext.Height},
+				tr: Position{ext.XBearing + ext.Width, ext.YBearing},
 			}
 		}
-		dst.attrs = attr.attributes
-		if attr.octaboxMetrics != nil {
-			dst.boxes = attr.octaboxMetrics.computeBoxes(dst.bbox)
+	}
+	if dst.octabox != nil {
+		dst.boxes = dst.octabox.computeBoxes(dst.bbox)
+	}
+}
+
+// checkVariations updates the glyphs metrics if
+// the variation coordinates of the font have changed since the last call.
+func (f *GraphiteFace) checkVariations() {
+	varFont, ok := f.FaceMetrics.(truetype.FaceVariable)
+	if !ok {
+		return
+	}
+	if coords := varFont.VarCoordinates(); !sameCoords(coords, f.varCoords) {
+		f.updateGlyphsMetrics()
+	}
+}
+
+func sameCoords(c1, c2 []float32) bool {
+	if len(c1) != len(c2) {
+		return false
+	}
+	for i, v := range c1 {
+		if c2[i] != v {
+			return false
 		}
 	}
+	return true
 }
 
 // FeaturesForLang selects the features and values for the given language, or
@@ -256,10 +359,77 @@ func (f *GraphiteFace) FeaturesForLang(lang Tag) FeaturesValue {
 	return f.sill.getFeatures(lang, f.feat)
 }
 
+// Features returns the features defined by the font,
+// in the order of the 'Feat' table.
+func (f *GraphiteFace) Features() []FeatureRef {
+	out := make([]FeatureRef, len(f.feat))
+	for i, feat := range f.feat {
+		out[i] = FeatureRef{
+			Settings: append([]FeatureSetting(nil), feat.settings...),
+			ID:       zeroToSpace(feat.id),
+			Flags:    feat.flags,
+			Label:    feat.label,
+		}
+	}
+	return out
+}
+
+// Languages returns the languages for which the font defines
+// specific feature values (see `FeaturesForLang`).
+func (f *GraphiteFace) Languages() []Tag {
+	out := make([]Tag, len(f.sill))
+	for i, rec := range f.sill {
+		// the language codes are padded with zeros: use spaces instead
+		tag := rec.langcode
+		for shift := 0; shift < 32 && tag>>shift&0xFF == 0; shift += 8 {
+			tag |= 0x20 << shift
+		}
+		out[i] = tag
+	}
+	return out
+}
+
+// Label returns the string identified by `name` in the 'name' table,
+// such as the labels of the features and their settings.
+// `lang` is a Windows language ID: if the string is not available in this language,
+// the English version is used, or any other version.
+// The language of the returned string is also returned.
+func (f *GraphiteFace) Label(name truetype.NameID, lang truetype.PlatformLanguageID) (string, truetype.PlatformLanguageID) {
+	var english, fallback *truetype.NameEntry
+	for i := range f.names {
+		entry := &f.names[i]
+		if entry.NameID != name || entry.PlatformID != truetype.PlatformMicrosoft ||
+			entry.EncodingID != truetype.PEMicrosoftUnicodeCs {
+			continue
+		}
+		if entry.LanguageID == lang {
+			return entry.String(), lang
+		}
+		if entry.LanguageID == truetype.PLMicrosoftEnglish && english == nil {
+			english = entry
+		}
+		if fallback == nil {
+			fallback = entry
+		}
+	}
+	if english == nil {
+		english = fallback
+	}
+	if english == nil { // try the other platforms
+		english = f.names.SelectEntry(name)
+	}
+	if english == nil {
+		return "", 0
+	}
+	return english.String(), english.LanguageID
+}
+
 // getGlyph return nil for invalid gid
 func (f *GraphiteFace) getGlyph(gid GID) *glyph {
 	if int(gid) < len(f.glyphs) {
-		return &f.glyphs[gid]
+		g := &f.glyphs[gid]
+		g.extentsOnce.Do(func() { f.computeExtents(gid, g) })
+		return g
 	}
 	return nil
 }
@@ -300,9 +470,9 @@ func (f *GraphiteFace) runGraphite(seg *Segment, silf *passes) {
 		}
 	}
 
-	if debugMode >= 2 {
-		seg.positionSlots(nil, nil, nil, seg.currdir(), true)
-		tr.finaliseOutput(seg)
+	if seg.trace != nil {
+		advance := seg.positionSlots(nil, nil, nil, seg.currdir(), true)
+		seg.trace.finaliseOutput(seg, advance)
 	}
 }
 
@@ -317,6 +487,7 @@ func (f *GraphiteFace) runGraphite(seg *Segment, silf *passes) {
 func (face *GraphiteFace) Shape(font *FontOptions, text []rune, script Tag, features FeaturesValue, dir int8) *Segment {
 	var seg Segment
 
+	face.checkVariations()
 	seg.face = face
 
 	// allocate memory
@@ -331,6 +502,9 @@ func (face *GraphiteFace) Shape(font *FontOptions, text []rune, script Tag, feat
 	}
 
 	seg.dir = dir
+	if font != nil && font.advances != nil {
+		seg.hinting = font
+	}
 	if seg.silf.hasCollision {
 		seg.flags = 1 << 1
 	}
@@ -343,10 +517,19 @@ func (face *GraphiteFace) Shape(font *FontOptions, text []rune, script Tag, feat
 	}
 	seg.feats = features
 
+	if face.logger != nil {
+		seg.trace = &traceOutput{Id: seg.objectID()}
+	}
+
 	seg.processRunes(text)
 
 	face.runGraphite(&seg, seg.silf)
 
+	if seg.trace != nil {
+		face.logger.write(seg.trace)
+		seg.trace = nil
+	}
+
 	seg.finalise(font, true)
 	return &seg
 }
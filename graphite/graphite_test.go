@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	testdata "github.com/benoitkugler/textlayout-testdata/graphite"
+	ttdata "github.com/benoitkugler/textlayout-testdata/truetype"
+	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/fonts/truetype"
 )
 
@@ -57,3 +59,140 @@ func TestCrash(t *testing.T) {
 		parseTableGlat(input, []uint32{1, 45, 78, 896, 4566})
 	}
 }
+
+// stretchedFont simulates a variable font, whose
+// glyphs are stretched horizontally by (1 + coords[0])
+type stretchedFont struct {
+	*truetype.Font
+	coords []float32
+}
+
+func (f *stretchedFont) VarCoordinates() []float32 { return f.coords }
+
+func (f *stretchedFont) factor() float32 {
+	if len(f.coords) == 0 {
+		return 1
+	}
+	return 1 + f.coords[0]
+}
+
+func (f *stretchedFont) HorizontalAdvance(gid GID) float32 {
+	return f.Font.HorizontalAdvance(gid) * f.factor()
+}
+
+func (f *stretchedFont) GlyphExtents(gid GID, xPpem, yPpem uint16) (fonts.GlyphExtents, bool) {
+	ext, ok := f.Font.GlyphExtents(gid, xPpem, yPpem)
+	ext.XBearing *= f.factor()
+	ext.Width *= f.factor()
+	return ext, ok
+}
+
+func TestGlyphMetricsVariations(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	font := &stretchedFont{Font: face.FaceMetrics.(*truetype.Font)}
+	face.FaceMetrics = font
+
+	text := []rune("l")
+	gid, _ := face.NominalGlyph('l')
+	seg := face.Shape(nil, text, 0, nil, 0)
+	advance, bbox := seg.First.Advance.X, face.getGlyph(gid).bbox
+	if advance == 0 || bbox.width() == 0 {
+		t.Fatalf("invalid metrics %f %v", advance, bbox)
+	}
+
+	font.coords = []float32{1}
+	seg = face.Shape(nil, text, 0, nil, 0)
+	if got := seg.First.Advance.X; got != 2*advance {
+		t.Fatalf("expected advance %f, got %f", 2*advance, got)
+	}
+	if got := face.getGlyph(gid).bbox; got.width() != 2*bbox.width() || got.height() != bbox.height() {
+		t.Fatalf("expected stretched bounding box, got %v (from %v)", got, bbox)
+	}
+
+	font.coords = nil
+	seg = face.Shape(nil, text, 0, nil, 0)
+	if got := seg.First.Advance.X; got != advance {
+		t.Fatalf("expected advance %f, got %f", advance, got)
+	}
+}
+
+func TestGlyphMetricsCFF(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+
+	b, err := ttdata.Files.ReadFile("Raleway-v4020-Regular.otf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cff, err := truetype.Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cff.NumGlyphs >= face.numGlyphs {
+		t.Fatal("expected less glyphs in the CFF font")
+	}
+	// use the metrics from the CFF outlines
+	face.FaceMetrics = cff
+	face.updateGlyphsMetrics()
+
+	nonEmpty := 0
+	for gid := 0; gid < face.numGlyphs; gid++ {
+		glyph := face.getGlyph(GID(gid))
+		ext, ok := cff.GlyphExtents(GID(gid), 0, 0)
+		if !ok { // out of range: no stale bounding box
+			if glyph.bbox != (rect{}) {
+				t.Fatalf("glyph %d: expected empty bounding box, got %v", gid, glyph.bbox)
+			}
+			continue
+		}
+		if exp := (rect{bl: Position{ext.XBearing, ext.YBearing + ext.Height}, tr: Position{ext.XBearing + ext.Width, ext.YBearing}}); glyph.bbox != exp {
+			t.Fatalf("glyph %d: expected bounding box %v, got %v", gid, exp, glyph.bbox)
+		}
+		if glyph.advance.X != cff.HorizontalAdvance(GID(gid)) {
+			t.Fatalf("glyph %d: expected advance %f, got %f", gid, cff.HorizontalAdvance(GID(gid)), glyph.advance.X)
+		}
+		if glyph.bbox.width() != 0 {
+			nonEmpty++
+		}
+	}
+	if nonEmpty == 0 {
+		t.Fatal("expected bounding boxes from the CFF outlines")
+	}
+}
+
+// countingMetrics records the calls to GlyphExtents
+type countingMetrics struct {
+	fonts.FaceMetrics
+	extents map[GID]int
+}
+
+func (c countingMetrics) GlyphExtents(gid GID, xPpem, yPpem uint16) (fonts.GlyphExtents, bool) {
+	c.extents[gid]++
+	return c.FaceMetrics.GlyphExtents(gid, xPpem, yPpem)
+}
+
+func TestGlyphExtentsLazy(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	metrics := countingMetrics{FaceMetrics: face.FaceMetrics, extents: map[GID]int{}}
+	face.FaceMetrics = metrics
+	face.updateGlyphsMetrics()
+
+	if len(metrics.extents) != 0 {
+		t.Fatalf("expected no extents to be computed, got %v", metrics.extents)
+	}
+
+	face.Shape(nil, []rune("Hello"), 0, nil, 0)
+	used := len(metrics.extents)
+	if used == 0 || used > 10 {
+		t.Fatalf("expected extents for the shaped glyphs only, got %v", metrics.extents)
+	}
+	for gid, nb := range metrics.extents {
+		if nb != 1 {
+			t.Fatalf("glyph %d: extents computed %d times", gid, nb)
+		}
+	}
+
+	face.Shape(nil, []rune("Hello"), 0, nil, 0)
+	if len(metrics.extents) != used {
+		t.Fatalf("expected cached extents, got %v", metrics.extents)
+	}
+}
@@ -0,0 +1,338 @@
+package graphite
+
+import "unicode"
+
+// JustifyFlags modifies the behavior of `Segment.Justify`.
+type JustifyFlags uint8
+
+const (
+	// JustifyStartInline indicates that the start of the slot range
+	// is not the start of a line.
+	JustifyStartInline JustifyFlags = 1 << iota
+	// JustifyEndInline indicates that the end of the slot range is
+	// not the end of a line, so that trailing whitespaces are not ignored.
+	JustifyEndInline
+)
+
+// nextBase returns the next slot not attached to another one, or nil
+func (sl *Slot) nextBase() *Slot {
+	s := sl.Next
+	for s != nil && !s.isBase() {
+		s = s.Next
+	}
+	return s
+}
+
+// isEmptyGlyph returns true if the glyph has no outline,
+// typically for whitespaces
+func (seg *Segment) isEmptyGlyph(s *Slot) bool {
+	g := seg.face.getGlyph(s.glyphID)
+	return g == nil || g.bbox == rect{}
+}
+
+// Justify adjusts the positions of the slots from `first` to `last` (included)
+// so that they span `width`, and returns the resulting advance.
+// It is the equivalent of the `gr_seg_justify` function of the reference
+// implementation.
+//
+// `start` is the first slot of the line, which is used as origin for the positions.
+// `first` and `last` are optional and default to `start` and the end of the segment.
+// `font` and `width` must use the same units as the ones given to `Shape`.
+//
+// The difference between the target and the current width is distributed on
+// the slots according to the justification levels defined in the font,
+// starting with the highest level, using the glyph attributes stretch, shrink, step and weight.
+// If the font does not define any level, whitespaces are stretched, or all
+// the slots if there are no whitespaces.
+// Then, the justification passes of the font are applied, and the slots are positioned again.
+// If the font has line-end contextual rules, the line break pseudo glyph is
+// inserted at the edges of the line during these passes, unless `flags`
+// indicates that the range does not start or end a line.
+//
+// A negative `width` only runs the justification passes, if the font
+// requires line end contextualization.
+func (seg *Segment) Justify(start *Slot, font *FontOptions, width float32, flags JustifyFlags, first, last *Slot) float32 {
+	silf := seg.silf
+	if start == nil || (width < 0 && silf.flags == 0) {
+		return width
+	}
+
+	var scale float32 = 1
+	if font != nil {
+		scale = font.scale
+	}
+
+	reversed := (seg.dir&1 != 0) != silf.isRTL && int(silf.indexBidiPass) != len(silf.passes)
+	if reversed {
+		seg.reverseSlots()
+		first, last = last, first
+	}
+	if first == nil {
+		first = start
+	}
+	first = first.findRoot()
+	if last == nil {
+		last = seg.last
+	}
+	last = last.findRoot()
+
+	base := first.Position.X / scale
+	width = width / scale
+	if flags&JustifyEndInline == 0 { // ignore the trailing whitespaces
+		last = seg.trimTrailingSpaces(first, last)
+	}
+
+	var end *Slot
+	if last != nil {
+		end = last.nextBase()
+	}
+
+	numLevels := len(silf.justificationLevels)
+	if numLevels == 0 {
+		// no justification data: only stretch the whitespaces,
+		// or every slot if there are none
+		setStretchable := func(s *Slot) {
+			s.setJustify(seg, 0, 3, 1)  // weight
+			s.setJustify(seg, 0, 2, 1)  // step
+			s.setJustify(seg, 0, 0, -1) // (unlimited) stretch
+		}
+		hasSpace := false
+		for s := start; s != nil && s != end; s = s.nextBase() {
+			if c := seg.getCharInfo(s.Before); c != nil && unicode.Is(unicode.White_Space, c.char) {
+				setStretchable(s)
+				hasSpace = true
+			}
+		}
+		if !hasSpace {
+			for s := start; s != nil && s != end; s = s.nextBase() {
+				setStretchable(s)
+			}
+		}
+		numLevels = 1
+	}
+
+	var currWidth float32
+	weights := make([]int, numLevels)
+	for s := first; s != nil && s != end; s = s.nextBase() {
+		if w := s.Position.X/scale + s.Advance.X - base; w > currWidth {
+			currWidth = w
+		}
+		for j := range weights {
+			weights[j] += int(s.getJustify(seg, uint8(j), 3))
+		}
+		s.just = 0
+	}
+
+	i := numLevels - 1
+	if width < 0 {
+		i = -1
+	}
+	for ; i >= 0; i-- {
+		tWeight := weights[i]
+		if tWeight == 0 {
+			continue
+		}
+		level := uint8(i)
+		// at level 0, the rounding error is redistributed (with a safety
+		// bound on the number of iterations)
+		for iter := 0; iter < 100; iter++ {
+			var err float32
+			diff := width - currWidth
+			diffpw := diff / float32(tWeight)
+			tWeight = 0
+			for s := first; s != nil && s != end; s = s.nextBase() {
+				w := s.getJustify(seg, level, 3)
+				pref := diffpw*float32(w) + err
+				step := s.getJustify(seg, level, 2)
+				if step == 0 {
+					step = 1 // handle lazy font developers
+				}
+				if pref > 0 {
+					max := float32(uint16(s.getJustify(seg, level, 0)))
+					if i == 0 {
+						max -= s.just
+					}
+					if pref > max {
+						pref = max
+					} else {
+						tWeight += int(w)
+					}
+				} else {
+					max := float32(uint16(s.getJustify(seg, level, 1)))
+					if i == 0 {
+						max += s.just
+					}
+					if -pref > max {
+						pref = -max
+					} else {
+						tWeight += int(w)
+					}
+				}
+				actual := int(pref/float32(step)) * int(step)
+				if actual != 0 {
+					err += diffpw*float32(w) - float32(actual)
+					if i == 0 {
+						s.just += float32(actual)
+					} else {
+						s.setJustify(seg, level, 4, int16(actual))
+					}
+				}
+			}
+			currWidth += diff - err
+			if !(i == 0 && int(abs(err)) > 0 && tWeight != 0) {
+				break
+			}
+		}
+	}
+
+	oldFirst, oldLast := seg.First, seg.last
+	wholeSegment := start == oldFirst && end == nil
+	// line end contextualization: the edges of the line are
+	// marked with the line break pseudo glyph
+	lineStart := silf.flags&1 != 0 && flags&JustifyStartInline == 0
+	lineEnd := silf.flags&1 != 0 && flags&JustifyEndInline == 0
+	if lineStart {
+		start = seg.addLineEnd(start)
+	}
+	if lineEnd {
+		last = seg.addLineEnd(end)
+	}
+	seg.First, seg.last = start, last
+
+	logger := seg.face.logger
+	if logger != nil {
+		seg.trace = &traceOutput{}
+	}
+
+	if silf.indexJustPass != silf.indexPosPass && (width >= 0 || silf.flags&1 != 0) {
+		silf.runGraphite(seg, silf.indexJustPass, silf.indexPosPass, false)
+	}
+
+	if logger != nil {
+		seg.positionSlots(nil, start, last, seg.dir&1 != 0, true)
+		out := justifyOutput{Justifies: seg.objectID(), Passes: seg.trace.Passes}
+		for s := start; s != nil; s = s.Next {
+			out.Output = append(out.Output, s.json(seg))
+			if s == last {
+				break
+			}
+		}
+		logger.write(out)
+		seg.trace = nil
+	}
+
+	res := seg.positionSlots(font, start, last, seg.dir&1 != 0, true)
+
+	if lineStart {
+		seg.delLineEnd(seg.First)
+	}
+	if lineEnd {
+		seg.delLineEnd(seg.last)
+	}
+	seg.First, seg.last = oldFirst, oldLast
+
+	if reversed {
+		seg.reverseSlots()
+	}
+	if wholeSegment {
+		seg.Advance = res
+	}
+	return res.X
+}
+
+// trimTrailingSpaces returns the last slot in [first, last]
+// which is not a whitespace (or first)
+func (seg *Segment) trimTrailingSpaces(first, last *Slot) *Slot {
+	for last != first && last != nil && seg.isEmptyGlyph(last) {
+		last = last.prev
+	}
+	return last
+}
+
+// ReshapeLine prepares the slots from `first` to `last` (included) to be displayed
+// as a line of text, and returns the advance of the line, excluding the trailing whitespaces
+// (unless `JustifyEndInline` is set).
+// It is typically used after choosing the line breaks of a paragraph shaped as one
+// segment (see `LineBreaks`), once for each line.
+// `first` and `last` are optional and default to the edges of the segment.
+//
+// The line-end contextual rules of the font, which match the line break pseudo glyph
+// and are stored in its justification passes, are applied at the start and at the end of the line,
+// unless `flags` indicates that the range does not start or end a line.
+// Then, the slots are positioned, relatively to the start of the line.
+// Any previous justification is removed.
+func (seg *Segment) ReshapeLine(font *FontOptions, first, last *Slot, flags JustifyFlags) float32 {
+	if first == nil {
+		first = seg.First
+	}
+	if first == nil {
+		return 0
+	}
+	if seg.silf.flags&1 != 0 {
+		return seg.Justify(first, font, -1, flags, first, last)
+	}
+
+	// no line-end contextuals: only position the line
+	wholeSegment := first == seg.First && (last == nil || last == seg.last)
+	if last == nil {
+		last = seg.last
+	}
+	if flags&JustifyEndInline == 0 {
+		last = seg.trimTrailingSpaces(first, last)
+	}
+	for s := first; s != nil; s = s.Next {
+		s.just = 0
+		if s == last {
+			break
+		}
+	}
+	res := seg.positionSlots(font, first, last, seg.dir&1 != 0, true)
+	if wholeSegment {
+		seg.Advance = res
+	}
+	return res.X
+}
+
+// addLineEnd inserts a line end slot before `next`,
+// or at the end of the segment if `next` is nil.
+func (seg *Segment) addLineEnd(next *Slot) *Slot {
+	eSlot := seg.newSlot()
+	eSlot.setGlyph(seg, seg.silf.lbGID)
+	if next != nil {
+		eSlot.Next = next
+		eSlot.prev = next.prev
+		if next.prev != nil {
+			next.prev.Next = eSlot
+		}
+		next.prev = eSlot
+		eSlot.Before = next.Before
+		if eSlot.prev != nil {
+			eSlot.After = eSlot.prev.After
+		} else {
+			eSlot.After = next.Before
+		}
+	} else {
+		next = seg.last
+		eSlot.prev = next
+		next.Next = eSlot
+		eSlot.After = next.After
+		eSlot.Before = next.After
+	}
+	return eSlot
+}
+
+// delLineEnd removes a slot added by `addLineEnd`.
+func (seg *Segment) delLineEnd(s *Slot) {
+	if s == nil {
+		return
+	}
+	if next := s.Next; next != nil {
+		next.prev = s.prev
+		if s.prev != nil {
+			s.prev.Next = next
+		}
+	} else if s.prev != nil {
+		s.prev.Next = nil
+	}
+	seg.freeSlot(s)
+}
@@ -0,0 +1,207 @@
+package graphite
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// positions returns the X positions of the base slots
+func basePositions(seg *Segment) []float32 {
+	var out []float32
+	for s := seg.First; s != nil; s = s.Next {
+		if s.isBase() {
+			out = append(out, s.Position.X)
+		}
+	}
+	return out
+}
+
+func TestJustifyStretch(t *testing.T) {
+	for _, file := range []string{
+		"charis.ttf", // with one justification level
+		"Padauk.ttf", // without justification levels
+	} {
+		face := loadGraphite(t, file)
+		text := []rune("hello world and more")
+		seg := face.Shape(nil, text, 0, nil, 0)
+		natural := seg.Advance.X
+		before := basePositions(seg)
+
+		target := natural + 600
+		got := seg.Justify(seg.First, nil, target, 0, nil, nil)
+		if math.Abs(float64(got-target)) > 1 {
+			t.Fatalf("%s: expected width %f, got %f", file, target, got)
+		}
+		if seg.Advance.X != got {
+			t.Fatalf("%s: segment advance not updated", file)
+		}
+		after := basePositions(seg)
+		if len(after) != len(before) {
+			t.Fatalf("%s: unexpected positions %v (before %v)", file, after, before)
+		}
+		// the inserted space accumulates along the line
+		for i := 1; i < len(after); i++ {
+			if after[i]-before[i] < after[i-1]-before[i-1] {
+				t.Fatalf("%s: unexpected positions %v (before %v)", file, after, before)
+			}
+		}
+		if after[len(after)-1] <= before[len(before)-1] {
+			t.Fatalf("%s: last glyph not moved", file)
+		}
+
+		// justifying again to the natural width reverts the change
+		got = seg.Justify(seg.First, nil, natural, 0, nil, nil)
+		if math.Abs(float64(got-natural)) > 1 {
+			t.Fatalf("%s: expected width %f, got %f", file, natural, got)
+		}
+	}
+}
+
+func TestJustifyScaled(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	font := NewFontOptions(12, face)
+	seg := face.Shape(font, []rune("a b c"), 0, nil, 0)
+	target := seg.Advance.X * 2
+	if got := seg.Justify(seg.First, font, target, 0, nil, nil); math.Abs(float64(got-target)) > 0.1 {
+		t.Fatalf("expected width %f, got %f", target, got)
+	}
+}
+
+func TestJustifyNoSpace(t *testing.T) {
+	face := loadGraphite(t, "Padauk.ttf")
+	seg := face.Shape(nil, []rune("abc"), 0, nil, 0)
+	natural := seg.Advance.X
+	// every slot is stretched
+	if got := seg.Justify(seg.First, nil, natural+500, 0, nil, nil); got != natural+500 {
+		t.Fatalf("expected width %f, got %f", natural+500, got)
+	}
+	for s := seg.First; s != nil; s = s.Next {
+		if s.just <= 0 {
+			t.Fatalf("expected stretched slot, got %f", s.just)
+		}
+	}
+}
+
+func TestJustifyLogging(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	seg := face.Shape(nil, []rune("hello world"), 0, nil, 0)
+
+	var w bytes.Buffer
+	face.StartLogging(&w)
+	seg.Justify(seg.First, nil, seg.Advance.X+600, 0, nil, nil)
+	if err := face.StopLogging(); err != nil {
+		t.Fatal(err)
+	}
+
+	var trace []struct {
+		Justifies string        `json:"justifies"`
+		Output    []interface{} `json:"output"`
+	}
+	if err := json.Unmarshal(w.Bytes(), &trace); err != nil {
+		t.Fatalf("invalid JSON trace: %s", err)
+	}
+	if len(trace) != 1 || trace[0].Justifies == "" || len(trace[0].Output) != seg.NumGlyphs {
+		t.Fatalf("unexpected trace %s", w.String())
+	}
+}
+
+func TestLineBreaks(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	text := []rune("hello world and more")
+	seg := face.Shape(nil, text, 0, nil, 0)
+
+	if got, exp := seg.LineBreaks(BreakWord), []int{6, 12, 16}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	if got := seg.LineBreaks(BreakNone); len(got) != 0 {
+		t.Fatalf("expected no breaks, got %v", got)
+	}
+}
+
+// lineSlots returns the first and last slots of the characters [start, end)
+func lineSlots(seg *Segment, start, end int) (first, last *Slot) {
+	for s := seg.First; s != nil; s = s.Next {
+		if s.Before >= start && s.After < end {
+			if first == nil {
+				first = s
+			}
+			last = s
+		}
+	}
+	return first, last
+}
+
+func TestReshapeLine(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	text := []rune("hello world and more")
+	seg := face.Shape(nil, text, 0, nil, 0)
+
+	start := 0
+	for _, end := range append(seg.LineBreaks(BreakWord), len(text)) {
+		first, last := lineSlots(seg, start, end)
+		got := seg.ReshapeLine(nil, first, last, 0)
+		if first.Position.X != 0 {
+			t.Fatalf("line %d: expected line relative positions, got %f", start, first.Position.X)
+		}
+		// trailing spaces are ignored
+		line := face.Shape(nil, []rune(strings.TrimSpace(string(text[start:end]))), 0, nil, 0)
+		if got != line.Advance.X {
+			t.Fatalf("line %d: expected advance %f, got %f", start, line.Advance.X, got)
+		}
+		start = end
+	}
+}
+
+func TestReshapeLineEnds(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	face.silf[0].flags |= 1 // simulate line end contextuals
+	text := []rune("hello world")
+	seg := face.Shape(nil, text, 0, nil, 0)
+	lb := face.silf[0].lbGID
+
+	for _, test := range []struct {
+		flags              JustifyFlags
+		lineStart, lineEnd bool
+	}{
+		{0, true, true},
+		{JustifyStartInline, false, true},
+		{JustifyEndInline, true, false},
+		{JustifyStartInline | JustifyEndInline, false, false},
+	} {
+		var w bytes.Buffer
+		face.StartLogging(&w)
+		seg.ReshapeLine(nil, nil, nil, test.flags)
+		if err := face.StopLogging(); err != nil {
+			t.Fatal(err)
+		}
+
+		var trace []struct {
+			Output []struct {
+				Gid GID `json:"gid"`
+			} `json:"output"`
+		}
+		if err := json.Unmarshal(w.Bytes(), &trace); err != nil {
+			t.Fatal(err)
+		}
+		output := trace[0].Output
+		if (output[0].Gid == lb) != test.lineStart || (output[len(output)-1].Gid == lb) != test.lineEnd {
+			t.Fatalf("flags %d: unexpected line ends %v", test.flags, output)
+		}
+
+		// the line end slots are removed
+		var n int
+		for s := seg.First; s != nil; s = s.Next {
+			if s.glyphID == lb {
+				t.Fatal("unexpected line end slot")
+			}
+			n++
+		}
+		if n != seg.NumGlyphs || seg.First.prev != nil || seg.last.Next != nil {
+			t.Fatalf("flags %d: invalid slots", test.flags)
+		}
+	}
+}
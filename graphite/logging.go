@@ -3,12 +3,81 @@ package graphite
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"sync"
 )
 
 // this file implements tracing helpers, which are only used
-// in debug mode
-
-var tr = &traceOutput{}
+// when logging is enabled (see `GraphiteFace.StartLogging`)
+
+// faceLogger writes the traces of the segments
+// shaped by a face, as a JSON array
+type faceLogger struct {
+	w     io.Writer
+	err   error // first write error
+	count int   // number of items written
+	mu    sync.Mutex
+}
+
+// StartLogging enables the tracing of the shaping process: each following
+// call to `Shape` (and `Segment.Justify`) writes to `w` a JSON object
+// describing the passes, the rules applied, the collision fixes and the final
+// slots of the segment. The objects are written as the items of a JSON array,
+// which is closed by `StopLogging`.
+// The output uses the same schema as the one produced by `gr_start_logging`
+// in the reference implementation, so that the same tools may be used to inspect it.
+//
+// Tracing slows down the shaping and, as in the reference implementation,
+// may slightly change the positions of the slots, since they
+// are computed again after each pass.
+// The logger is shared by all the segments shaped with the face, and must not be
+// changed while shaping.
+// If a log is already started, it is stopped first.
+func (face *GraphiteFace) StartLogging(w io.Writer) {
+	face.StopLogging()
+	lg := &faceLogger{w: w}
+	_, lg.err = io.WriteString(w, "[")
+	face.logger = lg
+}
+
+// StopLogging disables the tracing started by `StartLogging`,
+// closing the JSON array, and returns the first error
+// encountered when writing the traces, if any.
+// It is a no-op if the logging is not enabled.
+func (face *GraphiteFace) StopLogging() error {
+	lg := face.logger
+	if lg == nil {
+		return nil
+	}
+	face.logger = nil
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if lg.err == nil {
+		_, lg.err = io.WriteString(lg.w, "]\n")
+	}
+	return lg.err
+}
+
+// write appends an item to the array
+func (lg *faceLogger) write(item interface{}) {
+	b, err := json.Marshal(item)
+	lg.mu.Lock()
+	defer lg.mu.Unlock()
+	if lg.err != nil {
+		return
+	}
+	if err != nil {
+		lg.err = err
+		return
+	}
+	if lg.count != 0 {
+		b = append([]byte{','}, b...)
+	}
+	lg.count++
+	_, lg.err = lg.w.Write(append(b, '\n'))
+}
+
+func (seg *Segment) objectID() string { return fmt.Sprintf("%p", seg) }
 
 type traceOutput struct {
 	colliderEnv colliderEnv
@@ -43,16 +112,23 @@ func (tr *traceOutput) appendPass(s *passes, seg *Segment, i uint8) {
 	tr.Passes = append(tr.Passes, debug)
 }
 
-func (tr *traceOutput) finaliseOutput(seg *Segment) {
+func (tr *traceOutput) finaliseOutput(seg *Segment, advance Position) {
 	tr.Outputdir = "ltr"
 	if seg.currdir() {
 		tr.Outputdir = "rtl"
 	}
 	tr.Output = seg.slotsJSON()
-	tr.Advance = seg.Advance
+	tr.Advance = advance
 	tr.Chars = seg.charinfo
 }
 
+// justifyOutput is the trace of a call to `Segment.Justify`
+type justifyOutput struct {
+	Justifies string     `json:"justifies"`
+	Passes    []passJSON `json:"passes"`
+	Output    []slotJSON `json:"output"`
+}
+
 func (ci charInfo) MarshalJSON() ([]byte, error) {
 	type charInfoSlotJSON struct {
 		Before int `json:"before"`
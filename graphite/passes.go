@@ -173,7 +173,7 @@ func (pass *pass) testPassConstraint(m *machine) (bool, error) {
 	m.map_.pushSlot(m.map_.segment.First)
 	ret, _, err := m.run(pass.constraint, 1)
 
-	if debugMode >= 2 {
+	if tr := m.map_.segment.trace; tr != nil {
 		tr.setCurrentPassConstraint(ret != 0 && err == nil)
 	}
 
@@ -199,7 +199,7 @@ func (pa *pass) findAndDoRule(slot *Slot, m *machine, fsm *finiteStateMachine) (
 			}
 		}
 
-		if debugMode >= 2 {
+		if tr := fsm.slots.segment.trace; tr != nil {
 			tr.startDumpRule(fsm, i)
 		}
 
@@ -212,7 +212,7 @@ func (pa *pass) findAndDoRule(slot *Slot, m *machine, fsm *finiteStateMachine) (
 			)
 			adv, slot, err = pa.doAction(&rule.action, m)
 
-			if debugMode >= 2 {
+			if tr := fsm.slots.segment.trace; tr != nil {
 				tr.dumpRuleOutput(fsm, r, slot)
 			}
 
@@ -225,14 +225,14 @@ func (pa *pass) findAndDoRule(slot *Slot, m *machine, fsm *finiteStateMachine) (
 			}
 			slot = pa.adjustSlot(adv, slot, &fsm.slots)
 
-			if debugMode >= 2 {
+			if tr := fsm.slots.segment.trace; tr != nil {
 				tr.dumpRuleCursor(slot)
 			}
 
 			return slot, nil
 		}
 
-		if debugMode >= 2 {
+		if tr := fsm.slots.segment.trace; tr != nil {
 			tr.dumpRuleCursor(slot.Next)
 		}
 	}
@@ -460,13 +460,13 @@ func (pass *pass) collisionShift(seg *Segment, isRTL bool) bool {
 	var end *Slot
 	moved := false
 
-	if debugMode >= 2 {
+	if tr := seg.trace; tr != nil {
 		tr.startDumpCollisions(pass.collisionLoops)
 	}
 
 	for start != nil {
 
-		if debugMode >= 2 {
+		if tr := seg.trace; tr != nil {
 			tr.startDumpCollisionPhase("1", -1)
 		}
 
@@ -493,7 +493,7 @@ func (pass *pass) collisionShift(seg *Segment, isRTL bool) bool {
 		for i := 0; i < int(pass.collisionLoops)-1; i++ {
 			if hasCollisions || moved {
 
-				if debugMode >= 2 {
+				if tr := seg.trace; tr != nil {
 					tr.startDumpCollisionPhase("2a", i)
 				}
 
@@ -527,7 +527,7 @@ func (pass *pass) collisionShift(seg *Segment, isRTL bool) bool {
 					}
 				}
 
-				if debugMode >= 2 {
+				if tr := seg.trace; tr != nil {
 					tr.startDumpCollisionPhase("2b", i)
 				}
 
@@ -578,7 +578,7 @@ func (pass *pass) collisionKern(seg *Segment, isRTL bool) bool {
 	)
 
 	// phase 3 : handle kerning of clusters
-	if debugMode >= 2 {
+	if tr := seg.trace; tr != nil {
 		tr.startDumpCollisionPhase("3", -1)
 	}
 
@@ -782,8 +782,12 @@ type passes struct {
 	attrSkipPasses     byte  // Glyph attribute of bitmap indicating key glyphs for pass optimization
 	attrCollision      byte  // Glyph attribute number for collision.flags attribute (several more collision attrs come after it...)
 
+	lbGID GID  // glyph used for line ends, see `Segment.Justify`
+	flags byte // general flags, see `silfSubtablePart1.Flags`
+
 	indexBidiPass byte // (0xFF) means no bidi pass
 	indexPosPass  byte // index of the first positionning pass
+	indexJustPass byte // index of the first justification pass
 	hasCollision  bool
 	isRTL         bool
 }
@@ -834,6 +838,9 @@ func newPasses(silf *silfSubtable, numAttributes, numFeatures uint16) (out passe
 
 	out.indexBidiPass = silf.IBidi
 	out.indexPosPass = silf.IPos
+	out.indexJustPass = silf.IJust
+	out.lbGID = GID(silf.lbGID)
+	out.flags = silf.Flags
 	out.hasCollision = silf.Flags&0x20 != 0
 	// see the reference implementation for this switch
 	out.isRTL = (silf.Direction-1)&1 != 0
@@ -892,9 +899,9 @@ func (s *passes) runGraphite(seg *Segment, firstPass, lastPass uint8, doBidi boo
 			continue
 		}
 
-		if debugMode >= 2 {
+		if seg.trace != nil {
 			seg.positionSlots(nil, nil, nil, seg.currdir(), true)
-			tr.appendPass(s, seg, i)
+			seg.trace.appendPass(s, seg, i)
 		}
 
 		// test whether to reorder, prepare for positioning
@@ -17,6 +17,11 @@ func (ch *charInfo) addFlags(val uint8) { ch.flags |= val }
 // Segment represents a line of text.
 // It is used internally during shaping and
 // returned as the result of the operation.
+//
+// The glyphs are stored as a linked list of slots, which
+// may be walked with `First` and `Slot.Next` (or `Last` and `Slot.Prev`).
+// The mapping between the input characters and the slots
+// is described by `CharInfo`.
 type Segment struct {
 	// Start of the segment (may be nil for empty segments)
 	First *Slot
@@ -33,6 +38,11 @@ type Segment struct {
 	freeSlots  *Slot // linked list of free slots
 	collisions []slotCollision
 
+	trace *traceOutput // non nil when tracing is enabled
+
+	// hinted advances used during shaping, or nil
+	hinting *FontOptions
+
 	// Advance of the whole segment
 	Advance Position
 
@@ -49,6 +59,90 @@ type Segment struct {
 
 }
 
+// Last returns the last slot of the segment (nil for empty segments).
+func (seg *Segment) Last() *Slot { return seg.last }
+
+// CharInfo describes how an input character is
+// mapped to the slots of a segment.
+type CharInfo struct {
+	// Char is the input character.
+	Char rune
+	// Base is the index of the character in the input text.
+	Base int
+	// Before and After are the indices (see `Slot.Index`) of
+	// the first and last slots associated to the character,
+	// that is the slots a cursor may be placed before or after.
+	Before, After int
+	// BreakWeight is the line breaking weight of the character,
+	// as defined by the font (and possibly modified by its rules).
+	// See `BreakWhitespace` and the other constants.
+	BreakWeight int16
+}
+
+// Line breaking weights, as used by the `breakweight` glyph attribute.
+// Positive values allow a break after a character, negative values
+// a break before it. The lower the absolute value, the better the break.
+const (
+	BreakNone       = 0
+	BreakWhitespace = 10
+	BreakWord       = 15
+	BreakIntra      = 20
+	BreakLetter     = 30
+	BreakClip       = 40
+
+	BreakBeforeWhitespace = -BreakWhitespace
+	BreakBeforeWord       = -BreakWord
+	BreakBeforeIntra      = -BreakIntra
+	BreakBeforeLetter     = -BreakLetter
+	BreakBeforeClip       = -BreakClip
+)
+
+// NumChars returns the number of input characters.
+func (seg *Segment) NumChars() int { return len(seg.charinfo) }
+
+// CharInfo returns the information about the input character at `index`,
+// which must be in [0, NumChars()).
+func (seg *Segment) CharInfo(index int) CharInfo {
+	c := seg.charinfo[index]
+	return CharInfo{
+		Char:        c.char,
+		Base:        c.base,
+		Before:      c.before,
+		After:       c.after,
+		BreakWeight: c.breakWeight,
+	}
+}
+
+// LineBreaks returns the indices of the input characters before which a line
+// may be broken, according to the break weights of the font: a break is allowed
+// between two characters if the weight of the first one is in [1, maxWeight], or if the weight
+// of the second one is in [-maxWeight, -1]. For instance, a `maxWeight` of `BreakWord`
+// only allows breaks after whitespaces and between words.
+// Breaks inside a cluster of slots are never allowed.
+// The returned indices are sorted and in [1, NumChars()).
+func (seg *Segment) LineBreaks(maxWeight int16) []int {
+	// inCluster[i] is true if a slot covers both i-1 and i
+	inCluster := make([]bool, len(seg.charinfo))
+	for s := seg.First; s != nil; s = s.Next {
+		for i := s.Before + 1; i <= s.After && i < len(inCluster); i++ {
+			if i > 0 {
+				inCluster[i] = true
+			}
+		}
+	}
+	var out []int
+	for i := 1; i < len(seg.charinfo); i++ {
+		if inCluster[i] {
+			continue
+		}
+		after, before := seg.charinfo[i-1].breakWeight, seg.charinfo[i].breakWeight
+		if (0 < after && after <= maxWeight) || (-maxWeight <= before && before < 0) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
 func (seg *Segment) currdir() bool { return ((seg.dir>>reverseBit)^seg.dir)&1 != 0 }
 
 const (
@@ -2,6 +2,7 @@ package graphite
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
@@ -295,12 +296,10 @@ var fuzzTestInput = []shapingInput{
 }
 
 func TestShapeSegmentFuzz(t *testing.T) {
-	// WARNING: the debug mode must be disabled for some
+	// WARNING: the logging must be disabled for some
 	// test to pass (due to the additional positionSlots calls)
 
 	for _, input := range fuzzTestInput {
-		// tr.reset()
-
 		expected, err := testdata.Files.ReadFile("shape_refs/fuzz/" + input.name + ".log")
 		if err != nil {
 			t.Fatal(err)
@@ -308,13 +307,64 @@ func TestShapeSegmentFuzz(t *testing.T) {
 
 		err = input.test(t, expected)
 
-		// if err := tr.dump("testdata/trace.json"); err != nil {
-		// 	t.Fatal(err)
-		// }
-
 		if err != nil {
 			t.Fatal(err)
 		}
 
 	}
 }
+
+func TestLogging(t *testing.T) {
+	for _, input := range []struct {
+		fontfile string
+		text     []rune
+		dir      int8
+	}{
+		{"charis.ttf", []rune("ab́c"), 0},
+		{"AwamiNastaliq-Regular.ttf", []rune{0x06c6, 0x068a, 0x062c, 0x0648}, 1},
+	} {
+		face := loadGraphite(t, input.fontfile)
+		var w bytes.Buffer
+		face.StartLogging(&w)
+		seg := face.Shape(nil, input.text, 0, nil, input.dir)
+		face.Shape(nil, input.text, 0, nil, input.dir)
+		if err := face.StopLogging(); err != nil {
+			t.Fatal(err)
+		}
+		if face.Shape(nil, input.text, 0, nil, input.dir); strings.Count(w.String(), `"outputdir"`) != 2 {
+			t.Fatalf("expected no trace after StopLogging")
+		}
+
+		var trace []struct {
+			Id        string                   `json:"id"`
+			Passes    []map[string]interface{} `json:"passes"`
+			Outputdir string                   `json:"outputdir"`
+			Output    []map[string]interface{} `json:"output"`
+			Advance   [2]float32               `json:"advance"`
+			Chars     []map[string]interface{} `json:"chars"`
+		}
+		if err := json.Unmarshal(w.Bytes(), &trace); err != nil {
+			t.Fatalf("invalid JSON trace: %s", err)
+		}
+		if len(trace) != 2 {
+			t.Fatalf("expected 2 segments, got %d", len(trace))
+		}
+		seg0 := trace[0]
+		if seg0.Id == "" || seg0.Id == trace[1].Id {
+			t.Fatalf("invalid segment ids %s %s", seg0.Id, trace[1].Id)
+		}
+		if len(seg0.Passes) == 0 || len(seg0.Chars) != len(input.text) || len(seg0.Output) != seg.NumGlyphs {
+			t.Fatalf("unexpected trace %v", seg0)
+		}
+		if seg0.Advance[0] != seg.Advance.X {
+			t.Fatalf("expected advance %f, got %f", seg.Advance.X, seg0.Advance[0])
+		}
+		for _, pass := range seg0.Passes {
+			for _, key := range []string{"id", "slotsdir", "passdir", "slots", "rules"} {
+				if _, ok := pass[key]; !ok {
+					t.Fatalf("missing key %s in pass %v", key, pass)
+				}
+			}
+		}
+	}
+}
@@ -72,6 +72,49 @@ func (sl *Slot) GID() fonts.GID {
 	return sl.glyphID
 }
 
+// Prev returns the previous slot along the segment, or nil
+// for the first slot.
+func (sl *Slot) Prev() *Slot { return sl.prev }
+
+// AttachedTo returns the slot this slot is attached to (typically
+// the base glyph of a diacritic), or nil.
+func (sl *Slot) AttachedTo() *Slot { return sl.parent }
+
+// FirstAttachment returns the first slot attached to this slot, or nil.
+// The other ones are obtained with `NextSiblingAttachment`.
+func (sl *Slot) FirstAttachment() *Slot { return sl.child }
+
+// NextSiblingAttachment returns the next slot attached to the same
+// parent as this slot, or nil.
+func (sl *Slot) NextSiblingAttachment() *Slot { return sl.sibling }
+
+// Index returns the index of the slot in the segment, as
+// used by `CharInfo.Before` and `CharInfo.After`.
+func (sl *Slot) Index() int { return sl.index }
+
+// Original returns the index of the input character which
+// originated the slot (see `Segment.CharInfo`).
+func (sl *Slot) Original() int { return sl.original }
+
+// AdvanceX returns the horizontal advance of the slot, scaled
+// according to `font`, which may be nil.
+// Hinted advances are used, if provided by `font`.
+func (sl *Slot) AdvanceX(font *FontOptions) float32 {
+	if font != nil {
+		return font.hintedAdvance(sl.glyphID, sl.Advance.X)
+	}
+	return sl.Advance.X
+}
+
+// AdvanceY returns the vertical advance of the slot, scaled
+// according to `font`, which may be nil.
+func (sl *Slot) AdvanceY(font *FontOptions) float32 {
+	if font != nil {
+		return sl.Advance.Y * font.scale
+	}
+	return sl.Advance.Y
+}
+
 // returns true if the slot has no parent
 func (sl *Slot) isBase() bool {
 	return sl.parent == nil
@@ -178,7 +221,7 @@ func (sl *Slot) setGlyph(seg *Segment, glyphID GID) {
 			aGlyph = theGlyph
 		}
 	}
-	sl.Advance = Position{X: float32(aGlyph.advance.x), Y: 0.}
+	sl.Advance = Position{X: aGlyph.advance.X, Y: 0.}
 	if seg.silf.attrSkipPasses != 0 {
 		seg.mergePassBits(uint32(theGlyph.attrs.get(uint16(seg.silf.attrSkipPasses))))
 		if len(seg.silf.passes) > 16 {
@@ -652,7 +695,11 @@ func (sl *Slot) finalise(seg *Segment, font *FontOptions, base Position, bbox *r
 	if font != nil {
 		scale = font.scale
 		shift = shift.scale(scale)
-		tAdvance *= scale
+		tAdvance = font.hintedAdvance(sl.glyphID, tAdvance)
+	} else if seg.hinting != nil {
+		// keep the positions used by the rules and the collision
+		// avoidance consistent with the final (hinted) ones
+		tAdvance = seg.hinting.designAdvance(sl.glyphID, tAdvance)
 	}
 	var res Position
 
@@ -757,7 +804,13 @@ type slotJustify struct {
 }
 
 func (sj *slotJustify) loadSlot(s *Slot, seg *Segment) {
-	sj.values = make([][numJustParams]int16, len(seg.silf.justificationLevels))
+	// level 0 is always available, so that justification
+	// may be applied to fonts without justification levels (see `Segment.Justify`)
+	numLevels := len(seg.silf.justificationLevels)
+	if numLevels == 0 {
+		numLevels = 1
+	}
+	sj.values = make([][numJustParams]int16, numLevels)
 	for i, justs := range seg.silf.justificationLevels {
 		v := &sj.values[i]
 		v[0] = seg.face.getGlyphAttr(s.glyphID, uint16(justs.AttrStretch))
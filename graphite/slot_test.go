@@ -3,6 +3,7 @@ package graphite
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"testing"
 )
 
@@ -11,3 +12,103 @@ func TestJSON(t *testing.T) {
 	b, _ := json.MarshalIndent(p, "", "\t")
 	fmt.Println(string(b))
 }
+
+func TestSegmentInspection(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	text := []rune("ab\u0301c")
+	font := NewFontOptions(16, face)
+	seg := face.Shape(font, text, 0, nil, 0)
+
+	if seg.NumChars() != len(text) {
+		t.Fatalf("expected %d chars, got %d", len(text), seg.NumChars())
+	}
+
+	// walk in both directions
+	var slots []*Slot
+	for s := seg.First; s != nil; s = s.Next {
+		slots = append(slots, s)
+	}
+	if len(slots) != seg.NumGlyphs || slots[len(slots)-1] != seg.Last() {
+		t.Fatalf("inconsistent slots")
+	}
+	for i, s := range slots {
+		if s.Index() != i {
+			t.Fatalf("expected index %d, got %d", i, s.Index())
+		}
+		if i > 0 && s.Prev() != slots[i-1] {
+			t.Fatalf("inconsistent Prev for slot %d", i)
+		}
+		if s.AdvanceX(font) != s.Advance.X*font.scale || s.AdvanceX(nil) != s.Advance.X {
+			t.Fatalf("unexpected advance")
+		}
+	}
+
+	// the diacritic is attached to its base
+	mark := slots[2]
+	if mark.Original() != 2 || mark.AttachedTo() != slots[1] || slots[1].FirstAttachment() != mark {
+		t.Fatalf("diacritic not attached: %v", mark)
+	}
+	if mark.NextSiblingAttachment() != nil {
+		t.Fatalf("unexpected sibling")
+	}
+
+	for i, r := range text {
+		info := seg.CharInfo(i)
+		if info.Char != r || info.Base != i {
+			t.Fatalf("unexpected char info %v", info)
+		}
+		if info.Before > info.After || slots[info.Before].Before > i || slots[info.After].After < i {
+			t.Fatalf("inconsistent char info %v", info)
+		}
+	}
+}
+
+func TestHintedAdvances(t *testing.T) {
+	face := loadGraphite(t, "charis.ttf")
+	text := []rune("hello")
+	font := NewFontOptions(12, face)
+	hinted := NewFontOptionsWithAdvance(12, face, func(gid GID) float32 {
+		return float32(math.Round(float64(face.HorizontalAdvance(gid) * font.scale)))
+	})
+
+	seg := face.Shape(font, text, 0, nil, 0)
+	hintedSeg := face.Shape(hinted, text, 0, nil, 0)
+
+	var diff float32
+	for s := hintedSeg.First; s != nil; s = s.Next {
+		adv := s.AdvanceX(hinted)
+		if adv != float32(math.Round(float64(adv))) {
+			t.Fatalf("expected hinted advance, got %f", adv)
+		}
+		diff += adv - s.AdvanceX(font)
+	}
+	if got := hintedSeg.Advance.X - seg.Advance.X; math.Abs(float64(got-diff)) > 1e-3 {
+		t.Fatalf("expected advance difference %f, got %f", diff, got)
+	}
+	if hintedSeg.Last().Position.X-seg.Last().Position.X == 0 {
+		t.Fatal("positions not hinted")
+	}
+}
+
+func TestHintedCollisions(t *testing.T) {
+	face := loadGraphite(t, "Awami_test.ttf")
+	text := []rune("بلند بست تحت")
+	hinted := NewFontOptionsWithAdvance(12, face, func(gid GID) float32 {
+		return float32(math.Round(float64(face.HorizontalAdvance(gid) * 12 / float32(face.Upem()))))
+	})
+	seg := face.Shape(hinted, text, 0, nil, 1)
+
+	var final []Position
+	for s := seg.First; s != nil; s = s.Next {
+		final = append(final, s.Position)
+	}
+	// the positions in design units, used by the rules and the collision avoidance,
+	// are consistent with the final ones
+	seg.positionSlots(nil, nil, nil, seg.currdir(), true)
+	for i, s := 0, seg.First; s != nil; i, s = i+1, s.Next {
+		got := s.Position.scale(hinted.scale)
+		if math.Abs(float64(got.X-final[i].X)) > 1e-2 || math.Abs(float64(got.Y-final[i].Y)) > 1e-2 {
+			t.Fatalf("slot %d: expected %v, got %v", i, final[i], got)
+		}
+	}
+}
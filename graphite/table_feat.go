@@ -40,15 +40,27 @@ func (feats FeaturesValue) FindFeature(id Tag) *FeatureValue {
 type tableFeat []feature
 
 type feature struct {
-	settings []featureSetting
+	settings []FeatureSetting
 	id       Tag
 	flags    uint16
 	label    truetype.NameID
 }
 
-type featureSetting struct {
+// FeatureSetting is one of the values allowed for a feature.
+type FeatureSetting struct {
 	Value int16
-	Label truetype.NameID
+	Label truetype.NameID // see `GraphiteFace.Label`
+}
+
+// FeatureRef describes a feature defined by a font.
+type FeatureRef struct {
+	// Settings are the values allowed for the feature, the first
+	// being the default one.
+	Settings []FeatureSetting
+	// ID is the identifier of the feature, as used in `FeatureValue`.
+	ID    Tag
+	Flags uint16
+	Label truetype.NameID // see `GraphiteFace.Label`
 }
 
 // return the feature with their first setting selected (or 0)
@@ -128,7 +140,7 @@ func parseTableFeat(data []byte) (tableFeat, error) {
 	}
 
 	// parse the settings array
-	allSettings := make([]featureSetting, maxSettingsLength)
+	allSettings := make([]FeatureSetting, maxSettingsLength)
 	err = r.ReadStruct(allSettings)
 	if err != nil {
 		return nil, fmt.Errorf("invalid Feat table: %s", err)
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -317,3 +318,54 @@ func TestGetFeature(t *testing.T) {
 		t.Fatal("feature not found")
 	}
 }
+
+func TestFeatureRefs(t *testing.T) {
+	ft := loadGraphite(t, "charis.ttf")
+
+	feats := ft.Features()
+	if len(feats) != 38 {
+		t.Fatalf("expected 38 features, got %d", len(feats))
+	}
+	smcp := feats[0]
+	if smcp.ID != 1058 || len(smcp.Settings) != 2 {
+		t.Fatalf("unexpected feature %v", smcp)
+	}
+	if label, lang := ft.Label(smcp.Label, truetype.PLMicrosoftEnglish); label != "Small Caps" || lang != truetype.PLMicrosoftEnglish {
+		t.Fatalf("unexpected label %s (%d)", label, lang)
+	}
+	// French is not available
+	if label, lang := ft.Label(smcp.Label, 0x040C); label != "Small Caps" || lang != truetype.PLMicrosoftEnglish {
+		t.Fatalf("unexpected label %s (%d)", label, lang)
+	}
+	if label, _ := ft.Label(feats[6].Settings[1].Label, truetype.PLMicrosoftEnglish); label != "Show tramlines" {
+		t.Fatalf("unexpected setting label %s", label)
+	}
+	if label, _ := ft.Label(0xFFFF, truetype.PLMicrosoftEnglish); label != "" {
+		t.Fatalf("unexpected label %s", label)
+	}
+
+	// the IDs match the ones of the default values
+	defaults := ft.FeaturesForLang(0)
+	for _, feat := range feats {
+		if len(feat.Settings) == 0 {
+			continue
+		}
+		if v := defaults.FindFeature(feat.ID); v == nil || v.Value != feat.Settings[0].Value {
+			t.Fatalf("inconsistent default value for feature %d", feat.ID)
+		}
+	}
+
+	langs := ft.Languages()
+	expected := []Tag{
+		truetype.MustNewTag("chz "), truetype.MustNewTag("kxw "), truetype.MustNewTag("ro  "), truetype.MustNewTag("ron "),
+		truetype.MustNewTag("rum "), truetype.MustNewTag("tfr "), truetype.MustNewTag("vi  "), truetype.MustNewTag("vie "),
+	}
+	if !reflect.DeepEqual(langs, expected) {
+		t.Fatalf("expected %v, got %v", expected, langs)
+	}
+	for i, lang := range langs {
+		if got := ft.FeaturesForLang(lang); !reflect.DeepEqual(got, ft.sill[i].applyValues(ft.feat)) {
+			t.Fatalf("unexpected features for language %s", lang)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package harfbuzz
 
 import (
-	"math"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/benoitkugler/textlayout/fonts"
 	"github.com/benoitkugler/textlayout/fonts/truetype"
@@ -31,7 +32,7 @@ const (
 	bsfHasDefaultIgnorables
 	bsfHasSpaceFallback
 	bsfHasGPOSAttachment
-	bsfHasUnsafeToBreak
+	bsfHasGlyphFlags
 	bsfHasCGJ
 	bsfDefault bufferScratchFlags = 0x00000000
 
@@ -51,6 +52,10 @@ const (
 	maxLenDefault = 0x3FFFFFFF
 )
 
+// ReplacementDefault is the default value of `Buffer.Replacement`,
+// that is U+FFFD REPLACEMENT CHARACTER.
+const ReplacementDefault = utf8.RuneError
+
 // Buffer is the main structure holding the input text segment and its properties before shaping,
 // and output glyphs and their information after shaping.
 type Buffer struct {
@@ -86,6 +91,11 @@ type Buffer struct {
 	// ".notdef" glyph.
 	NotFound fonts.GID
 
+	// Replacement is the rune used to replace invalid
+	// sequences met by `AddUTF8` and `AddUTF16`.
+	// It defaults to `ReplacementDefault`.
+	Replacement rune
+
 	// Information about how the text in the buffer should be treated.
 	Flags ShappingOptions
 	// Precise the cluster handling behavior.
@@ -111,6 +121,7 @@ type Buffer struct {
 func NewBuffer() *Buffer {
 	return &Buffer{
 		ClusterLevel: MonotoneGraphemes,
+		Replacement:  ReplacementDefault,
 		maxOps:       maxOpsDefault,
 	}
 }
@@ -173,6 +184,189 @@ func (b *Buffer) AddRunes(text []rune, itemOffset, itemLength int) {
 	b.context[1] = text[itemOffset+itemLength : s]
 }
 
+// AddUTF8 is the same as `AddRunes`, but for UTF-8 encoded text.
+// `itemOffset` and `itemLength` are expressed in bytes, and are expected
+// to fall on character boundaries.
+// The cluster value attributed to each rune is the byte offset of its
+// first byte in `text`.
+// Invalid sequences are replaced by `b.Replacement`, one rune per maximal
+// subpart of an ill-formed sequence (as recommended by the Unicode Standard, section 3.9).
+func (b *Buffer) AddUTF8(text []byte, itemOffset, itemLength int) {
+	if itemLength < 0 {
+		itemLength = len(text) - itemOffset
+	}
+
+	if len(b.Info) == 0 && itemOffset > 0 {
+		// add pre-context
+		b.clearContext(0)
+		prev := text[:itemOffset]
+		for len(prev) > 0 && len(b.context[0]) < contextLength {
+			u, size := b.decodeLastUTF8(prev)
+			prev = prev[:len(prev)-size]
+			b.context[0] = append(b.context[0], u)
+		}
+	}
+
+	end := itemOffset + itemLength
+	for i := itemOffset; i < end; {
+		u, size := b.decodeUTF8(text[i:end])
+		b.append(u, i)
+		i += size
+	}
+
+	// add post-context, without aliasing a previous one
+	var post []rune
+	for next := text[end:]; len(next) > 0 && len(post) < contextLength; {
+		u, size := b.decodeUTF8(next)
+		next = next[size:]
+		post = append(post, u)
+	}
+	b.context[1] = post
+}
+
+// decodeUTF8 decodes the first rune of `text`, which must not be empty,
+// returning the number of bytes used.
+// An invalid sequence is replaced by `b.Replacement`.
+func (b *Buffer) decodeUTF8(text []byte) (rune, int) {
+	u, size := utf8.DecodeRune(text)
+	if u == utf8.RuneError && size <= 1 {
+		return b.Replacement, maximalSubpartUTF8(text)
+	}
+	return u, size
+}
+
+// decodeLastUTF8 decodes the last rune of `text`, which must not be empty,
+// returning the number of bytes used.
+// An invalid sequence is replaced by `b.Replacement`.
+func (b *Buffer) decodeLastUTF8(text []byte) (rune, int) {
+	u, size := utf8.DecodeLastRune(text)
+	if u != utf8.RuneError || size > 1 {
+		return u, size
+	}
+	// look for a truncated sequence at the end of text
+	L := len(text)
+	for start := L - 2; start >= 0 && start >= L-3; start-- {
+		if utf8.RuneStart(text[start]) {
+			if maximalSubpartUTF8(text[start:]) == L-start {
+				return b.Replacement, L - start
+			}
+			break
+		}
+	}
+	return b.Replacement, 1
+}
+
+// maximalSubpartUTF8 returns the length of the maximal subpart
+// of the ill-formed sequence starting `text`, that is the longest
+// prefix of a well-formed sequence, or 1.
+// See the table 3-7 of the Unicode Standard for the well-formed sequences.
+func maximalSubpartUTF8(text []byte) int {
+	c := text[0]
+	var (
+		n      int               // number of continuation bytes
+		lo, hi byte = 0x80, 0xBF // range of the next byte
+	)
+	switch {
+	case 0xC2 <= c && c <= 0xDF:
+		n = 1
+	case c == 0xE0:
+		n, lo = 2, 0xA0
+	case c == 0xED:
+		n, hi = 2, 0x9F
+	case 0xE1 <= c && c <= 0xEF:
+		n = 2
+	case c == 0xF0:
+		n, lo = 3, 0x90
+	case c == 0xF4:
+		n, hi = 3, 0x8F
+	case 0xF1 <= c && c <= 0xF3:
+		n = 3
+	default:
+		return 1
+	}
+	size := 1
+	for ; size <= n && size < len(text); size++ {
+		if c := text[size]; c < lo || hi < c {
+			break
+		}
+		lo, hi = 0x80, 0xBF
+	}
+	return size
+}
+
+// AddUTF16 is the same as `AddRunes`, but for UTF-16 encoded text.
+// `itemOffset` and `itemLength` are expressed in code units, and are expected
+// to fall on character boundaries.
+// The cluster value attributed to each rune is the index
+// of its first code unit in `text`.
+// Unpaired surrogates are replaced by `b.Replacement`.
+func (b *Buffer) AddUTF16(text []uint16, itemOffset, itemLength int) {
+	if itemLength < 0 {
+		itemLength = len(text) - itemOffset
+	}
+
+	if len(b.Info) == 0 && itemOffset > 0 {
+		// add pre-context
+		b.clearContext(0)
+		for prev := itemOffset; prev > 0 && len(b.context[0]) < contextLength; {
+			u, size := b.decodeLastUTF16(text[:prev])
+			prev -= size
+			b.context[0] = append(b.context[0], u)
+		}
+	}
+
+	end := itemOffset + itemLength
+	for i := itemOffset; i < end; {
+		u, size := b.decodeUTF16(text[i:end])
+		b.append(u, i)
+		i += size
+	}
+
+	// add post-context, without aliasing a previous one
+	var post []rune
+	for next := text[end:]; len(next) > 0 && len(post) < contextLength; {
+		u, size := b.decodeUTF16(next)
+		next = next[size:]
+		post = append(post, u)
+	}
+	b.context[1] = post
+}
+
+const (
+	surr1    = 0xd800 // start of high surrogates
+	surr2    = 0xdc00 // start of low surrogates
+	surrLast = 0xe000 // end of low surrogates
+)
+
+// decodeUTF16 decodes the first rune of `text`, which must not be empty,
+// returning the number of code units used.
+func (b *Buffer) decodeUTF16(text []uint16) (rune, int) {
+	c := text[0]
+	switch {
+	case c < surr1 || surrLast <= c:
+		return rune(c), 1
+	case c < surr2 && len(text) >= 2 && surr2 <= text[1] && text[1] < surrLast:
+		return utf16.DecodeRune(rune(c), rune(text[1])), 2
+	default: // unpaired surrogate
+		return b.Replacement, 1
+	}
+}
+
+// decodeLastUTF16 decodes the last rune of `text`, which must not be empty,
+// returning the number of code units used.
+func (b *Buffer) decodeLastUTF16(text []uint16) (rune, int) {
+	L := len(text)
+	c := text[L-1]
+	switch {
+	case c < surr1 || surrLast <= c:
+		return rune(c), 1
+	case surr2 <= c && L >= 2 && surr1 <= text[L-2] && text[L-2] < surr2:
+		return utf16.DecodeRune(rune(text[L-2]), rune(c)), 2
+	default: // unpaired surrogate
+		return b.Replacement, 1
+	}
+}
+
 // GuessSegmentProperties fills unset buffer segment properties based on buffer Unicode
 // contents and can be used when no other information is available.
 //
@@ -219,6 +413,7 @@ func (b *Buffer) Clear() {
 	b.Flags = 0
 	b.Invisible = 0
 	b.NotFound = 0
+	b.Replacement = ReplacementDefault
 
 	b.Props = SegmentProperties{}
 	b.scratchFlags = 0
@@ -440,18 +635,73 @@ done:
 	b.skipGlyph()
 }
 
-// unsafeToBreak adds the flag `GlyphFlagUnsafeToBreak`
+// unsafeToBreak adds the flags `GlyphUnsafeToBreak` and `GlyphUnsafeToConcat`
 // when needed, between `start` and `end`.
 func (b *Buffer) unsafeToBreak(start, end int) {
-	if end-start < 2 {
+	b.setGlyphFlags(GlyphUnsafeToBreak|GlyphUnsafeToConcat, start, end, true, false)
+}
+
+// unsafeToConcat adds the flag `GlyphUnsafeToConcat`
+// when needed, between `start` and `end`.
+// It does nothing if `ProduceUnsafeToConcat` is not set.
+func (b *Buffer) unsafeToConcat(start, end int) {
+	if b.Flags&ProduceUnsafeToConcat == 0 {
 		return
 	}
-	b.unsafeToBreakImpl(start, end)
+	b.setGlyphFlags(GlyphUnsafeToConcat, start, end, true, false)
 }
 
-func (b *Buffer) unsafeToBreakImpl(start, end int) {
-	cluster := findMinCluster(b.Info, start, end, maxInt)
-	b.unsafeToBreakSetMask(b.Info, start, end, cluster)
+func (b *Buffer) unsafeToBreakFromOutbuffer(start, end int) {
+	b.setGlyphFlags(GlyphUnsafeToBreak|GlyphUnsafeToConcat, start, end, true, true)
+}
+
+func (b *Buffer) unsafeToConcatFromOutbuffer(start, end int) {
+	if b.Flags&ProduceUnsafeToConcat == 0 {
+		return
+	}
+	b.setGlyphFlags(GlyphUnsafeToConcat, start, end, false, true)
+}
+
+// setGlyphFlags adds `mask` to the glyphs in [start, end[.
+// If `interior` is true, only the glyphs not belonging to the first cluster are flagged.
+// If `fromOutBuffer` is true, the range starts in `outInfo` (at `start`)
+// and ends in `Info` (at `end`).
+func (b *Buffer) setGlyphFlags(mask GlyphMask, start, end int, interior, fromOutBuffer bool) {
+	end = min(end, len(b.Info))
+
+	if interior && !fromOutBuffer && end-start < 2 {
+		return
+	}
+
+	b.scratchFlags |= bsfHasGlyphFlags
+
+	if !fromOutBuffer || !b.haveOutput {
+		if !interior {
+			for i := start; i < end; i++ {
+				b.Info[i].Mask |= mask
+			}
+		} else {
+			cluster := findMinCluster(b.Info, start, end, maxInt)
+			b.infosSetGlyphFlags(b.Info, start, end, cluster, mask)
+		}
+	} else {
+		//   assert (start <= out_len);
+		//   assert (idx <= end);
+
+		if !interior {
+			for i := start; i < len(b.outInfo); i++ {
+				b.outInfo[i].Mask |= mask
+			}
+			for i := b.idx; i < end; i++ {
+				b.Info[i].Mask |= mask
+			}
+		} else {
+			cluster := findMinCluster(b.Info, b.idx, end, maxInt)
+			cluster = findMinCluster(b.outInfo, start, len(b.outInfo), cluster)
+			b.infosSetGlyphFlags(b.outInfo, start, len(b.outInfo), cluster, mask)
+			b.infosSetGlyphFlags(b.Info, b.idx, end, cluster, mask)
+		}
+	}
 }
 
 // return the smallest cluster between `cluster` and  infos[start:end]
@@ -462,33 +712,15 @@ func findMinCluster(infos []GlyphInfo, start, end, cluster int) int {
 	return cluster
 }
 
-func (b *Buffer) unsafeToBreakSetMask(infos []GlyphInfo,
-	start, end, cluster int,
-) {
+func (b *Buffer) infosSetGlyphFlags(infos []GlyphInfo, start, end, cluster int, mask GlyphMask) {
 	for i := start; i < end; i++ {
 		if cluster != infos[i].Cluster {
-			b.scratchFlags |= bsfHasUnsafeToBreak
-			infos[i].Mask |= GlyphUnsafeToBreak
+			b.scratchFlags |= bsfHasGlyphFlags
+			infos[i].Mask |= mask
 		}
 	}
 }
 
-func (b *Buffer) unsafeToBreakFromOutbuffer(start, end int) {
-	if !b.haveOutput {
-		b.unsafeToBreakImpl(start, end)
-		return
-	}
-
-	//   assert (start <= out_len);
-	//   assert (idx <= end);
-
-	cluster := math.MaxInt32
-	cluster = findMinCluster(b.outInfo, start, len(b.outInfo), cluster)
-	cluster = findMinCluster(b.Info, b.idx, end, cluster)
-	b.unsafeToBreakSetMask(b.outInfo, start, len(b.outInfo), cluster)
-	b.unsafeToBreakSetMask(b.Info, b.idx, end, cluster)
-}
-
 // reset `b.outInfo`, and adjust `pos` to have
 // same length as `Info` (without zeroing its values)
 func (b *Buffer) clearPositions() {
@@ -713,8 +945,11 @@ func (b *Buffer) syllableIterator() (*syllableIterator, int) {
 	return &syllableIterator{buffer: b}, len(b.Info)
 }
 
-// only modifies Info, thus assume Pos is not used yet
-func (b *Buffer) sort(start, end int, compar func(a, b *GlyphInfo) int) {
+// sort performs a stable insertion sort of the glyphs in [start, end[.
+// By default, only Info is modified, thus assuming Pos is not used yet,
+// and the clusters of the moved glyphs are merged.
+// If `withPositions` is true, Pos is moved along with Info and the clusters are kept.
+func (b *Buffer) sort(start, end int, compar func(a, b *GlyphInfo) int, withPositions bool) {
 	for i := start + 1; i < end; i++ {
 		j := i
 		for j > start && compar(&b.Info[j-1], &b.Info[i]) > 0 {
@@ -724,7 +959,13 @@ func (b *Buffer) sort(start, end int, compar func(a, b *GlyphInfo) int) {
 			continue
 		}
 		// move item i to occupy place for item j, shift what's in between.
-		b.mergeClusters(j, i+1)
+		if withPositions {
+			p := b.Pos[i]
+			copy(b.Pos[j+1:], b.Pos[j:i])
+			b.Pos[j] = p
+		} else {
+			b.mergeClusters(j, i+1)
+		}
 
 		t := b.Info[i]
 		copy(b.Info[j+1:], b.Info[j:i])
@@ -769,3 +1010,295 @@ func (b *Buffer) mergeOutClusters(start, end int) {
 		b.outInfo[i].setCluster(cluster, 0)
 	}
 }
+
+// IndexRange is a range [Start, End) of indices.
+type IndexRange struct {
+	Start, End int
+}
+
+// BreakRanges describes how a shaped buffer may be split
+// when breaking its text at a given cluster, so that only
+// small pieces around the break have to be reshaped.
+type BreakRanges struct {
+	// ReuseBefore and ReuseAfter are the ranges of glyphs in `Buffer.Info`
+	// which may be reused as they are, respectively for the text
+	// before and after the break.
+	// Note that for backward directions, ReuseBefore is located
+	// at the end of `Buffer.Info`.
+	ReuseBefore, ReuseAfter IndexRange
+
+	// ReshapeBefore and ReshapeAfter are the ranges of text, expressed
+	// with cluster values, which must be reshaped, respectively
+	// before and after the break. They are empty when breaking is safe.
+	ReshapeBefore, ReshapeAfter IndexRange
+}
+
+// BreakRanges computes the glyphs of the (shaped) buffer which may be reused and
+// the text which must be reshaped when the input text is broken
+// at `breakCluster`, typically by a line breaker.
+// `textEnd` is the cluster value following the last character of the text shaped in `b`.
+//
+// If the break is not flagged as unsafe, the glyphs are simply split.
+// Otherwise, the surrounding text is reshaped up to the closest clusters which
+// may be concatenated. For reliable results, the `ProduceUnsafeToConcat` option
+// should have been set during shaping: if not, the closest clusters which are safe
+// to break are used instead.
+//
+// The reshaped fragments should then be checked for the `GlyphUnsafeToConcat` flag
+// at their joining boundary: if it is set, the reshaped range should be extended and the
+// process repeated.
+//
+// Cluster values are required to be monotone, which means `ClusterLevel` must not be `Characters`.
+func (b *Buffer) BreakRanges(breakCluster, textEnd int) BreakRanges {
+	info := b.Info
+	count := len(info)
+	backward := b.Props.Direction.isBackward()
+
+	// work in logical order: map logical indices to indices in `info`
+	at := func(i int) *GlyphInfo {
+		if backward {
+			return &info[count-1-i]
+		}
+		return &info[i]
+	}
+	toVisual := func(start, end int) IndexRange {
+		if backward {
+			return IndexRange{count - end, count - start}
+		}
+		return IndexRange{start, end}
+	}
+	clusterAt := func(i int) int {
+		if i == count {
+			return textEnd
+		}
+		return at(i).Cluster
+	}
+	// returns true if i is the start of a cluster, safe to break or concat
+	unsafeMask := GlyphUnsafeToBreak
+	if b.Flags&ProduceUnsafeToConcat != 0 {
+		unsafeMask = GlyphUnsafeToConcat
+	}
+	isSafe := func(i int) bool {
+		return i == 0 || i == count ||
+			(at(i-1).Cluster != at(i).Cluster && at(i).Mask&unsafeMask == 0)
+	}
+
+	// logical index of the first glyph starting after the break
+	k := 0
+	for k < count && at(k).Cluster < breakCluster {
+		k++
+	}
+
+	// breaking inside a cluster (for instance a ligature) always requires reshaping
+	inCluster := k > 0 && clusterAt(k) != breakCluster
+	if !inCluster && (k == 0 || k == count || at(k).Mask&GlyphUnsafeToBreak == 0) {
+		return BreakRanges{
+			ReuseBefore:   toVisual(0, k),
+			ReuseAfter:    toVisual(k, count),
+			ReshapeBefore: IndexRange{breakCluster, breakCluster},
+			ReshapeAfter:  IndexRange{breakCluster, breakCluster},
+		}
+	}
+
+	start := k - 1
+	for !isSafe(start) {
+		start--
+	}
+	end := k
+	for !isSafe(end) {
+		end++
+	}
+
+	return BreakRanges{
+		ReuseBefore:   toVisual(0, start),
+		ReuseAfter:    toVisual(end, count),
+		ReshapeBefore: IndexRange{clusterAt(start), breakCluster},
+		ReshapeAfter:  IndexRange{breakCluster, clusterAt(end)},
+	}
+}
+
+// BufferDiff is a set of flags returned when comparing two buffers (see `Buffer.Diff`).
+//
+// For buffers with differing length, the per-glyph comparison is not
+// attempted, though the reference buffer is still scanned for dotted circle and
+// `.notdef` glyphs.
+//
+// If the buffers have the same length, they are compared glyph-by-glyph and
+// the aspect(s) of the glyph info/position which are different are reported.
+type BufferDiff uint16
+
+// the values match the HB_BUFFER_DIFF_FLAG_* constants of harfbuzz;
+// 0x0001 (HB_BUFFER_DIFF_FLAG_CONTENT_TYPE_MISMATCH) is not used since
+// the buffers of this package have no content type
+const (
+	// The buffers are equal.
+	DiffEqual BufferDiff = 0
+
+	// For buffers with differing length, the per-glyph comparison is not
+	// attempted, though the reference is still scanned for dotted circle / .notdef
+	// glyphs.
+	DiffLengthMismatch BufferDiff = 0x0002
+
+	// '.notdef' glyphs are present in the reference buffer.
+	DiffNotdefPresent BufferDiff = 0x0004
+	// Dotted circle glyphs are present in the reference buffer.
+	DiffDottedCirclePresent BufferDiff = 0x0008
+
+	// The input runes or the output glyphs are different
+	DiffCodepointMismatch BufferDiff = 0x0010
+	// The cluster values are different
+	DiffClusterMismatch BufferDiff = 0x0020
+	// The buffer has glyph flags (see `GlyphUnsafeToBreak` and `GlyphUnsafeToConcat`)
+	// not present in the reference
+	DiffGlyphFlagsMismatch BufferDiff = 0x0040
+	// The positions are different (beyond the allowed fuzz)
+	DiffPositionMismatch BufferDiff = 0x0080
+)
+
+// DiffNoSpecialGlyph may be used as dotted circle glyph in `Buffer.Diff`
+// to disable the detection of dotted circle and '.notdef' glyphs.
+const DiffNoSpecialGlyph = ^fonts.GID(0)
+
+// Diff compares the contents of `b` with `reference`, typically after shaping,
+// and returns the differences found.
+// `dottedCircleGlyph` is the glyph id of U+25CC DOTTED CIRCLE, or `DiffNoSpecialGlyph`;
+// in the latter case, `DiffDottedCirclePresent` and `DiffNotdefPresent` are never returned.
+// This should be used by most callers if just comparing two buffers is needed.
+// `positionFuzz` is the allowed absolute difference in position values.
+func (b *Buffer) Diff(reference *Buffer, dottedCircleGlyph fonts.GID, positionFuzz Position) BufferDiff {
+	result := DiffEqual
+	contains := dottedCircleGlyph != DiffNoSpecialGlyph
+
+	count := len(reference.Info)
+
+	if len(b.Info) != count {
+		// we can't compare glyph-by-glyph, but we do want to know if there
+		// are .notdef or dottedcircle glyphs present in the reference buffer
+		for _, info := range reference.Info {
+			if contains && info.Glyph == dottedCircleGlyph {
+				result |= DiffDottedCirclePresent
+			}
+			if contains && info.Glyph == 0 {
+				result |= DiffNotdefPresent
+			}
+		}
+		result |= DiffLengthMismatch
+		return result
+	}
+
+	if count == 0 {
+		return result
+	}
+
+	bufInfo := b.Info
+	refInfo := reference.Info
+	for i := 0; i < count; i++ {
+		if bufInfo[i].codepoint != refInfo[i].codepoint || bufInfo[i].Glyph != refInfo[i].Glyph {
+			result |= DiffCodepointMismatch
+		}
+		if bufInfo[i].Cluster != refInfo[i].Cluster {
+			result |= DiffClusterMismatch
+		}
+		if (bufInfo[i].Mask & ^refInfo[i].Mask & glyphFlagDefined) != 0 {
+			result |= DiffGlyphFlagsMismatch
+		}
+		if contains && refInfo[i].Glyph == dottedCircleGlyph {
+			result |= DiffDottedCirclePresent
+		}
+		if contains && refInfo[i].Glyph == 0 {
+			result |= DiffNotdefPresent
+		}
+	}
+
+	isDifferent := func(a, b Position) bool {
+		d := a - b
+		if d < 0 {
+			d = -d
+		}
+		return d > positionFuzz
+	}
+
+	bufPos := b.Pos
+	refPos := reference.Pos
+	for i := 0; i < count; i++ {
+		if isDifferent(bufPos[i].XAdvance, refPos[i].XAdvance) ||
+			isDifferent(bufPos[i].YAdvance, refPos[i].YAdvance) ||
+			isDifferent(bufPos[i].XOffset, refPos[i].XOffset) ||
+			isDifferent(bufPos[i].YOffset, refPos[i].YOffset) {
+			result |= DiffPositionMismatch
+			break
+		}
+	}
+
+	return result
+}
+
+// NormalizeGlyphs reorders the glyphs of a shaped buffer, so that
+// two buffers with the same glyphs in the same clusters may be compared, regardless
+// of the order of the glyphs inside a cluster.
+//
+// Inside each cluster, the advance of the whole cluster is transferred
+// to the first glyph (the last one for backward directions), the other glyphs
+// getting zero advance and an offset relative to the origin of the cluster.
+// Then, the other glyphs are sorted by glyph index.
+//
+// The `Pos` slice is expected to be filled, that is `b` should have been shaped.
+func (b *Buffer) NormalizeGlyphs() {
+	backward := b.Props.Direction.isBackward()
+	iter, count := b.clusterIterator()
+	for start, end := iter.next(); start < count; start, end = iter.next() {
+		b.normalizeGlyphsCluster(start, end, backward)
+	}
+}
+
+func (b *Buffer) normalizeGlyphsCluster(start, end int, backward bool) {
+	pos := b.Pos
+
+	// total cluster advance
+	var totalXAdvance, totalYAdvance Position
+	for i := start; i < end; i++ {
+		totalXAdvance += pos[i].XAdvance
+		totalYAdvance += pos[i].YAdvance
+	}
+
+	var xAdvance, yAdvance Position
+	for i := start; i < end; i++ {
+		pos[i].XOffset += xAdvance
+		pos[i].YOffset += yAdvance
+
+		xAdvance += pos[i].XAdvance
+		yAdvance += pos[i].YAdvance
+
+		pos[i].XAdvance = 0
+		pos[i].YAdvance = 0
+	}
+
+	if backward {
+		// transfer all cluster advance to the last glyph
+		pos[end-1].XAdvance = totalXAdvance
+		pos[end-1].YAdvance = totalYAdvance
+
+		b.sort(start, end-1, compareGlyph, true)
+	} else {
+		// transfer all cluster advance to the first glyph
+		pos[start].XAdvance += totalXAdvance
+		pos[start].YAdvance += totalYAdvance
+		for i := start + 1; i < end; i++ {
+			pos[i].XOffset -= totalXAdvance
+			pos[i].YOffset -= totalYAdvance
+		}
+
+		b.sort(start+1, end, compareGlyph, true)
+	}
+}
+
+// compareGlyph sorts by glyph index
+func compareGlyph(pa, pb *GlyphInfo) int {
+	a, b := pa.Glyph, pb.Glyph
+	if a < b {
+		return -1
+	} else if a == b {
+		return 0
+	}
+	return 1
+}
@@ -207,136 +207,225 @@ func TestBuffer(t *testing.T) {
 	}
 }
 
-/*
- * Comparing buffers.
- */
-
-/**
- *
- * Flags from comparing two #hb_buffer_t's.
- *
- * Buffer with different #hb_buffer_content_type_t cannot be meaningfully
- * compared in any further detail.
- *
- * For buffers with differing length, the per-glyph comparison is not
- * attempted, though we do still scan reference buffer for dotted circle and
- * `.notdef` glyphs.
- *
- * If the buffers have the same length, we compare them glyph-by-glyph and
- * report which aspect(s) of the glyph info/position are different.
- *
- * Since: 1.5.0
- */
-const (
-	bufferDiffFlagEqual = 0x0000
-
-	// /* Buffers with different content_type cannot be meaningfully compared
-	//  * in any further detail. */
-	// HB_BUFFER_DIFF_FLAG_CONTENT_TYPE_MISMATCH	= 0x0001
-
-	/* For buffers with differing length, the per-glyph comparison is not
-	 * attempted, though we do still scan reference for dottedcircle / .notdef
-	 * glyphs. */
-	HB_BUFFER_DIFF_FLAG_LENGTH_MISMATCH = 0x0002
-
-	/* We want to know if dottedcircle / .notdef glyphs are present in the
-	 * reference, as we may not care so much about other differences in this
-	 * case. */
-	HB_BUFFER_DIFF_FLAG_NOTDEF_PRESENT        = 0x0004
-	HB_BUFFER_DIFF_FLAG_DOTTED_CIRCLE_PRESENT = 0x0008
-
-	/* If the buffers have the same length, we compare them glyph-by-glyph
-	 * and report which aspect(s) of the glyph info/position are different. */
-	HB_BUFFER_DIFF_FLAG_CODEPOINT_MISMATCH   = 0x0010
-	HB_BUFFER_DIFF_FLAG_CLUSTER_MISMATCH     = 0x0020
-	HB_BUFFER_DIFF_FLAG_GLYPH_FLAGS_MISMATCH = 0x0040
-	HB_BUFFER_DIFF_FLAG_POSITION_MISMATCH    = 0x0080
-)
+func TestBufferUTF8(t *testing.T) {
+	text := []byte("tést\U0001F600\xffend")
 
-/**
- * hb_buffer_diff:
- * @buffer: a buffer.
- * @reference: other buffer to compare to.
- * @dottedcircleGlyph: glyph id of U+25CC DOTTED CIRCLE, or (hb_codepont_t) -1.
- * @positionFuzz: allowed absolute difference in position values.
- *
- * If dottedcircleGlyph is (hb_codepoint_t) -1 then #HB_BUFFER_DIFF_FLAG_DOTTED_CIRCLE_PRESENT
- * and #HB_BUFFER_DIFF_FLAG_NOTDEF_PRESENT are never returned.  This should be used by most
- * callers if just comparing two buffers is needed.
- *
- * Since: 1.5.0
- **/
-
-func bufferDiff(buffer, reference *Buffer, dottedcircleGlyph fonts.GID, positionFuzz int32) int {
-	//    if (buffer.content_type != reference.content_type && buffer.len && reference.len){
-	// 	 return HB_BUFFER_DIFF_FLAG_CONTENT_TYPE_MISMATCH;}
-
-	result := bufferDiffFlagEqual
-	contains := dottedcircleGlyph != ^fonts.GID(0)
-
-	count := len(reference.Info)
-
-	if len(buffer.Info) != count {
-		/*
-		 * we can't compare glyph-by-glyph, but we do want to know if there
-		 * are .notdef or dottedcircle glyphs present in the reference buffer
-		 */
-		info := reference.Info
-		for i := 0; i < count; i++ {
-			if contains && info[i].Glyph == dottedcircleGlyph {
-				result |= HB_BUFFER_DIFF_FLAG_DOTTED_CIRCLE_PRESENT
-			}
-			if contains && info[i].Glyph == 0 {
-				result |= HB_BUFFER_DIFF_FLAG_NOTDEF_PRESENT
-			}
-		}
-		result |= HB_BUFFER_DIFF_FLAG_LENGTH_MISMATCH
-		return result
+	b := NewBuffer()
+	b.AddUTF8(text, 1, 8)
+	expRunes := []rune{'é', 's', 't', 0x1F600}
+	expClusters := []int{1, 3, 4, 5}
+	assertEqualInt(t, len(b.Info), len(expRunes))
+	for i, info := range b.Info {
+		assertEqualInt(t, int(info.codepoint), int(expRunes[i]))
+		assertEqualInt(t, info.Cluster, expClusters[i])
 	}
+	assertEqualInt(t, len(b.context[0]), 1)
+	assertEqualInt(t, int(b.context[0][0]), 't')
+	assertEqualInt(t, len(b.context[1]), 4)
+	assertEqualInt(t, int(b.context[1][0]), int(ReplacementDefault))
+
+	b = NewBuffer()
+	b.Replacement = '?'
+	b.AddUTF8(text, 9, -1)
+	assertEqualInt(t, len(b.Info), 4)
+	assertEqualInt(t, int(b.Info[0].codepoint), '?')
+	assertEqualInt(t, b.Info[1].Cluster, 10)
+	assertEqualInt(t, len(b.context[0]), 5)
+	assertEqualInt(t, int(b.context[0][0]), 0x1F600)
+	assertEqualInt(t, len(b.context[1]), 0)
+}
+
+func TestBufferUTF8Truncated(t *testing.T) {
+	// maximal subparts, as in the table 3-8 of the Unicode Standard
+	text := []byte("a\xe2\x82b\xf0\x9f\x98\xc0\xaf\xe0\x80c\xed\xa0\x80\xf4\x90d\xe2\x82")
 
-	if count == 0 {
-		return result
+	b := NewBuffer()
+	b.AddUTF8(text, 0, -1)
+	R := ReplacementDefault
+	expRunes := []rune{'a', R, 'b', R, R, R, R, R, 'c', R, R, R, R, R, 'd', R}
+	expClusters := []int{0, 1, 3, 4, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18}
+	assertEqualInt(t, len(b.Info), len(expRunes))
+	for i, info := range b.Info {
+		assertEqualInt(t, int(info.codepoint), int(expRunes[i]))
+		assertEqualInt(t, info.Cluster, expClusters[i])
 	}
 
-	bufInfo := buffer.Info
-	refInfo := reference.Info
-	for i := 0; i < count; i++ {
-		if bufInfo[i].codepoint != refInfo[i].codepoint {
-			result |= HB_BUFFER_DIFF_FLAG_CODEPOINT_MISMATCH
-		}
-		if bufInfo[i].Cluster != refInfo[i].Cluster {
-			result |= HB_BUFFER_DIFF_FLAG_CLUSTER_MISMATCH
-		}
-		if (bufInfo[i].Mask & ^refInfo[i].Mask & glyphFlagDefined) != 0 {
-			result |= HB_BUFFER_DIFF_FLAG_GLYPH_FLAGS_MISMATCH
+	// truncated sequences in the contexts
+	b = NewBuffer()
+	b.AddUTF8(text, 3, 1)
+	assertEqualInt(t, len(b.context[0]), 2)
+	assertEqualInt(t, int(b.context[0][0]), int(R))
+	assertEqualInt(t, int(b.context[0][1]), 'a')
+	assertEqualInt(t, len(b.context[1]), 5)
+	assertEqualInt(t, int(b.context[1][0]), int(R))
+	assertEqualInt(t, int(b.context[1][1]), int(R))
+	assertEqualInt(t, int(b.context[1][4]), int(R))
+
+	b = NewBuffer()
+	b.AddUTF8(text, len(text), 0)
+	expContext := []rune{R, 'd', R, R, R}
+	assertEqualInt(t, len(b.context[0]), len(expContext))
+	for i, r := range b.context[0] {
+		assertEqualInt(t, int(r), int(expContext[i]))
+	}
+}
+
+func TestBufferUTF16(t *testing.T) {
+	text := []uint16{'a', 0xD83D, 0xDE00, 'b', 0xDC00, 'c', 0xD800}
+
+	b := NewBuffer()
+	b.AddUTF16(text, 1, 5)
+	expRunes := []rune{0x1F600, 'b', ReplacementDefault, 'c'}
+	expClusters := []int{1, 3, 4, 5}
+	assertEqualInt(t, len(b.Info), len(expRunes))
+	for i, info := range b.Info {
+		assertEqualInt(t, int(info.codepoint), int(expRunes[i]))
+		assertEqualInt(t, info.Cluster, expClusters[i])
+	}
+	assertEqualInt(t, len(b.context[0]), 1)
+	assertEqualInt(t, int(b.context[0][0]), 'a')
+	assertEqualInt(t, len(b.context[1]), 1)
+	assertEqualInt(t, int(b.context[1][0]), int(ReplacementDefault))
+
+	b = NewBuffer()
+	b.AddUTF16(text, 3, 1)
+	assertEqualInt(t, len(b.context[0]), 2)
+	assertEqualInt(t, int(b.context[0][0]), 0x1F600)
+	assertEqualInt(t, int(b.context[0][1]), 'a')
+}
+
+func TestBufferBreakRanges(t *testing.T) {
+	font := NewFont(openFontFile("fonts/NotoNastaliqUrdu-Regular.ttf"))
+	text := []rune("ہم نے اس کو ٹھیک کیا")
+
+	for _, produceConcat := range []bool{false, true} {
+		b := NewBuffer()
+		b.AddRunes(text, 0, -1)
+		b.Props.Direction = RightToLeft
+		b.Props.Script = language.Arabic
+		if produceConcat {
+			b.Flags = ProduceUnsafeToConcat
 		}
-		if contains && refInfo[i].Glyph == dottedcircleGlyph {
-			result |= HB_BUFFER_DIFF_FLAG_DOTTED_CIRCLE_PRESENT
+		b.Shape(font, nil)
+
+		for _, info := range b.Info {
+			// unsafe to break implies unsafe to concat
+			assert(t, info.Mask&GlyphUnsafeToBreak == 0 || info.Mask&GlyphUnsafeToConcat != 0)
 		}
-		if contains && refInfo[i].Glyph == 0 {
-			result |= HB_BUFFER_DIFF_FLAG_NOTDEF_PRESENT
+
+		for breakCluster := 1; breakCluster < len(text); breakCluster++ {
+			r := b.BreakRanges(breakCluster, len(text))
+
+			assert(t, r.ReshapeBefore.End == breakCluster && r.ReshapeAfter.Start == breakCluster)
+			assert(t, r.ReshapeBefore.Start <= breakCluster && breakCluster <= r.ReshapeAfter.End)
+			// RTL: glyphs after the break come first
+			assert(t, r.ReuseAfter.Start == 0 && r.ReuseBefore.End == len(b.Info))
+			assert(t, r.ReuseAfter.End <= r.ReuseBefore.Start)
+			for _, info := range b.Info[r.ReuseAfter.Start:r.ReuseAfter.End] {
+				assert(t, info.Cluster >= r.ReshapeAfter.End)
+			}
+			for _, info := range b.Info[r.ReuseBefore.Start:r.ReuseBefore.End] {
+				assert(t, info.Cluster < r.ReshapeBefore.Start)
+			}
 		}
 	}
+}
 
-	isDifferent := func(a, b int32) bool {
-		d := a - b
-		if d < 0 {
-			d = -d
-		}
-		return d > positionFuzz
+func TestBufferBreakRangesLigature(t *testing.T) {
+	font := NewFont(openFontFileTT("DejaVuSerif.ttf"))
+	text := []rune("waffle")
+	b := NewBuffer()
+	b.AddRunes(text, 0, -1)
+	b.GuessSegmentProperties()
+	b.Shape(font, nil)
+	assertEqualInt(t, 4, len(b.Info)) // w a ffl e
+
+	// inside the ffl ligature
+	for _, breakCluster := range []int{3, 4} {
+		r := b.BreakRanges(breakCluster, len(text))
+		assert(t, r.ReshapeBefore == IndexRange{2, breakCluster})
+		assert(t, r.ReshapeAfter == IndexRange{breakCluster, 5})
+		assert(t, r.ReuseBefore == IndexRange{0, 2} && r.ReuseAfter == IndexRange{3, 4})
 	}
+}
+
+func TestBufferDiffValues(t *testing.T) {
+	// HB_BUFFER_DIFF_FLAG_*
+	for i, v := range []BufferDiff{
+		DiffEqual, DiffLengthMismatch, DiffNotdefPresent, DiffDottedCirclePresent,
+		DiffCodepointMismatch, DiffClusterMismatch, DiffGlyphFlagsMismatch, DiffPositionMismatch,
+	} {
+		assertEqualInt(t, []int{0x0000, 0x0002, 0x0004, 0x0008, 0x0010, 0x0020, 0x0040, 0x0080}[i], int(v))
+	}
+}
 
-	bufPos := buffer.Pos
-	refPos := reference.Pos
-	for i := 0; i < count; i++ {
-		if isDifferent(bufPos[i].XAdvance, refPos[i].XAdvance) ||
-			isDifferent(bufPos[i].YAdvance, refPos[i].YAdvance) ||
-			isDifferent(bufPos[i].XOffset, refPos[i].XOffset) ||
-			isDifferent(bufPos[i].YOffset, refPos[i].YOffset) {
-			result |= HB_BUFFER_DIFF_FLAG_POSITION_MISMATCH
-			break
+func TestBufferDiff(t *testing.T) {
+	newBuffer := func() *Buffer {
+		b := NewBuffer()
+		b.AddRunes([]rune("abc"), 0, -1)
+		for i := range b.Info {
+			b.Info[i].Glyph = fonts.GID(i + 1)
+			b.Pos[i].XAdvance = 100
 		}
+		return b
 	}
 
-	return result
+	b, ref := newBuffer(), newBuffer()
+	assert(t, b.Diff(ref, DiffNoSpecialGlyph, 0) == DiffEqual)
+
+	ref.Info[1].Glyph = 0
+	ref.Info[2].Glyph = 9
+	assert(t, b.Diff(ref, DiffNoSpecialGlyph, 0) == DiffCodepointMismatch)
+	assert(t, b.Diff(ref, 9, 0) == DiffCodepointMismatch|DiffNotdefPresent|DiffDottedCirclePresent)
+
+	ref = newBuffer()
+	ref.Pos[0].XOffset = 2
+	b.Info[2].Cluster = 1
+	b.Info[2].Mask = GlyphUnsafeToBreak
+	assert(t, b.Diff(ref, DiffNoSpecialGlyph, 0) == DiffClusterMismatch|DiffGlyphFlagsMismatch|DiffPositionMismatch)
+	assert(t, b.Diff(ref, DiffNoSpecialGlyph, 2) == DiffClusterMismatch|DiffGlyphFlagsMismatch)
+	// flags only present in the reference are ignored
+	assert(t, ref.Diff(b, DiffNoSpecialGlyph, 2) == DiffClusterMismatch)
+
+	ref.AddRune('d', 3)
+	assert(t, b.Diff(ref, DiffNoSpecialGlyph, 0) == DiffLengthMismatch)
+}
+
+func TestBufferNormalizeGlyphs(t *testing.T) {
+	for _, backward := range []bool{false, true} {
+		b := NewBuffer()
+		b.AddRunes([]rune("abcd"), 0, -1)
+		b.Props.Direction = LeftToRight
+		if backward {
+			b.Props.Direction = RightToLeft
+		}
+		// one cluster of three glyphs, followed by a simple glyph
+		for i, g := range []fonts.GID{7, 5, 3, 1} {
+			b.Info[i].Glyph = g
+			b.Pos[i].XAdvance = Position(10 * (i + 1))
+			b.Info[i].Cluster = i / 3
+		}
+
+		b.NormalizeGlyphs()
+
+		if backward {
+			expGlyphs := []fonts.GID{5, 7, 3, 1}
+			expOffsets := []Position{10, 0, 30, 0}
+			expAdvances := []Position{0, 0, 60, 40}
+			for i, info := range b.Info {
+				assertEqualInt(t, int(expGlyphs[i]), int(info.Glyph))
+				assertEqualInt32(t, b.Pos[i].XOffset, expOffsets[i])
+				assertEqualInt32(t, b.Pos[i].XAdvance, expAdvances[i])
+			}
+		} else {
+			expGlyphs := []fonts.GID{7, 3, 5, 1}
+			expOffsets := []Position{0, -30, -50, 0}
+			expAdvances := []Position{60, 0, 0, 40}
+			for i, info := range b.Info {
+				assertEqualInt(t, int(expGlyphs[i]), int(info.Glyph))
+				assertEqualInt32(t, b.Pos[i].XOffset, expOffsets[i])
+				assertEqualInt32(t, b.Pos[i].XAdvance, expAdvances[i])
+			}
+		}
+	}
 }
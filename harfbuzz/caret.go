@@ -0,0 +1,338 @@
+package harfbuzz
+
+import (
+	"sort"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/segmenter"
+)
+
+// CaretSpan is an interval along the advance axis of a line
+// (horizontal for horizontal text, vertical otherwise),
+// such as the extent of a selection rectangle.
+// `Start` is always less or equal to `End`.
+type CaretSpan struct {
+	Start, End Position
+}
+
+// CaretMap maps the text offsets of a shaped run to caret positions, and back.
+//
+// Positions are measured along the advance axis, from the visual start of the run:
+// the left edge for horizontal text, the top edge for vertical text (so that
+// they are positive in both cases), shifted by `Origin`.
+//
+// Carets are placed on grapheme cluster boundaries: when a glyph cluster
+// contains several graphemes (typically a ligature), it is split using
+// the ligature carets of the font (see `Font.GetOTLigatureCarets`), or in equal
+// parts if the font does not provide them.
+type CaretMap struct {
+	// Start and End delimit the text of the run.
+	Start, End int
+
+	// Origin is added to the positions, and is used to
+	// combine the runs of a line (see `LineCarets`).
+	Origin Position
+
+	backward bool
+	width    Position
+
+	// caret position for each offset in [Start, End],
+	// not including Origin
+	carets []Position
+	// isBoundary is true for grapheme boundaries, with the same indexing as carets
+	isBoundary []bool
+}
+
+// CaretMap computes the caret positions for the buffer `b`, shaped with `font`,
+// whose text is text[start:end], as added with `AddRunes(text, start, end-start)`.
+// All the cluster levels are supported. `font` may be nil, in which case ligatures
+// are split in equal parts.
+func (b *Buffer) CaretMap(font *Font, text []rune, start, end int) *CaretMap {
+	var ligCarets func(fonts.GID) []Position
+	if font != nil {
+		ligCarets = func(glyph fonts.GID) []Position {
+			return font.GetOTLigatureCarets(b.Props.Direction, glyph)
+		}
+	}
+	return newCaretMap(b, text, start, end, ligCarets)
+}
+
+// advance returns the advance of the glyph, along the (positive) direction of the line
+func advanceAlong(dir Direction, pos GlyphPosition) Position {
+	if dir.isVertical() {
+		return -pos.YAdvance
+	}
+	return pos.XAdvance
+}
+
+// clusterSpan is the text [start, end) covered by the glyphs with Cluster == start
+type clusterSpan struct {
+	start, end int
+	lo, hi     Position // visual extent
+	glyphs     []int    // indices in Buffer.Info
+}
+
+func newCaretMap(b *Buffer, text []rune, start, end int, ligCarets func(fonts.GID) []Position) *CaretMap {
+	dir := b.Props.Direction
+	out := &CaretMap{
+		Start:      start,
+		End:        end,
+		backward:   dir.isBackward(),
+		carets:     make([]Position, end-start+1),
+		isBoundary: make([]bool, end-start+1),
+	}
+
+	// grapheme boundaries
+	it := segmenter.NewGraphemeIterator(text[start:end])
+	for it.Next() {
+		out.isBoundary[it.Segment().Offset] = true
+	}
+	out.isBoundary[end-start] = true
+
+	// visual extent of each cluster; with the Characters level,
+	// the glyphs of a cluster may not be consecutive
+	byCluster := map[int]*clusterSpan{}
+	var u Position
+	for i, glyph := range b.Info {
+		adv := advanceAlong(dir, b.Pos[i])
+		span := byCluster[glyph.Cluster]
+		if span == nil {
+			span = &clusterSpan{start: glyph.Cluster, lo: u, hi: u + adv}
+			byCluster[glyph.Cluster] = span
+		}
+		if u < span.lo {
+			span.lo = u
+		}
+		if u+adv > span.hi {
+			span.hi = u + adv
+		}
+		span.glyphs = append(span.glyphs, i)
+		u += adv
+	}
+	out.width = u
+
+	spans := make([]*clusterSpan, 0, len(byCluster))
+	for _, span := range byCluster {
+		if start <= span.start && span.start < end {
+			spans = append(spans, span)
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	for i, span := range spans {
+		span.end = end
+		if i+1 < len(spans) {
+			span.end = spans[i+1].start
+		}
+	}
+
+	// the text before the first cluster (if any) is put at the start of the run
+	runStart := Position(0)
+	if out.backward {
+		runStart = out.width
+	}
+	for i := range out.carets {
+		out.carets[i] = runStart
+	}
+
+	for _, span := range spans {
+		leading, trailing := span.lo, span.hi
+		if out.backward {
+			leading, trailing = span.hi, span.lo
+		}
+
+		// grapheme boundaries inside the span
+		var inner []int
+		for offset := span.start + 1; offset < span.end; offset++ {
+			if out.isBoundary[offset-start] {
+				inner = append(inner, offset)
+			}
+		}
+		if out.isBoundary[span.start-start] {
+			out.carets[span.start-start] = leading
+		} else {
+			// with the Characters levels, a combining mark may have its own cluster:
+			// use the caret of the start of the grapheme (spans are sorted)
+			out.carets[span.start-start] = out.carets[span.start-start-1]
+		}
+		if len(inner) != 0 {
+			var carets []Position
+			if ligCarets != nil && len(span.glyphs) == 1 {
+				carets = ligCarets(b.Info[span.glyphs[0]].Glyph)
+			}
+			if len(carets) == len(inner) { // use the carets of the font, relative to the glyph origin
+				sorted := append([]Position(nil), carets...)
+				sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+				for j, offset := range inner {
+					if out.backward {
+						out.carets[offset-start] = span.lo + sorted[len(sorted)-1-j]
+					} else {
+						out.carets[offset-start] = span.lo + sorted[j]
+					}
+				}
+			} else { // split the cluster in equal parts
+				n := Position(len(inner) + 1)
+				for j, offset := range inner {
+					out.carets[offset-start] = leading + (trailing-leading)*Position(j+1)/n
+				}
+			}
+		}
+		// offsets inside a grapheme are mapped to the start of the grapheme
+		for offset := span.start + 1; offset < span.end; offset++ {
+			if !out.isBoundary[offset-start] {
+				out.carets[offset-start] = out.carets[offset-start-1]
+			}
+		}
+	}
+
+	// the end of the text is after the logically last cluster
+	if L := len(spans); L != 0 {
+		if out.backward {
+			out.carets[end-start] = spans[L-1].lo
+		} else {
+			out.carets[end-start] = spans[L-1].hi
+		}
+	}
+
+	return out
+}
+
+// Width returns the total advance of the run.
+func (cm *CaretMap) Width() Position { return cm.width }
+
+// Position returns the caret position for the text offset `offset`,
+// which is clamped to [Start, End]. Offsets inside a grapheme
+// are mapped to the start of the grapheme.
+func (cm *CaretMap) Position(offset int) Position {
+	if offset < cm.Start {
+		offset = cm.Start
+	} else if offset > cm.End {
+		offset = cm.End
+	}
+	return cm.Origin + cm.carets[offset-cm.Start]
+}
+
+// HitTest returns the grapheme boundary whose caret
+// is the closest to the position `x`.
+func (cm *CaretMap) HitTest(x Position) int {
+	best, bestDist := cm.Start, Position(-1)
+	for i, isBoundary := range cm.isBoundary {
+		if !isBoundary {
+			continue
+		}
+		dist := cm.Origin + cm.carets[i] - x
+		if dist < 0 {
+			dist = -dist
+		}
+		if bestDist == -1 || dist < bestDist {
+			best, bestDist = cm.Start+i, dist
+		}
+	}
+	return best
+}
+
+// Selection returns the visual extents of the text [start, end),
+// sorted and merged. The result may contain several spans
+// if the glyphs are reordered.
+func (cm *CaretMap) Selection(start, end int) []CaretSpan {
+	return mergeSpans(cm.appendSelection(nil, start, end))
+}
+
+func (cm *CaretMap) appendSelection(out []CaretSpan, start, end int) []CaretSpan {
+	if start < cm.Start {
+		start = cm.Start
+	}
+	if end > cm.End {
+		end = cm.End
+	}
+	// walk through the graphemes in [start, end)
+	for offset := start; offset < end; {
+		next := offset + 1
+		for next < cm.End && !cm.isBoundary[next-cm.Start] {
+			next++
+		}
+		a, b := cm.Position(offset), cm.Position(next)
+		if a > b {
+			a, b = b, a
+		}
+		out = append(out, CaretSpan{a, b})
+		offset = next
+	}
+	return out
+}
+
+// mergeSpans sorts and merges the overlapping or adjacent spans
+func mergeSpans(spans []CaretSpan) []CaretSpan {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].Start < spans[j].Start })
+	var out []CaretSpan
+	for _, span := range spans {
+		if span.Start == span.End {
+			continue
+		}
+		if L := len(out); L != 0 && span.Start <= out[L-1].End {
+			if span.End > out[L-1].End {
+				out[L-1].End = span.End
+			}
+			continue
+		}
+		out = append(out, span)
+	}
+	return out
+}
+
+// LineCarets combines the caret maps of the runs of a line, which may
+// have different directions.
+type LineCarets []*CaretMap
+
+// NewLineCarets returns the carets for a line made of `runs`,
+// given in visual order (see `bidi.Paragraph.Runs`).
+// The `Origin` of each run is updated.
+func NewLineCarets(runs []*CaretMap) LineCarets {
+	var origin Position
+	for _, run := range runs {
+		run.Origin = origin
+		origin += run.width
+	}
+	return runs
+}
+
+// Position returns the caret position of the text offset `offset`.
+// At the boundary between two runs, the run starting at `offset` is used, so
+// that the caret is displayed next to the character following it; the end of the
+// line is handled by the run ending it.
+func (lc LineCarets) Position(offset int) Position {
+	for _, run := range lc {
+		if run.Start <= offset && offset < run.End {
+			return run.Position(offset)
+		}
+	}
+	for _, run := range lc {
+		if run.End == offset {
+			return run.Position(offset)
+		}
+	}
+	return 0
+}
+
+// HitTest returns the grapheme boundary closest to the position `x`,
+// or -1 for an empty line.
+func (lc LineCarets) HitTest(x Position) int {
+	if len(lc) == 0 {
+		return -1
+	}
+	for _, run := range lc {
+		if x < run.Origin+run.width {
+			return run.HitTest(x)
+		}
+	}
+	return lc[len(lc)-1].HitTest(x)
+}
+
+// Selection returns the visual extents of the text [start, end) in the line,
+// sorted and merged. Mixed-direction text usually produces several spans.
+func (lc LineCarets) Selection(start, end int) []CaretSpan {
+	var out []CaretSpan
+	for _, run := range lc {
+		out = run.appendSelection(out, start, end)
+	}
+	return mergeSpans(out)
+}
@@ -0,0 +1,195 @@
+package harfbuzz
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/language"
+)
+
+func shapeForCarets(font *Font, text []rune, props SegmentProperties, level ClusterLevel) *Buffer {
+	buf := NewBuffer()
+	buf.Props = props
+	buf.ClusterLevel = level
+	buf.AddRunes(text, 0, len(text))
+	buf.Shape(font, nil)
+	return buf
+}
+
+func TestCaretMapLTR(t *testing.T) {
+	font := NewFont(openFontFileTT("DejaVuSerif.ttf"))
+	text := []rune("abc")
+	buf := shapeForCarets(font, text, SegmentProperties{Direction: LeftToRight, Script: language.Latin}, MonotoneGraphemes)
+	cm := buf.CaretMap(font, text, 0, len(text))
+
+	var x Position
+	for i := range text {
+		if got := cm.Position(i); got != x {
+			t.Fatalf("offset %d: expected %d, got %d", i, x, got)
+		}
+		x += buf.Pos[i].XAdvance
+	}
+	if got := cm.Position(len(text)); got != x || cm.Width() != x {
+		t.Fatalf("end: expected %d, got %d", x, got)
+	}
+
+	for i := range text {
+		// slightly after the caret
+		if got := cm.HitTest(cm.Position(i) + 10); got != i {
+			t.Fatalf("hit test for %d: got %d", i, got)
+		}
+	}
+}
+
+func TestCaretMapRTL(t *testing.T) {
+	font := NewFont(openFontFileTT("NotoSansArabic.ttf"))
+	text := []rune("مرحبا بالعالم")
+	buf := shapeForCarets(font, text, SegmentProperties{Direction: RightToLeft, Script: language.Arabic}, MonotoneGraphemes)
+	cm := buf.CaretMap(font, text, 0, len(text))
+
+	if cm.Position(0) != cm.Width() || cm.Position(len(text)) != 0 {
+		t.Fatalf("unexpected run edges %d %d", cm.Position(0), cm.Position(len(text)))
+	}
+	for i := 1; i <= len(text); i++ {
+		if cm.Position(i) > cm.Position(i-1) {
+			t.Fatalf("carets should be decreasing: %d > %d", cm.Position(i), cm.Position(i-1))
+		}
+	}
+	if got := cm.HitTest(cm.Width()); got != 0 {
+		t.Fatalf("expected 0, got %d", got)
+	}
+}
+
+func TestCaretMapVertical(t *testing.T) {
+	font := NewFont(openFontFileTT("DejaVuSerif.ttf"))
+	text := []rune("abc")
+	buf := shapeForCarets(font, text, SegmentProperties{Direction: TopToBottom, Script: language.Latin}, MonotoneGraphemes)
+	cm := buf.CaretMap(font, text, 0, len(text))
+
+	if cm.Width() <= 0 {
+		t.Fatalf("expected positive width, got %d", cm.Width())
+	}
+	if cm.Position(0) != 0 || cm.Position(len(text)) != cm.Width() {
+		t.Fatalf("unexpected run edges %d %d", cm.Position(0), cm.Position(len(text)))
+	}
+	for i := 1; i <= len(text); i++ {
+		if cm.Position(i) <= cm.Position(i-1) {
+			t.Fatalf("carets should be increasing: %d <= %d", cm.Position(i), cm.Position(i-1))
+		}
+	}
+}
+
+func TestCaretMapClusterLevels(t *testing.T) {
+	font := NewFont(openFontFileTT("DejaVuSerif.ttf"))
+	// e\u0301 is composed into one glyph, x\u0301 is not
+	for _, text := range [][]rune{[]rune("xe\u0301y"), []rune("xx\u0301y")} {
+		for _, level := range []ClusterLevel{MonotoneGraphemes, MonotoneCharacters, Characters} {
+			buf := shapeForCarets(font, text, SegmentProperties{Direction: LeftToRight, Script: language.Latin}, level)
+			cm := buf.CaretMap(font, text, 0, len(text))
+
+			// the combining mark is inside the grapheme
+			if cm.Position(2) != cm.Position(1) {
+				t.Fatalf("level %d: expected same caret for offsets 1 and 2", level)
+			}
+			if cm.Position(3) <= cm.Position(1) {
+				t.Fatalf("level %d: unexpected caret for offset 3", level)
+			}
+			for x := Position(0); x <= cm.Width(); x += 50 {
+				if got := cm.HitTest(x); got == 2 {
+					t.Fatalf("level %d: hit test should not return a grapheme continuation", level)
+				}
+			}
+			if sel := cm.Selection(1, 3); len(sel) != 1 || sel[0] != (CaretSpan{cm.Position(1), cm.Position(3)}) {
+				t.Fatalf("level %d: unexpected selection %v", level, sel)
+			}
+		}
+	}
+}
+
+// ligatureBuffer returns a buffer with one glyph for three characters
+func ligatureBuffer(dir Direction) *Buffer {
+	buf := NewBuffer()
+	buf.Props.Direction = dir
+	buf.Info = []GlyphInfo{{Cluster: 0, Glyph: 5}}
+	buf.Pos = []GlyphPosition{{XAdvance: 300}}
+	return buf
+}
+
+func TestCaretMapLigature(t *testing.T) {
+	text := []rune("ffi")
+	carets := func(cm *CaretMap) []Position {
+		var out []Position
+		for i := 0; i <= len(text); i++ {
+			out = append(out, cm.Position(i))
+		}
+		return out
+	}
+
+	// no ligature carets: equal parts
+	cm := newCaretMap(ligatureBuffer(LeftToRight), text, 0, 3, nil)
+	if got, exp := carets(cm), []Position{0, 100, 200, 300}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	cm = newCaretMap(ligatureBuffer(RightToLeft), text, 0, 3, nil)
+	if got, exp := carets(cm), []Position{300, 200, 100, 0}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+
+	// carets from the font
+	fromFont := func(glyph fonts.GID) []Position {
+		if glyph == 5 {
+			return []Position{120, 250}
+		}
+		return nil
+	}
+	cm = newCaretMap(ligatureBuffer(LeftToRight), text, 0, 3, fromFont)
+	if got, exp := carets(cm), []Position{0, 120, 250, 300}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+	if got := cm.HitTest(130); got != 1 {
+		t.Fatalf("expected 1, got %d", got)
+	}
+	cm = newCaretMap(ligatureBuffer(RightToLeft), text, 0, 3, fromFont)
+	if got, exp := carets(cm), []Position{300, 250, 120, 0}; !reflect.DeepEqual(got, exp) {
+		t.Fatalf("expected %v, got %v", exp, got)
+	}
+}
+
+func TestLineCaretsMixed(t *testing.T) {
+	text := []rune("abcABC") // ABC stands for a right-to-left run
+	ltr := NewBuffer()
+	ltr.Props.Direction = LeftToRight
+	ltr.Info = []GlyphInfo{{Cluster: 0}, {Cluster: 1}, {Cluster: 2}}
+	ltr.Pos = []GlyphPosition{{XAdvance: 100}, {XAdvance: 100}, {XAdvance: 100}}
+	rtl := NewBuffer()
+	rtl.Props.Direction = RightToLeft
+	rtl.Info = []GlyphInfo{{Cluster: 5}, {Cluster: 4}, {Cluster: 3}}
+	rtl.Pos = []GlyphPosition{{XAdvance: 100}, {XAdvance: 100}, {XAdvance: 100}}
+
+	line := NewLineCarets([]*CaretMap{
+		newCaretMap(ltr, text, 0, 3, nil),
+		newCaretMap(rtl, text, 3, 6, nil),
+	})
+	if got := line.Position(3); got != 600 {
+		t.Fatalf("expected 600, got %d", got)
+	}
+	if got := line.Position(6); got != 300 {
+		t.Fatalf("expected 300, got %d", got)
+	}
+	if got := line.HitTest(490); got != 4 {
+		t.Fatalf("expected 4, got %d", got)
+	}
+	if got := line.HitTest(90); got != 1 {
+		t.Fatalf("expected 1, got %d", got)
+	}
+
+	sel := line.Selection(2, 4)
+	if exp := []CaretSpan{{200, 300}, {500, 600}}; !reflect.DeepEqual(sel, exp) {
+		t.Fatalf("expected %v, got %v", exp, sel)
+	}
+	sel = line.Selection(0, 6)
+	if exp := []CaretSpan{{0, 600}}; !reflect.DeepEqual(sel, exp) {
+		t.Fatalf("expected %v, got %v", exp, sel)
+	}
+}
@@ -42,6 +42,19 @@ type FaceOpentype interface {
 	VariationGlyph(ch, varSelector rune) (fonts.GID, bool)
 }
 
+// FaceKerning is an optional interface, implemented by faces
+// providing pair kerning outside of Opentype layout tables,
+// such as Type1 fonts with an attached AFM file.
+// Such faces are shaped with the Opentype shaper, and their
+// kerning is applied for horizontal text when the font has no 'GPOS', 'kerx' or 'kern' tables.
+type FaceKerning interface {
+	Face
+
+	// KernPair returns the horizontal kern value for the given pair,
+	// expressed in font units, or zero.
+	KernPair(left, right fonts.GID) int16
+}
+
 // Font is used internally as a light wrapper around the provided Face.
 //
 // While a font face is generally the in-memory representation of a static font file,
@@ -52,9 +65,16 @@ type FaceOpentype interface {
 // Font are constructed with `NewFont` and adjusted by accessing the fields
 // XPpem, YPpem, Ptem,XScale, YScale and with the method `SetVarCoordsDesign` for
 // variable fonts.
+//
+// The data provided by the face may be overridden for one font
+// with the `Funcs` field. Sub fonts created with `NewSubFont`
+// use by default the data of their parent.
 type Font struct {
 	face Face
 
+	// non nil for sub fonts
+	parent *Font
+
 	// only non nil for valid graphite fonts
 	gr *graphite.GraphiteFace
 
@@ -79,6 +99,52 @@ type Font struct {
 	// Is is used to select bitmap sizes and to perform some Opentype
 	// positionning.
 	XPpem, YPpem uint16
+
+	// Funcs may be used to override the glyph and metrics data
+	// used during shaping. Its fields default to the data provided
+	// by the parent font (for sub fonts) or the face.
+	Funcs FontFuncs
+}
+
+// FontFuncs is a table of optional functions used to override, for one `Font`,
+// the data provided by its face or its parent.
+// A nil field means the default behavior is used.
+//
+// Each function is given the font being queried, so that it may
+// fall back to its parent (see `Font.Parent`).
+// The returned positions and extents are expected to be scaled, that is,
+// expressed in the same units as the shaping output.
+type FontFuncs struct {
+	// NominalGlyph returns the glyph for a rune, or false if it is not supported.
+	NominalGlyph func(font *Font, ch rune) (fonts.GID, bool)
+	// VariationGlyph returns the glyph for a rune followed by a variation selector,
+	// or false if it is not supported.
+	VariationGlyph func(font *Font, ch, varSelector rune) (fonts.GID, bool)
+
+	// GlyphHAdvance returns the advance of a glyph, for horizontal text.
+	GlyphHAdvance func(font *Font, glyph fonts.GID) Position
+	// GlyphVAdvance returns the advance of a glyph, for vertical text.
+	GlyphVAdvance func(font *Font, glyph fonts.GID) Position
+
+	// GlyphHOrigin returns the origin of a glyph for horizontal text,
+	// or false if it is not available.
+	GlyphHOrigin func(font *Font, glyph fonts.GID) (x, y Position, ok bool)
+	// GlyphVOrigin returns the origin of a glyph for vertical text,
+	// or false if it is not available.
+	GlyphVOrigin func(font *Font, glyph fonts.GID) (x, y Position, ok bool)
+
+	// GlyphExtents returns the extents of a glyph, or false if it is not available.
+	GlyphExtents func(font *Font, glyph fonts.GID) (GlyphExtents, bool)
+
+	// GlyphName returns the name of a glyph, or an empty string.
+	GlyphName func(font *Font, glyph fonts.GID) string
+
+	// FontHExtents returns the extents of the font for horizontal text,
+	// or false if they are not available.
+	FontHExtents func(font *Font) (fonts.FontExtents, bool)
+	// FontVExtents returns the extents of the font for vertical text,
+	// or false if they are not available.
+	FontVExtents func(font *Font) (fonts.FontExtents, bool)
 }
 
 // NewFont constructs a new font object from the specified face.
@@ -116,11 +182,32 @@ func NewFont(face Face) *Font {
 		if tables, is := opentypeFace.IsGraphite(); is {
 			font.gr, _ = graphite.LoadGraphite(tables)
 		}
+	} else if _, ok := face.(FaceKerning); ok {
+		// use the Opentype shaper, without layout tables,
+		// so that the fallback kerning is applied
+		font.otTables = &tt.LayoutTables{}
 	}
 
 	return &font
 }
 
+// NewSubFont returns a font sharing the face, the scale and the other settings
+// of `parent`, and delegating all the data queries to `parent`, by default.
+// Its `Funcs` fields may then be set to override some of them.
+//
+// When the scale of the sub font is changed, the positions provided by
+// the parent are scaled accordingly.
+func NewSubFont(parent *Font) *Font {
+	font := *parent
+	font.parent = parent
+	font.Funcs = FontFuncs{}
+	return &font
+}
+
+// Parent returns the parent of a font created with `NewSubFont`,
+// or nil.
+func (f *Font) Parent() *Font { return f.parent }
+
 // SetVarCoordsDesign applies a list of variation coordinates, in design-space units,
 // to the font.
 func (f *Font) SetVarCoordsDesign(coords []float32) {
@@ -135,13 +222,70 @@ func (f *Font) SetVarCoordsDesign(coords []float32) {
 func (f *Font) Face() fonts.Face { return f.face }
 
 func (f *Font) nominalGlyph(r rune, notFound fonts.GID) (fonts.GID, bool) {
-	g, ok := f.face.NominalGlyph(r)
+	g, ok := f.NominalGlyph(r)
 	if !ok {
 		g = notFound
 	}
 	return g, ok
 }
 
+// NominalGlyph returns the glyph used for a rune, or false
+// if it is not supported by the font.
+func (f *Font) NominalGlyph(ch rune) (fonts.GID, bool) {
+	if fn := f.Funcs.NominalGlyph; fn != nil {
+		return fn(f, ch)
+	}
+	if f.parent != nil {
+		return f.parent.NominalGlyph(ch)
+	}
+	return f.face.NominalGlyph(ch)
+}
+
+// VariationGlyph returns the glyph used for a rune followed by
+// a variation selector, or false if it is not supported by the font.
+func (f *Font) VariationGlyph(ch, varSelector rune) (fonts.GID, bool) {
+	if fn := f.Funcs.VariationGlyph; fn != nil {
+		return fn(f, ch, varSelector)
+	}
+	if f.parent != nil {
+		return f.parent.VariationGlyph(ch, varSelector)
+	}
+	if ot, ok := f.face.(FaceOpentype); ok {
+		return ot.VariationGlyph(ch, varSelector)
+	}
+	return 0, false
+}
+
+// ---- Convert from parent scale to the font scale ----
+
+func (f *Font) parentScaleXDistance(v Position) Position {
+	if f.parent.XScale == f.XScale || f.parent.XScale == 0 {
+		return v
+	}
+	return Position(int64(v) * int64(f.XScale) / int64(f.parent.XScale))
+}
+
+func (f *Font) parentScaleYDistance(v Position) Position {
+	if f.parent.YScale == f.YScale || f.parent.YScale == 0 {
+		return v
+	}
+	return Position(int64(v) * int64(f.YScale) / int64(f.parent.YScale))
+}
+
+func (f *Font) parentScaleXFloat(v float32) float32 {
+	if f.parent.XScale == 0 {
+		return v
+	}
+	return v * float32(f.XScale) / float32(f.parent.XScale)
+}
+
+func (f *Font) parentScaleYFloat(v float32) float32 {
+	if f.parent.YScale == 0 {
+		return v
+	}
+	return v * float32(f.YScale) / float32(f.parent.YScale)
+}
+
 // ---- Convert from font-space to user-space ----
 
 func (f *Font) emScaleX(v int16) Position    { return Position(v) * f.XScale / f.faceUpem }
@@ -170,6 +314,17 @@ type GlyphExtents struct {
 // GlyphExtents fetches the GlyphExtents data for a glyph ID
 // in the specified font, or false if not found
 func (f *Font) GlyphExtents(glyph fonts.GID) (out GlyphExtents, ok bool) {
+	if fn := f.Funcs.GlyphExtents; fn != nil {
+		return fn(f, glyph)
+	}
+	if f.parent != nil {
+		out, ok = f.parent.GlyphExtents(glyph)
+		out.XBearing = f.parentScaleXDistance(out.XBearing)
+		out.Width = f.parentScaleXDistance(out.Width)
+		out.YBearing = f.parentScaleYDistance(out.YBearing)
+		out.Height = f.parentScaleYDistance(out.Height)
+		return out, ok
+	}
 	ext, ok := f.face.GlyphExtents(glyph, f.XPpem, f.YPpem)
 	if !ok {
 		return out, false
@@ -190,23 +345,57 @@ func (f *Font) GlyphAdvanceForDirection(glyph fonts.GID, dir Direction) (x, y Po
 	if dir.isHorizontal() {
 		return f.GlyphHAdvance(glyph), 0
 	}
-	return 0, f.getGlyphVAdvance(glyph)
+	return 0, f.GlyphVAdvance(glyph)
 }
 
 // GlyphHAdvance fetches the advance for a glyph ID in the font,
 // for horizontal text segments.
 func (f *Font) GlyphHAdvance(glyph fonts.GID) Position {
+	if fn := f.Funcs.GlyphHAdvance; fn != nil {
+		return fn(f, glyph)
+	}
+	if f.parent != nil {
+		return f.parentScaleXDistance(f.parent.GlyphHAdvance(glyph))
+	}
 	adv := f.face.HorizontalAdvance(glyph)
 	return f.emScalefX(adv)
 }
 
-// Fetches the advance for a glyph ID in the font,
+// GlyphVAdvance fetches the advance for a glyph ID in the font,
 // for vertical text segments.
-func (f *Font) getGlyphVAdvance(glyph fonts.GID) Position {
+func (f *Font) GlyphVAdvance(glyph fonts.GID) Position {
+	if fn := f.Funcs.GlyphVAdvance; fn != nil {
+		return fn(f, glyph)
+	}
+	if f.parent != nil {
+		return f.parentScaleYDistance(f.parent.GlyphVAdvance(glyph))
+	}
 	adv := f.face.VerticalAdvance(glyph)
 	return f.emScalefY(adv)
 }
 
+func (f *Font) glyphHOrigin(glyph fonts.GID) (x, y Position, ok bool) {
+	if fn := f.Funcs.GlyphHOrigin; fn != nil {
+		return fn(f, glyph)
+	}
+	if f.parent != nil {
+		x, y, ok = f.parent.glyphHOrigin(glyph)
+		return f.parentScaleXDistance(x), f.parentScaleYDistance(y), ok
+	}
+	return f.face.GlyphHOrigin(glyph)
+}
+
+func (f *Font) glyphVOrigin(glyph fonts.GID) (x, y Position, ok bool) {
+	if fn := f.Funcs.GlyphVOrigin; fn != nil {
+		return fn(f, glyph)
+	}
+	if f.parent != nil {
+		x, y, ok = f.parent.glyphVOrigin(glyph)
+		return f.parentScaleXDistance(x), f.parentScaleYDistance(y), ok
+	}
+	return f.face.GlyphVOrigin(glyph)
+}
+
 // Subtracts the origin coordinates from an (X,Y) point coordinate,
 // in the specified glyph ID in the specified font.
 //
@@ -233,9 +422,9 @@ func (f *Font) getGlyphOriginForDirection(glyph fonts.GID, direction Direction)
 }
 
 func (f *Font) getGlyphHOriginWithFallback(glyph fonts.GID) (Position, Position) {
-	x, y, ok := f.face.GlyphHOrigin(glyph)
+	x, y, ok := f.glyphHOrigin(glyph)
 	if !ok {
-		x, y, ok = f.face.GlyphVOrigin(glyph)
+		x, y, ok = f.glyphVOrigin(glyph)
 		if ok {
 			dx, dy := f.guessVOriginMinusHOrigin(glyph)
 			return x - dx, y - dy
@@ -245,9 +434,9 @@ func (f *Font) getGlyphHOriginWithFallback(glyph fonts.GID) (Position, Position)
 }
 
 func (f *Font) getGlyphVOriginWithFallback(glyph fonts.GID) (Position, Position) {
-	x, y, ok := f.face.GlyphVOrigin(glyph)
+	x, y, ok := f.glyphVOrigin(glyph)
 	if !ok {
-		x, y, ok = f.face.GlyphHOrigin(glyph)
+		x, y, ok = f.glyphHOrigin(glyph)
 		if ok {
 			dx, dy := f.guessVOriginMinusHOrigin(glyph)
 			return x + dx, y + dy
@@ -263,15 +452,15 @@ func (f *Font) guessVOriginMinusHOrigin(glyph fonts.GID) (x, y Position) {
 }
 
 func (f *Font) getHExtendsAscender() Position {
-	extents, ok := f.face.FontHExtents()
+	extents, ok := f.fontHExtents()
 	if !ok {
 		return f.YScale * 4 / 5
 	}
-	return f.emScalefY(extents.Ascender)
+	return Position(extents.Ascender)
 }
 
 func (f *Font) hasGlyph(ch rune) bool {
-	_, ok := f.face.NominalGlyph(ch)
+	_, ok := f.NominalGlyph(ch)
 	return ok
 }
 
@@ -304,9 +493,19 @@ func (f *Font) getGlyphContourPointForOrigin(glyph fonts.GID, pointIndex uint16,
 	return x, y, ok
 }
 
+func (f *Font) glyphName(glyph fonts.GID) string {
+	if fn := f.Funcs.GlyphName; fn != nil {
+		return fn(f, glyph)
+	}
+	if f.parent != nil {
+		return f.parent.glyphName(glyph)
+	}
+	return f.face.GlyphName(glyph)
+}
+
 // Generates gidDDD if glyph has no name.
 func (f *Font) glyphToString(glyph fonts.GID) string {
-	if name := f.face.GlyphName(glyph); name != "" {
+	if name := f.glyphName(glyph); name != "" {
 		return name
 	}
 
@@ -324,20 +523,14 @@ func (f *Font) ExtentsForDirection(direction Direction) fonts.FontExtents {
 		ok      bool
 	)
 	if direction.isHorizontal() {
-		extents, ok = f.face.FontHExtents()
-		extents.Ascender = float32(f.emScalefY(extents.Ascender))
-		extents.Descender = float32(f.emScalefY(extents.Descender))
-		extents.LineGap = float32(f.emScalefY(extents.LineGap))
+		extents, ok = f.fontHExtents()
 		if !ok {
 			extents.Ascender = float32(f.YScale) * 0.8
 			extents.Descender = extents.Ascender - float32(f.YScale)
 			extents.LineGap = 0
 		}
 	} else {
-		extents, ok = f.face.FontVExtents()
-		extents.Ascender = float32(f.emScalefX(extents.Ascender))
-		extents.Descender = float32(f.emScalefX(extents.Descender))
-		extents.LineGap = float32(f.emScalefX(extents.LineGap))
+		extents, ok = f.fontVExtents()
 		if !ok {
 			extents.Ascender = float32(f.XScale) * 0.5
 			extents.Descender = extents.Ascender - float32(f.XScale)
@@ -347,6 +540,44 @@ func (f *Font) ExtentsForDirection(direction Direction) fonts.FontExtents {
 	return extents
 }
 
+// returns the scaled horizontal extents
+func (f *Font) fontHExtents() (fonts.FontExtents, bool) {
+	if fn := f.Funcs.FontHExtents; fn != nil {
+		return fn(f)
+	}
+	if f.parent != nil {
+		extents, ok := f.parent.fontHExtents()
+		extents.Ascender = f.parentScaleYFloat(extents.Ascender)
+		extents.Descender = f.parentScaleYFloat(extents.Descender)
+		extents.LineGap = f.parentScaleYFloat(extents.LineGap)
+		return extents, ok
+	}
+	extents, ok := f.face.FontHExtents()
+	extents.Ascender = float32(f.emScalefY(extents.Ascender))
+	extents.Descender = float32(f.emScalefY(extents.Descender))
+	extents.LineGap = float32(f.emScalefY(extents.LineGap))
+	return extents, ok
+}
+
+// returns the scaled vertical extents
+func (f *Font) fontVExtents() (fonts.FontExtents, bool) {
+	if fn := f.Funcs.FontVExtents; fn != nil {
+		return fn(f)
+	}
+	if f.parent != nil {
+		extents, ok := f.parent.fontVExtents()
+		extents.Ascender = f.parentScaleXFloat(extents.Ascender)
+		extents.Descender = f.parentScaleXFloat(extents.Descender)
+		extents.LineGap = f.parentScaleXFloat(extents.LineGap)
+		return extents, ok
+	}
+	extents, ok := f.face.FontVExtents()
+	extents.Ascender = float32(f.emScalefX(extents.Ascender))
+	extents.Descender = float32(f.emScalefX(extents.Descender))
+	extents.LineGap = float32(f.emScalefX(extents.LineGap))
+	return extents, ok
+}
+
 // LineMetric fetches the given metric, applying potential variations
 // and scaling.
 func (f *Font) LineMetric(metric fonts.LineMetric) (int32, bool) {
@@ -384,7 +615,8 @@ func (font *Font) getYDelta(varStore tt.VariationStore, device tt.DeviceTable) P
 }
 
 // GetOTLayoutTables returns the OpenType layout tables, or nil
-// if the underlying face is not a FaceOpentype.
+// if the underlying face is not a FaceOpentype (or a FaceKerning,
+// in which case the tables are empty).
 // The returned tables should not be modified.
 func (f *Font) GetOTLayoutTables() *tt.LayoutTables { return f.otTables }
 
@@ -63,6 +63,62 @@ func TestFontProperties(t *testing.T) {
 	assertEqualInt(t, int(font.Ptem), 0)
 }
 
+func TestFontFuncs(t *testing.T) {
+	parent := NewFont(openFontFile("fonts/SourceSansVariable-Roman-nohvar-41,C1.ttf"))
+	gA, _ := parent.NominalGlyph('A')
+	gB, _ := parent.NominalGlyph('B')
+	advA, advB := parent.GlyphHAdvance(gA), parent.GlyphHAdvance(gB)
+	assert(t, advA != advB)
+
+	// sub font delegating everything, with a different scale
+	sub := NewSubFont(parent)
+	assert(t, sub.Parent() == parent)
+	g, _ := sub.NominalGlyph('A')
+	assertEqualInt(t, int(gA), int(g))
+	assertEqualInt32(t, sub.GlyphHAdvance(gA), advA)
+	sub.XScale *= 2
+	assertEqualInt32(t, sub.GlyphHAdvance(gA), 2*advA)
+	extA, _ := parent.GlyphExtents(gA)
+	subExtA, _ := sub.GlyphExtents(gA)
+	assertEqualInt32(t, subExtA.Width, 2*extA.Width)
+	assertEqualInt32(t, subExtA.Height, extA.Height)
+
+	// remapping PUA and forcing monospace advances
+	sub = NewSubFont(parent)
+	sub.Funcs.NominalGlyph = func(font *Font, ch rune) (fonts.GID, bool) {
+		if ch == 0xE000 {
+			ch = 'B'
+		}
+		return font.Parent().NominalGlyph(ch)
+	}
+	sub.Funcs.GlyphHAdvance = func(font *Font, glyph fonts.GID) Position { return 600 }
+
+	buffer := NewBuffer()
+	buffer.AddRunes([]rune{'A', 0xE000}, 0, -1)
+	buffer.Props.Direction = LeftToRight
+	buffer.Shape(sub, nil)
+	assertEqualInt(t, len(buffer.Info), 2)
+	assertEqualInt(t, int(buffer.Info[0].Glyph), int(gA))
+	assertEqualInt(t, int(buffer.Info[1].Glyph), int(gB))
+	assertEqualInt32(t, buffer.Pos[0].XAdvance, 600)
+	assertEqualInt32(t, buffer.Pos[1].XAdvance, 600)
+
+	// the parent is not affected
+	buffer = NewBuffer()
+	buffer.AddRunes([]rune{'A', 0xE000}, 0, -1)
+	buffer.Props.Direction = LeftToRight
+	buffer.Shape(parent, nil)
+	assertEqualInt(t, int(buffer.Info[1].Glyph), 0)
+	assertEqualInt32(t, buffer.Pos[0].XAdvance, advA)
+
+	// font extents
+	sub.Funcs.FontHExtents = func(font *Font) (fonts.FontExtents, bool) {
+		return fonts.FontExtents{Ascender: 10, Descender: -5}, true
+	}
+	ext := sub.ExtentsForDirection(LeftToRight)
+	assert(t, ext.Ascender == 10 && ext.Descender == -5)
+}
+
 // Unit tests for glyph advance Widths and extents of TrueType variable fonts
 // ported from harfbuzz/test/api/test-ot-metrics-tt-var.c Copyright © 2019 Adobe Inc. Michiharu Ariza
 
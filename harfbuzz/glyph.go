@@ -85,8 +85,21 @@ const (
 	// breaking point only.
 	GlyphUnsafeToBreak GlyphMask = 0x00000001
 
+	// Indicates that if input text is changed on one side of the beginning of the cluster this glyph
+	// is part of, then the shaping results for the other side might change.
+	// Note that the absence of this flag will NOT by itself mean that it IS safe to concat text.
+	// Only two pieces of text both of which clear of this flag can be concatenated safely.
+	// This can be used to optimize paragraph layout, by limiting the reshaping to a small piece
+	// around the breaking position only, even if the breaking position carries the
+	// `GlyphUnsafeToBreak` flag or when hyphenation or other text transformation happens
+	// at line-break position (see `Buffer.BreakRanges`).
+	// `GlyphUnsafeToBreak` always implies this flag.
+	// To use this flag, the `ProduceUnsafeToConcat` buffer option must be set during shaping,
+	// otherwise it will not be reliably produced.
+	GlyphUnsafeToConcat GlyphMask = 0x00000002
+
 	// OR of all defined flags
-	glyphFlagDefined GlyphMask = GlyphUnsafeToBreak
+	glyphFlagDefined GlyphMask = GlyphUnsafeToBreak | GlyphUnsafeToConcat
 )
 
 // GlyphInfo holds information about the
@@ -160,6 +173,10 @@ func (info GlyphInfo) String() string {
 	return fmt.Sprintf("%d=%d(%d)", info.Glyph, info.Cluster, info.Mask)
 }
 
+// Codepoint returns the input rune this glyph was created from.
+// It may be used by custom shapers (see `Shaper`).
+func (info GlyphInfo) Codepoint() rune { return info.codepoint }
+
 // use glyphProps, ligProps and syllable to store an int32 (see getInt32)
 func (info *GlyphInfo) setInt32(val int32) {
 	info.glyphProps = uint16(val >> 16)
@@ -185,11 +202,7 @@ func (info *GlyphInfo) setGeneralCategory(genCat generalCategory) {
 
 func (info *GlyphInfo) setCluster(cluster int, mask GlyphMask) {
 	if info.Cluster != cluster {
-		if mask&GlyphUnsafeToBreak != 0 {
-			info.Mask |= GlyphUnsafeToBreak
-		} else {
-			info.Mask &= ^GlyphUnsafeToBreak
-		}
+		info.Mask = (info.Mask & ^glyphFlagDefined) | (mask & glyphFlagDefined)
 	}
 	info.Cluster = cluster
 }
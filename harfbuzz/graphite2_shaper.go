@@ -27,9 +27,9 @@ type graphite2Cluster struct {
 // shaperGraphite implements a shaper using Graphite features.
 type shaperGraphite graphite.GraphiteFace
 
-func (shaperGraphite) kind() shaperKind { return skGraphite }
+func (*shaperGraphite) kind() shaperKind { return skGraphite }
 
-func (shaperGraphite) compile(props SegmentProperties, userFeatures []Feature) {}
+func (*shaperGraphite) compile(props SegmentProperties, userFeatures []Feature) {}
 
 // Converts a string into a Tag. Valid tags
 // are four characters. Shorter input strings will be
@@ -210,5 +210,5 @@ func (sh *shaperGraphite) shape(font *Font, buffer *Buffer, features []Feature)
 		buffer.reverseClusters()
 	}
 
-	buffer.clearGlyphFlags(GlyphUnsafeToBreak)
+	buffer.clearGlyphFlags(GlyphUnsafeToBreak | GlyphUnsafeToConcat)
 }
@@ -287,6 +287,16 @@ func arabicJoining(buffer *Buffer) {
 		if entry.prevAction != arabNone && prev != -1 {
 			info[prev].complexAux = entry.prevAction
 			buffer.unsafeToBreak(prev, i+1)
+		} else {
+			if prev == -1 {
+				if thisType >= joiningTypeR {
+					buffer.unsafeToConcatFromOutbuffer(0, i+1)
+				}
+			} else {
+				if thisType >= joiningTypeR || (2 <= state && state <= 5) /* States that have a possible prevAction. */ {
+					buffer.unsafeToConcat(prev, i+1)
+				}
+			}
 		}
 
 		info[i].complexAux = entry.currAction
@@ -305,6 +315,9 @@ func arabicJoining(buffer *Buffer) {
 		entry := &arabicStateTable[state][thisType]
 		if entry.prevAction != arabNone && prev != -1 {
 			info[prev].complexAux = entry.prevAction
+			buffer.unsafeToBreak(prev, len(buffer.Info))
+		} else if prev != -1 && 2 <= state && state <= 5 /* States that have a possible prevAction. */ {
+			buffer.unsafeToConcat(prev, len(buffer.Info))
 		}
 		break
 	}
@@ -650,8 +663,8 @@ func arabicFallbackSynthesizeLookupSingle(font *Font, featureIndex int) *lookupG
 	// populate arrays
 	for u := rune(ucd.FirstArabicShape); u <= ucd.LastArabicShape; u++ {
 		s := rune(ucd.ArabicShaping[u-ucd.FirstArabicShape][featureIndex])
-		uGlyph, hasU := font.face.NominalGlyph(u)
-		sGlyph, hasS := font.face.NominalGlyph(s)
+		uGlyph, hasU := font.NominalGlyph(u)
+		sGlyph, hasS := font.NominalGlyph(s)
 
 		if s == 0 || !hasU || !hasS || uGlyph == sGlyph || uGlyph > 0xFFFF || sGlyph > 0xFFFF {
 			continue
@@ -699,7 +712,7 @@ func arabicFallbackSynthesizeLookupLigature(font *Font) *lookupGSUB {
 
 	// sort out the first-glyphs
 	for firstGlyphIdx, lig := range ucd.ArabicLigatures {
-		firstGlyph, ok := font.face.NominalGlyph(lig.First)
+		firstGlyph, ok := font.NominalGlyph(lig.First)
 		if !ok {
 			continue
 		}
@@ -721,8 +734,8 @@ func arabicFallbackSynthesizeLookupLigature(font *Font) *lookupGSUB {
 		var ligatureSet []tt.LigatureGlyph
 		for _, v := range ligs {
 			secondU, ligatureU := v[0], v[1]
-			secondGlyph, hasSecond := font.face.NominalGlyph(secondU)
-			ligatureGlyph, hasLigature := font.face.NominalGlyph(ligatureU)
+			secondGlyph, hasSecond := font.NominalGlyph(secondU)
+			ligatureGlyph, hasLigature := font.NominalGlyph(ligatureU)
 			if secondU == 0 || !hasSecond || !hasLigature {
 				continue
 			}
@@ -759,11 +772,11 @@ type arabicFallbackPlan struct {
 
 func (fbPlan *arabicFallbackPlan) initWin1256(plan *otShapePlan, font *Font) bool {
 	// does this font look like it's Windows-1256-encoded?
-	g1, _ := font.face.NominalGlyph(0x0627) /* ALEF */
-	g2, _ := font.face.NominalGlyph(0x0644) /* LAM */
-	g3, _ := font.face.NominalGlyph(0x0649) /* ALEF MAKSURA */
-	g4, _ := font.face.NominalGlyph(0x064A) /* YEH */
-	g5, _ := font.face.NominalGlyph(0x0652) /* SUKUN */
+	g1, _ := font.NominalGlyph(0x0627) /* ALEF */
+	g2, _ := font.NominalGlyph(0x0644) /* LAM */
+	g3, _ := font.NominalGlyph(0x0649) /* ALEF MAKSURA */
+	g4, _ := font.NominalGlyph(0x064A) /* YEH */
+	g5, _ := font.NominalGlyph(0x0652) /* SUKUN */
 	if !(g1 == 199 && g2 == 225 && g3 == 236 && g4 == 237 && g5 == 250) {
 		return false
 	}
@@ -443,7 +443,7 @@ type indicShapePlan struct {
 
 func (indicPlan *indicShapePlan) loadViramaGlyph(font *Font) fonts.GID {
 	if indicPlan.viramaGlyph == ^fonts.GID(0) {
-		glyph, ok := font.face.NominalGlyph(indicPlan.config.virama)
+		glyph, ok := font.NominalGlyph(indicPlan.config.virama)
 		if indicPlan.config.virama == 0 || !ok {
 			glyph = 0
 		}
@@ -1631,7 +1631,7 @@ func (cs *complexShaperIndic) decompose(c *otNormalizeContext, ab rune) (rune, r
 		 */
 
 		indicPlan := cs.plan
-		glyph, ok := c.font.face.NominalGlyph(ab)
+		glyph, ok := c.font.NominalGlyph(ab)
 		if indicPlan.uniscribeBugCompatible ||
 			(ok && indicPlan.pstf.wouldSubstitute([]fonts.GID{glyph}, c.font)) {
 			/* Ok, safe to use Uniscribe-style decomposition. */
@@ -28,7 +28,9 @@ func kern(driver tt.SimpleKerns, crossStream bool, font *Font, buffer *Buffer, k
 		}
 
 		skippyIter.reset(idx, 1)
-		if !skippyIter.next() {
+		var unsafeTo int
+		if !skippyIter.next(&unsafeTo) {
+			buffer.unsafeToConcat(idx, unsafeTo)
 			idx++
 			continue
 		}
@@ -89,6 +91,9 @@ func (sp *otShapePlan) otApplyFallbackKern(font *Font, buffer *Buffer) {
 
 	if driver := simpleKern(font.otTables.Kern); driver != nil {
 		kern(driver, false, font, buffer, sp.kernMask, false)
+	} else if driver, ok := font.face.(FaceKerning); ok && buffer.Props.Direction.isHorizontal() {
+		// kerning values are in font units
+		kern(driver, false, font, buffer, sp.kernMask, true)
 	}
 
 	if reverse {
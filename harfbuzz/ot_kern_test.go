@@ -0,0 +1,69 @@
+package harfbuzz
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	testdata "github.com/benoitkugler/textlayout-testdata/type1"
+	tt "github.com/benoitkugler/textlayout/fonts/truetype"
+	"github.com/benoitkugler/textlayout/fonts/type1"
+)
+
+// calligrapherAFM provides metrics for the glyphs A and V of CalligrapherRegular.pfb
+const calligrapherAFM = `StartFontMetrics 4.1
+FontName Calligrapher-Regular
+FontBBox -173 -234 1328 899
+StartCharMetrics 2
+C 65 ; WX 743 ; N A ; B -23 -14 777 722 ;
+C 86 ; WX 753 ; N V ; B -28 -20 816 729 ;
+EndCharMetrics
+StartKernData
+StartKernPairs 2
+KPX A V -74
+KPX V A -62
+EndKernPairs
+EndKernData
+EndFontMetrics
+`
+
+func TestFallbackKernAFM(t *testing.T) {
+	pfb, err := testdata.Files.ReadFile("CalligrapherRegular.pfb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := type1.ParseWithAFM(bytes.NewReader(pfb), strings.NewReader(calligrapherAFM))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shape := func(features []Feature) []GlyphPosition {
+		font := NewFont(face)
+		if font.GetOTLayoutTables() == nil {
+			t.Fatal("expected Opentype shaper")
+		}
+		buf := NewBuffer()
+		buf.AddRunes([]rune("AVA"), 0, -1)
+		buf.GuessSegmentProperties()
+		buf.Shape(font, features)
+		return buf.Pos
+	}
+
+	// A V: -74, V A: -62
+	pos := shape(nil)
+	if len(pos) != 3 {
+		t.Fatalf("unexpected output %v", pos)
+	}
+	assertEqualInt(t, 743-37, int(pos[0].XAdvance))
+	assertEqualInt(t, 753-37-31, int(pos[1].XAdvance))
+	assertEqualInt(t, -37, int(pos[1].XOffset))
+	assertEqualInt(t, 743-31, int(pos[2].XAdvance))
+	assertEqualInt(t, -31, int(pos[2].XOffset))
+
+	// kerning disabled
+	pos = shape([]Feature{{Tag: tt.MustNewTag("kern"), Value: 0, Start: 0, End: FeatureGlobalEnd}})
+	for i, p := range pos {
+		assertEqualInt(t, []int{743, 753, 743}[i], int(p.XAdvance))
+		assertEqualInt(t, 0, int(p.XOffset))
+	}
+}
@@ -148,19 +148,24 @@ func (table gposSubtable) apply(c *otApplyContext) bool {
 	case tt.GPOSPair1:
 		skippyIter := &c.iterInput
 		skippyIter.reset(buffer.idx, 1)
-		if !skippyIter.next() {
+		var unsafeTo int
+		if !skippyIter.next(&unsafeTo) {
+			buffer.unsafeToConcat(buffer.idx, unsafeTo)
 			return false
 		}
 		set := data.Values[index]
 		record := set.FindGlyph(buffer.Info[skippyIter.idx].Glyph)
 		if record == nil {
+			buffer.unsafeToConcat(buffer.idx, skippyIter.idx+1)
 			return false
 		}
 		c.applyGPOSPair(data.Formats, record.Pos, skippyIter.idx)
 	case tt.GPOSPair2:
 		skippyIter := &c.iterInput
 		skippyIter.reset(buffer.idx, 1)
-		if !skippyIter.next() {
+		var unsafeTo int
+		if !skippyIter.next(&unsafeTo) {
+			buffer.unsafeToConcat(buffer.idx, unsafeTo)
 			return false
 		}
 		class1, _ := data.First.ClassID(glyphID)
@@ -287,6 +292,8 @@ func (c *otApplyContext) applyGPOSPair(formats [2]tt.GPOSValueFormat, values [2]
 
 	if ap1 || ap2 {
 		buffer.unsafeToBreak(buffer.idx, pos+1)
+	} else {
+		buffer.unsafeToConcat(buffer.idx, pos+1)
 	}
 	buffer.idx = pos
 	if formats[1] != 0 {
@@ -304,16 +311,20 @@ func (c *otApplyContext) applyGPOSCursive(data tt.GPOSCursive1, covIndex int, co
 
 	skippyIter := &c.iterInput
 	skippyIter.reset(buffer.idx, 1)
-	if !skippyIter.prev() {
+	var unsafeFrom int
+	if !skippyIter.prev(&unsafeFrom) {
+		buffer.unsafeToConcatFromOutbuffer(unsafeFrom, buffer.idx+1)
 		return false
 	}
 
 	prevIndex, ok := cov.Index(buffer.Info[skippyIter.idx].Glyph)
 	if !ok {
+		buffer.unsafeToConcatFromOutbuffer(skippyIter.idx, buffer.idx+1)
 		return false
 	}
 	prevRecord := data[prevIndex]
 	if prevRecord[1] == nil {
+		buffer.unsafeToConcatFromOutbuffer(skippyIter.idx, buffer.idx+1)
 		return false
 	}
 
@@ -475,7 +486,9 @@ func (c *otApplyContext) applyGPOSMarkToBase(data tt.GPOSMarkToBase1, markIndex
 	skippyIter.reset(buffer.idx, 1)
 	skippyIter.matcher.lookupProps = uint32(tt.IgnoreMarks)
 	for {
-		if !skippyIter.prev() {
+		var unsafeFrom int
+		if !skippyIter.prev(&unsafeFrom) {
+			buffer.unsafeToConcatFromOutbuffer(unsafeFrom, buffer.idx+1)
 			return false
 		}
 		/* We only want to attach to the first of a MultipleSubst sequence.
@@ -497,6 +510,7 @@ func (c *otApplyContext) applyGPOSMarkToBase(data tt.GPOSMarkToBase1, markIndex
 
 	baseIndex, ok := data.BaseCoverage.Index(buffer.Info[skippyIter.idx].Glyph)
 	if !ok {
+		buffer.unsafeToConcatFromOutbuffer(skippyIter.idx, buffer.idx+1)
 		return false
 	}
 
@@ -510,13 +524,16 @@ func (c *otApplyContext) applyGPOSMarkToLigature(data tt.GPOSMarkToLigature1, ma
 	skippyIter := &c.iterInput
 	skippyIter.reset(buffer.idx, 1)
 	skippyIter.matcher.lookupProps = uint32(tt.IgnoreMarks)
-	if !skippyIter.prev() {
+	var unsafeFrom int
+	if !skippyIter.prev(&unsafeFrom) {
+		buffer.unsafeToConcatFromOutbuffer(unsafeFrom, buffer.idx+1)
 		return false
 	}
 
 	j := skippyIter.idx
 	ligIndex, ok := data.LigatureCoverage.Index(buffer.Info[j].Glyph)
 	if !ok {
+		buffer.unsafeToConcatFromOutbuffer(j, buffer.idx+1)
 		return false
 	}
 
@@ -525,6 +542,7 @@ func (c *otApplyContext) applyGPOSMarkToLigature(data tt.GPOSMarkToLigature1, ma
 	/* Find component to attach to */
 	compCount := len(ligAttach)
 	if compCount == 0 {
+		buffer.unsafeToConcatFromOutbuffer(j, buffer.idx+1)
 		return false
 	}
 
@@ -550,11 +568,14 @@ func (c *otApplyContext) applyGPOSMarkToMark(data tt.GPOSMarkToMark1, mark1Index
 	skippyIter := &c.iterInput
 	skippyIter.reset(buffer.idx, 1)
 	skippyIter.matcher.lookupProps = c.lookupProps &^ uint32(ignoreFlags)
-	if !skippyIter.prev() {
+	var unsafeFrom int
+	if !skippyIter.prev(&unsafeFrom) {
+		buffer.unsafeToConcatFromOutbuffer(unsafeFrom, buffer.idx+1)
 		return false
 	}
 
 	if !buffer.Info[skippyIter.idx].isMark() {
+		buffer.unsafeToConcatFromOutbuffer(skippyIter.idx, buffer.idx+1)
 		return false
 	}
 
@@ -580,11 +601,13 @@ func (c *otApplyContext) applyGPOSMarkToMark(data tt.GPOSMarkToMark1, mark1Index
 	}
 
 	/* Didn't match. */
+	buffer.unsafeToConcatFromOutbuffer(j, buffer.idx+1)
 	return false
 
 good:
 	mark2Index, ok := data.Mark2Coverage.Index(buffer.Info[j].Glyph)
 	if !ok {
+		buffer.unsafeToConcatFromOutbuffer(j, buffer.idx+1)
 		return false
 	}
 
@@ -174,7 +174,7 @@ func initialReorderingConsonantSyllableMyanmar(buffer *Buffer, start, end int) {
 	}
 
 	/* Sit tight, rock 'n roll! */
-	buffer.sort(start, end, func(a, b *GlyphInfo) int { return int(a.complexAux) - int(b.complexAux) })
+	buffer.sort(start, end, func(a, b *GlyphInfo) int { return int(a.complexAux) - int(b.complexAux) }, false)
 }
 
 func reorderSyllableMyanmar(buffer *Buffer, start, end int) {
@@ -380,24 +380,24 @@ func fallbackSpaces(font *Font, buffer *Buffer) {
 			}
 		case spaceFigure:
 			for u := '0'; u <= '9'; u++ {
-				if glyph, ok := font.face.NominalGlyph(u); ok {
+				if glyph, ok := font.NominalGlyph(u); ok {
 					if horizontal {
 						pos[i].XAdvance = font.GlyphHAdvance(glyph)
 					} else {
-						pos[i].YAdvance = font.getGlyphVAdvance(glyph)
+						pos[i].YAdvance = font.GlyphVAdvance(glyph)
 					}
 				}
 			}
 		case spacePunctuation:
-			glyph, ok := font.face.NominalGlyph('.')
+			glyph, ok := font.NominalGlyph('.')
 			if !ok {
-				glyph, ok = font.face.NominalGlyph(',')
+				glyph, ok = font.NominalGlyph(',')
 			}
 			if ok {
 				if horizontal {
 					pos[i].XAdvance = font.GlyphHAdvance(glyph)
 				} else {
-					pos[i].YAdvance = font.getGlyphVAdvance(glyph)
+					pos[i].YAdvance = font.GlyphVAdvance(glyph)
 				}
 			}
 		case spaceNarrow:
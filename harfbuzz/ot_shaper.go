@@ -521,7 +521,7 @@ func hideDefaultIgnorables(buffer *Buffer, font *Font) {
 		ok        bool
 	)
 	if invisible == 0 {
-		invisible, ok = font.face.NominalGlyph(' ')
+		invisible, ok = font.NominalGlyph(' ')
 	}
 	if buffer.Flags&RemoveDefaultIgnorables == 0 && ok {
 		// replace default-ignorables with a zero-advance invisible glyph.
@@ -630,7 +630,7 @@ func (c *otContext) positionDefault() {
 		}
 	} else {
 		for i, inf := range info {
-			pos[i].XAdvance, pos[i].YAdvance = 0, c.font.getGlyphVAdvance(inf.Glyph)
+			pos[i].XAdvance, pos[i].YAdvance = 0, c.font.GlyphVAdvance(inf.Glyph)
 			pos[i].XOffset, pos[i].YOffset = c.font.subtractGlyphVOrigin(inf.Glyph, 0, 0)
 		}
 	}
@@ -711,7 +711,7 @@ func (c *otContext) position() {
 /* Propagate cluster-level glyph flags to be the same on all cluster glyphs.
  * Simplifies using them. */
 func propagateFlags(buffer *Buffer) {
-	if buffer.scratchFlags&bsfHasUnsafeToBreak == 0 {
+	if buffer.scratchFlags&bsfHasGlyphFlags == 0 {
 		return
 	}
 
@@ -721,10 +721,7 @@ func propagateFlags(buffer *Buffer) {
 	for start, end := iter.next(); start < count; start, end = iter.next() {
 		var mask uint32
 		for i := start; i < end; i++ {
-			if info[i].Mask&GlyphUnsafeToBreak != 0 {
-				mask = GlyphUnsafeToBreak
-				break
-			}
+			mask |= info[i].Mask & glyphFlagDefined
 		}
 		if mask != 0 {
 			for i := start; i < end; i++ {
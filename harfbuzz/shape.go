@@ -25,9 +25,112 @@ import (
 //
 // It also depends on the properties of the segment of text : the `Props`
 // field of the buffer must be set before calling `Shape`.
+//
+// Shape is equivalent to `ShapeFull` with the default shaper list.
 func (b *Buffer) Shape(font *Font, features []Feature) {
-	shapePlan := newShapePlanCached(font, b.Props, features, font.varCoords())
-	shapePlan.execute(font, b, features)
+	b.ShapeFull(font, features, nil)
+}
+
+// Names of the built-in shapers, which may be used in `Buffer.ShapeFull`.
+const (
+	ShaperGraphite = "graphite2" // requires a font with valid Graphite tables
+	ShaperOpentype = "ot"        // requires a font implementing `FaceOpentype`
+	ShaperFallback = "fallback"  // supports every font
+)
+
+// defaultShapers is the shaper list used when none is provided,
+// which falls back to the most capable built-in shaper.
+var defaultShapers = []string{ShaperGraphite, ShaperOpentype, ShaperFallback}
+
+// ShapeFull is the same as `Shape`, but tries the shapers in `shapers` in order,
+// until one supporting `font` is found. The names may refer to the built-in
+// shapers (see the `ShaperXXX` constants) or to custom shapers registered
+// with `RegisterShaper`. Unknown names are ignored.
+// If `shapers` is nil, the built-in shapers are tried, starting with the most capable.
+//
+// It returns false if no shaper in the list supports `font`, in which case the buffer is left unchanged.
+func (b *Buffer) ShapeFull(font *Font, features []Feature, shapers []string) bool {
+	if shapers == nil {
+		shapers = defaultShapers
+	}
+	for _, name := range shapers {
+		var kind shaperKind
+		switch name {
+		case ShaperGraphite:
+			if font.gr == nil {
+				continue
+			}
+			kind = skGraphite
+		case ShaperOpentype:
+			if font.otTables == nil {
+				continue
+			}
+			kind = skOpentype
+		case ShaperFallback:
+			kind = skFallback
+		default:
+			custom := lookupShaper(name)
+			if custom == nil || !custom.Supports(font) {
+				continue
+			}
+			custom.Shape(font, b, features)
+			return true
+		}
+
+		shapePlan := newShapePlanCached(font, b.Props, features, font.varCoords(), kind)
+		shapePlan.execute(font, b, features)
+		return true
+	}
+	return false
+}
+
+// Shaper is the interface to implement to provide custom shapers.
+// Once registered with `RegisterShaper`, they may be selected
+// by name in `Buffer.ShapeFull`.
+type Shaper interface {
+	// Name returns the identifier of the shaper,
+	// which must be distinct from the built-in shapers.
+	Name() string
+
+	// Supports returns true if the shaper is able to process `font`.
+	// Shapers not supporting a font are skipped.
+	Supports(font *Font) bool
+
+	// Shape performs the shaping of `buffer`, whose `Props` are set.
+	// The input runes and their clusters are given by `GlyphInfo.Codepoint` and `GlyphInfo.Cluster`.
+	// When it returns, `buffer.Info` must hold the output glyphs in visual order, and
+	// `buffer.Pos` must have the same length, filled with the glyph positions.
+	// Custom shapers may delegate part of their work to the built-in
+	// shapers by calling `ShapeFull` on a temporary buffer.
+	Shape(font *Font, buffer *Buffer, features []Feature)
+}
+
+var (
+	customShapers     = map[string]Shaper{}
+	customShapersLock sync.Mutex
+)
+
+// RegisterShaper registers `shaper` so that it may be selected in `Buffer.ShapeFull`,
+// replacing a previous shaper with the same name.
+// It panics if the name is one of the built-in shapers.
+// It is safe for concurrent use.
+func RegisterShaper(shaper Shaper) {
+	name := shaper.Name()
+	switch name {
+	case ShaperGraphite, ShaperOpentype, ShaperFallback:
+		panic(fmt.Sprintf("harfbuzz: can't register shaper with built-in name %s", name))
+	}
+
+	customShapersLock.Lock()
+	defer customShapersLock.Unlock()
+	customShapers[name] = shaper
+}
+
+// lookupShaper returns the custom shaper with the given name, or nil
+func lookupShaper(name string) Shaper {
+	customShapersLock.Lock()
+	defer customShapersLock.Unlock()
+	return customShapers[name]
 }
 
 type shaperKind uint8
@@ -68,8 +171,9 @@ type shapePlan struct {
 	userFeatures []Feature
 }
 
+// `kind` is assumed to be supported by `font`
 func (plan *shapePlan) init(copy bool, font *Font, props SegmentProperties,
-	userFeatures []Feature, coords []float32) {
+	userFeatures []Feature, coords []float32, kind shaperKind) {
 	plan.props = props
 	if !copy {
 		plan.userFeatures = userFeatures
@@ -86,12 +190,12 @@ func (plan *shapePlan) init(copy bool, font *Font, props SegmentProperties,
 		}
 	}
 
-	// Choose shaper.
-	if font.gr != nil {
+	switch kind {
+	case skGraphite:
 		plan.shaper = (*shaperGraphite)(font.gr)
-	} else if font.otTables != nil {
+	case skOpentype:
 		plan.shaper = newShaperOpentype(font.otTables, coords)
-	} else {
+	default:
 		plan.shaper = shaperFallback{}
 	}
 }
@@ -116,17 +220,17 @@ func (plan shapePlan) equal(other shapePlan) bool {
 }
 
 // Constructs a shaping plan for a combination of @face, @userFeatures, @props,
-// plus the variation-space coordinates @coords.
+// plus the variation-space coordinates @coords, using the shaper @kind.
 // See newShapePlanCached for caching support.
 func newShapePlan(font *Font, props SegmentProperties,
-	userFeatures []Feature, coords []float32) *shapePlan {
+	userFeatures []Feature, coords []float32, kind shaperKind) *shapePlan {
 	if debugMode >= 1 {
 		fmt.Printf("NEW SHAPE PLAN: face:%p features:%v coords:%v\n", &font.face, userFeatures, coords)
 	}
 
 	var sp shapePlan
 
-	sp.init(true, font, props, userFeatures, coords)
+	sp.init(true, font, props, userFeatures, coords, kind)
 
 	if debugMode >= 1 {
 		fmt.Println("NEW SHAPE PLAN - compiling shaper plan")
@@ -156,12 +260,13 @@ var (
 )
 
 // creates (or returns) a cached shaping plan suitable for reuse, for a combination
-// of `face`, `userFeatures`, `props`, plus the variation-space coordinates `coords`.
+// of `face`, `userFeatures`, `props`, plus the variation-space coordinates `coords`,
+// using the shaper `kind`.
 func newShapePlanCached(font *Font, props SegmentProperties,
-	userFeatures []Feature, coords []float32) *shapePlan {
+	userFeatures []Feature, coords []float32, kind shaperKind) *shapePlan {
 
 	var key shapePlan
-	key.init(false, font, props, userFeatures, coords)
+	key.init(false, font, props, userFeatures, coords, kind)
 
 	planCacheLock.Lock()
 	defer planCacheLock.Unlock()
@@ -176,7 +281,7 @@ func newShapePlanCached(font *Font, props SegmentProperties,
 			return plan
 		}
 	}
-	plan := newShapePlan(font, props, userFeatures, coords)
+	plan := newShapePlan(font, props, userFeatures, coords, kind)
 
 	plans = append(plans, plan)
 	planCache[font.face] = plans
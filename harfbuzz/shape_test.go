@@ -14,6 +14,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"unicode"
 
 	testdata "github.com/benoitkugler/textlayout-testdata/harfbuzz"
 	"github.com/benoitkugler/textlayout/fonts"
@@ -452,8 +453,8 @@ func (so *shapeOptions) verifyBufferSafeToBreak(buffer, textBuffer *Buffer, font
 		}
 	}
 
-	diff := bufferDiff(reconstruction, buffer, ^fonts.GID(0), 0)
-	if diff != bufferDiffFlagEqual {
+	diff := reconstruction.Diff(buffer, DiffNoSpecialGlyph, 0)
+	if diff != DiffEqual {
 		/* Return the reconstructed result instead so it can be inspected. */
 		buffer.Info = nil
 		buffer.Pos = nil
@@ -882,6 +883,57 @@ func TestGraphite(t *testing.T) {
 	}
 }
 
+// upperShaper maps runes to their uppercase glyphs,
+// using the fallback shaper
+type upperShaper struct{}
+
+func (upperShaper) Name() string             { return "upper" }
+func (upperShaper) Supports(font *Font) bool { return font.otTables != nil }
+
+func (upperShaper) Shape(font *Font, buffer *Buffer, features []Feature) {
+	for i := range buffer.Info {
+		buffer.Info[i].codepoint = unicode.ToUpper(buffer.Info[i].Codepoint())
+	}
+	buffer.ShapeFull(font, features, []string{ShaperFallback})
+}
+
+func TestShapeFull(t *testing.T) {
+	font := NewFont(openFontFile("fonts/Simple-Graphite-Font.ttf"))
+	shape := func(shapers []string) (*Buffer, bool) {
+		buffer := NewBuffer()
+		buffer.AddRunes([]rune("abc"), 0, -1)
+		buffer.Props.Direction = LeftToRight
+		ok := buffer.ShapeFull(font, nil, shapers)
+		return buffer, ok
+	}
+
+	graphite, _ := shape(nil)
+	forced, ok := shape([]string{ShaperGraphite, ShaperFallback})
+	assert(t, ok)
+	assert(t, graphite.Diff(forced, DiffNoSpecialGlyph, 0) == DiffEqual)
+
+	// the graphite rules of the font are not applied
+	opentype, ok := shape([]string{ShaperOpentype})
+	assert(t, ok)
+	assert(t, graphite.Diff(opentype, DiffNoSpecialGlyph, 0)&DiffCodepointMismatch != 0)
+
+	fallback, ok := shape([]string{"unknown", ShaperFallback})
+	assert(t, ok)
+	assert(t, opentype.Diff(fallback, DiffNoSpecialGlyph, 0) == DiffEqual)
+
+	_, ok = shape([]string{"upper"})
+	assert(t, !ok) // not registered yet
+
+	RegisterShaper(upperShaper{})
+	upper, ok := shape([]string{"upper"})
+	assert(t, ok)
+	assert(t, graphite.Diff(upper, DiffNoSpecialGlyph, 0)&DiffCodepointMismatch != 0)
+	for i, info := range upper.Info {
+		g, _ := font.face.NominalGlyph(unicode.ToUpper(rune('a' + i)))
+		assertEqualInt(t, int(g), int(info.Glyph))
+	}
+}
+
 func TestExample(t *testing.T) {
 	// face := openFontFileTT("DejaVuSerif.ttf")
 	face := openFontFileTT("NotoSansArabic.ttf")
@@ -0,0 +1,288 @@
+// Package hyphenation implements Liang's hyphenation algorithm,
+// as used by TeX, with patterns and exceptions in the format
+// of the hyph-utf8 project (https://github.com/hyphenation/tex-hyphen).
+//
+// Pattern files are not bundled, and must be loaded by the user
+// (see `ParseTeX` and `ParsePatterns`).
+package hyphenation
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/benoitkugler/textlayout/language"
+)
+
+// Dictionary stores the compiled patterns and the exceptions of one language.
+type Dictionary struct {
+	// LeftMin and RightMin are the minimum number of letters
+	// before the first and after the last hyphenation point.
+	// Values lower than 1 are interpreted as 1.
+	LeftMin, RightMin int
+
+	patterns trie
+	// lower case word -> hyphenation points
+	exceptions map[string][]int
+}
+
+// ParsePatterns reads patterns and exceptions (which may be nil) given one
+// by line (or separated by spaces), as in the hyph-*.pat.txt and hyph-*.hyp.txt files.
+// The minimums of the returned dictionary are not set (see `Hyphenator.Register`).
+func ParsePatterns(patterns, exceptions io.Reader) (*Dictionary, error) {
+	var b dictionaryBuilder
+	b.init()
+	if err := b.readWords(patterns, b.addPattern); err != nil {
+		return nil, err
+	}
+	if exceptions != nil {
+		if err := b.readWords(exceptions, b.addException); err != nil {
+			return nil, err
+		}
+	}
+	return b.compile(), nil
+}
+
+// ParseTeX reads the patterns and exceptions defined by the
+// \patterns{...} and \hyphenation{...} commands of a TeX file,
+// as the hyph-*.tex files.
+// The minimums of the returned dictionary are not set (see `Hyphenator.Register`).
+func ParseTeX(r io.Reader) (*Dictionary, error) {
+	var b dictionaryBuilder
+	b.init()
+
+	var add func(string) error // nil outside of a command
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '%'); i != -1 { // comment
+			line = line[:i]
+		}
+		for _, word := range strings.Fields(line) {
+			for word != "" {
+				if add == nil {
+					switch {
+					case strings.HasPrefix(word, `\patterns{`):
+						add, word = b.addPattern, word[len(`\patterns{`):]
+					case strings.HasPrefix(word, `\hyphenation{`):
+						add, word = b.addException, word[len(`\hyphenation{`):]
+					default:
+						word = "" // ignore other commands
+					}
+					continue
+				}
+
+				token := word
+				word = ""
+				if i := strings.IndexByte(token, '}'); i != -1 { // end of the command
+					token, word = token[:i], token[i+1:]
+					if token != "" {
+						if err := add(token); err != nil {
+							return nil, err
+						}
+					}
+					add = nil
+					continue
+				}
+				if err := add(token); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if add != nil {
+		return nil, fmt.Errorf("unterminated TeX command")
+	}
+	return b.compile(), nil
+}
+
+type dictionaryBuilder struct {
+	root       builderNode
+	exceptions map[string][]int
+}
+
+func (b *dictionaryBuilder) init() {
+	b.root.children = map[rune]*builderNode{}
+	b.exceptions = map[string][]int{}
+}
+
+func (b *dictionaryBuilder) readWords(r io.Reader, add func(string) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '%'); i != -1 { // comment
+			line = line[:i]
+		}
+		for _, word := range strings.Fields(line) {
+			if err := add(word); err != nil {
+				return err
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// addPattern parses a pattern like .hy3ph
+func (b *dictionaryBuilder) addPattern(pattern string) error {
+	var (
+		letters []rune
+		values  = []uint8{0}
+	)
+	for _, r := range pattern {
+		if '0' <= r && r <= '9' {
+			values[len(values)-1] = uint8(r - '0')
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		values = append(values, 0)
+	}
+	if len(letters) == 0 {
+		return fmt.Errorf("invalid hyphenation pattern %s", pattern)
+	}
+	b.root.insert(letters, values)
+	return nil
+}
+
+// addException parses an exception like as-so-ciate
+func (b *dictionaryBuilder) addException(exception string) error {
+	var (
+		letters []rune
+		points  []int
+	)
+	for _, r := range exception {
+		if r == '-' {
+			points = append(points, len(letters))
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+	}
+	if len(letters) == 0 {
+		return fmt.Errorf("invalid hyphenation exception %s", exception)
+	}
+	b.exceptions[string(letters)] = points
+	return nil
+}
+
+func (b *dictionaryBuilder) compile() *Dictionary {
+	return &Dictionary{patterns: b.root.compile(), exceptions: b.exceptions}
+}
+
+// Hyphenate returns the hyphenation points of `word`, as indices in `word`:
+// a point `i` means that the word may be broken between word[:i] and word[i:].
+// `word` should only contain letters (without surrounding punctuation).
+func (d *Dictionary) Hyphenate(word []rune) []int {
+	lower := make([]rune, len(word)+2)
+	lower[0], lower[len(lower)-1] = '.', '.'
+	for i, r := range word {
+		lower[i+1] = unicode.ToLower(r)
+	}
+
+	leftMin, rightMin := d.LeftMin, d.RightMin
+	if leftMin < 1 {
+		leftMin = 1
+	}
+	if rightMin < 1 {
+		rightMin = 1
+	}
+	isAllowed := func(i int) bool { return i >= leftMin && len(word)-i >= rightMin }
+
+	var out []int
+	if points, ok := d.exceptions[string(lower[1:len(lower)-1])]; ok {
+		for _, i := range points {
+			if isAllowed(i) {
+				out = append(out, i)
+			}
+		}
+		return out
+	}
+
+	// values[i] is the value before lower[i]
+	values := make([]uint8, len(lower)+1)
+	d.patterns.apply(lower, values)
+	for i := 1; i < len(word); i++ {
+		if values[i+1]%2 == 1 && isAllowed(i) {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// minimums stores the left and right hyphen minimums used by the hyph-utf8 project
+var minimums = map[language.Language][2]int{
+	"en":    {2, 3},
+	"en-gb": {2, 3},
+	"en-us": {2, 3},
+	"fr":    {2, 2},
+	"de":    {2, 2},
+	"it":    {2, 2},
+	"es":    {2, 2},
+	"pt":    {2, 3},
+	"nl":    {2, 2},
+	"pl":    {2, 2},
+	"ru":    {2, 2},
+	"sv":    {2, 2},
+	"da":    {2, 2},
+	"nb":    {2, 2},
+	"nn":    {2, 2},
+	"fi":    {2, 2},
+	"cs":    {2, 3},
+	"sk":    {2, 3},
+	"la":    {2, 2},
+}
+
+// Hyphenator selects the dictionary to use for a language.
+type Hyphenator struct {
+	dictionaries map[language.Language]*Dictionary
+}
+
+// NewHyphenator returns an empty hyphenator.
+func NewHyphenator() *Hyphenator {
+	return &Hyphenator{dictionaries: map[language.Language]*Dictionary{}}
+}
+
+// Register sets the dictionary used for `lang`, and for the languages
+// derived from it, like en-us for en.
+// If the minimums of `dict` are not set, the usual values for `lang` are used,
+// or 2 if not known.
+func (h *Hyphenator) Register(lang language.Language, dict *Dictionary) {
+	defaults := [2]int{2, 2}
+	for _, l := range lang.SimpleInheritance() {
+		if m, ok := minimums[l]; ok {
+			defaults = m
+			break
+		}
+	}
+	if dict.LeftMin == 0 {
+		dict.LeftMin = defaults[0]
+	}
+	if dict.RightMin == 0 {
+		dict.RightMin = defaults[1]
+	}
+	h.dictionaries[lang] = dict
+}
+
+// Dictionary returns the dictionary registered for `lang`, or for its
+// closest parent language, or nil if not found.
+func (h *Hyphenator) Dictionary(lang language.Language) *Dictionary {
+	for _, l := range lang.SimpleInheritance() {
+		if dict, ok := h.dictionaries[l]; ok {
+			return dict
+		}
+	}
+	return nil
+}
+
+// Hyphenate returns the hyphenation points of `word` (see `Dictionary.Hyphenate`),
+// or nil if no dictionary is registered for `lang`.
+func (h *Hyphenator) Hyphenate(word []rune, lang language.Language) []int {
+	dict := h.Dictionary(lang)
+	if dict == nil {
+		return nil
+	}
+	return dict.Hyphenate(word)
+}
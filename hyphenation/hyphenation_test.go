@@ -0,0 +1,140 @@
+package hyphenation
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/language"
+)
+
+func loadTeX(t *testing.T, filename string) *Dictionary {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dict, err := ParseTeX(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dict
+}
+
+func loadPatterns(t *testing.T, filename string) *Dictionary {
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	dict, err := ParsePatterns(f, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dict
+}
+
+func newTestHyphenator(t *testing.T) *Hyphenator {
+	h := NewHyphenator()
+	h.Register("en-us", loadTeX(t, "testdata/hyph-en-us.tex"))
+	h.Register("fr", loadPatterns(t, "testdata/hyph-fr.pat.txt"))
+	h.Register("de", loadTeX(t, "testdata/hyph-de-1996.tex"))
+	return h
+}
+
+// hyphenated inserts hyphens at the points returned by `h`
+func hyphenated(h *Hyphenator, word string, lang language.Language) string {
+	runes := []rune(word)
+	var (
+		out  []string
+		last int
+	)
+	for _, i := range h.Hyphenate(runes, lang) {
+		out = append(out, string(runes[last:i]))
+		last = i
+	}
+	out = append(out, string(runes[last:]))
+	return strings.Join(out, "-")
+}
+
+func TestHyphenate(t *testing.T) {
+	h := newTestHyphenator(t)
+	for _, test := range []struct {
+		lang     string
+		word     string
+		expected string
+	}{
+		{"en-US", "hyphenation", "hy-phen-ation"},
+		{"en-us", "Hyphenation", "Hy-phen-ation"},
+		{"en-us", "supercalifragilisticexpialidocious", "su-per-cal-ifrag-ilis-tic-ex-pi-ali-do-cious"},
+		{"en-us", "Concatenation", "Con-cate-na-tion"},
+		{"en-us", "typesetting", "type-set-ting"},
+		{"en-us", "algorithm", "al-go-rithm"},
+		{"en-us", "computer", "com-puter"},
+		// exceptions
+		{"en-us", "associate", "as-so-ciate"},
+		{"en-us", "Project", "Project"},
+		{"en-us", "table", "ta-ble"},
+		{"fr", "ordinateur", "or-di-na-teur"},
+		{"fr", "problème", "pro-blème"},
+		{"fr-CA", "hyphénation", "hy-phé-na-tion"},
+		{"fr", "chocolat", "cho-co-lat"},
+		{"fr", "bibliothèque", "bi-blio-thèque"},
+		{"fr", "typographie", "ty-po-gra-phie"},
+		{"fr", "dictionnaire", "dic-tion-naire"},
+		{"de", "Silbentrennung", "Sil-ben-tren-nung"},
+		{"de-CH", "Zucker", "Zu-cker"},
+		{"de", "Flussschifffahrt", "Fluss-schiff-fahrt"},
+		{"de", "Wörterbuch", "Wör-ter-buch"},
+		{"de", "Donaudampfschifffahrt", "Do-nau-dampf-schiff-fahrt"},
+		{"de", "Rechtschreibung", "Recht-schrei-bung"},
+		// no dictionary
+		{"it", "parola", "parola"},
+	} {
+		if got := hyphenated(h, test.word, language.NewLanguage(test.lang)); got != test.expected {
+			t.Errorf("for %s (%s), expected %s, got %s", test.word, test.lang, test.expected, got)
+		}
+	}
+}
+
+func TestMinimums(t *testing.T) {
+	h := newTestHyphenator(t)
+	en := h.Dictionary("en-us")
+	if en.LeftMin != 2 || en.RightMin != 3 {
+		t.Fatalf("unexpected minimums %d %d", en.LeftMin, en.RightMin)
+	}
+	en.LeftMin, en.RightMin = 1, 1
+	if got := en.Hyphenate([]rune("ation")); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("unexpected points %v", got)
+	}
+	en.LeftMin, en.RightMin = 3, 5
+	if got := en.Hyphenate([]rune("hyphenation")); !reflect.DeepEqual(got, []int{6}) {
+		t.Fatalf("unexpected points %v", got)
+	}
+
+	if h.Dictionary("de-ch").RightMin != 2 {
+		t.Fatal("unexpected right minimum")
+	}
+}
+
+func TestParseTeX(t *testing.T) {
+	dict, err := ParseTeX(strings.NewReader(`\message{patterns} \patterns{1ba 1be} % \patterns{1bi}
+	\hyphenation{tab-le}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := dict.Hyphenate([]rune("abeba")); !reflect.DeepEqual(got, []int{1, 3}) {
+		t.Fatalf("unexpected points %v", got)
+	}
+	if got := dict.Hyphenate([]rune("babi")); !reflect.DeepEqual(got, []int(nil)) {
+		t.Fatalf("unexpected points %v", got)
+	}
+	if got := dict.Hyphenate([]rune("TABLE")); !reflect.DeepEqual(got, []int{3}) {
+		t.Fatalf("unexpected points %v", got)
+	}
+
+	if _, err = ParseTeX(strings.NewReader(`\patterns{1ba`)); err == nil {
+		t.Fatal("expected error for unterminated command")
+	}
+}
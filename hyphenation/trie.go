@@ -0,0 +1,107 @@
+package hyphenation
+
+import "sort"
+
+// trie is a compact, read-only prefix tree storing the patterns:
+// the children of all the nodes are stored in a single sorted slice,
+// and so are the pattern values.
+type trie struct {
+	nodes []trieNode
+	edges []trieEdge
+	// values stores the inter-letter values of the patterns:
+	// a pattern with n letters has n+1 values
+	values []uint8
+}
+
+type trieNode struct {
+	firstEdge, numEdges    uint32 // children, in trie.edges
+	valuesStart, valuesEnd uint32 // pattern ending at this node, in trie.values
+}
+
+type trieEdge struct {
+	letter rune
+	node   uint32
+}
+
+// builderNode is used to build the trie
+type builderNode struct {
+	children map[rune]*builderNode
+	values   []uint8
+}
+
+func (n *builderNode) insert(letters []rune, values []uint8) {
+	for _, r := range letters {
+		child := n.children[r]
+		if child == nil {
+			child = &builderNode{children: map[rune]*builderNode{}}
+			n.children[r] = child
+		}
+		n = child
+	}
+	n.values = values
+}
+
+// compile flattens the tree, in breadth first order
+func (root *builderNode) compile() trie {
+	var out trie
+	queue := []*builderNode{root}
+	out.nodes = append(out.nodes, trieNode{})
+	for i := 0; i < len(queue); i++ {
+		n := queue[i]
+		node := &out.nodes[i]
+		node.valuesStart = uint32(len(out.values))
+		out.values = append(out.values, n.values...)
+		node.valuesEnd = uint32(len(out.values))
+
+		letters := make([]rune, 0, len(n.children))
+		for r := range n.children {
+			letters = append(letters, r)
+		}
+		sort.Slice(letters, func(i, j int) bool { return letters[i] < letters[j] })
+
+		node.firstEdge, node.numEdges = uint32(len(out.edges)), uint32(len(letters))
+		for _, r := range letters {
+			out.edges = append(out.edges, trieEdge{letter: r, node: uint32(len(queue))})
+			queue = append(queue, n.children[r])
+		}
+		out.nodes = append(out.nodes, make([]trieNode, len(letters))...)
+	}
+	return out
+}
+
+// child returns the child of `node` for `letter`, or 0 if not found
+func (t *trie) child(node uint32, letter rune) uint32 {
+	n := t.nodes[node]
+	edges := t.edges[n.firstEdge : n.firstEdge+n.numEdges]
+	// binary search
+	for i, j := 0, len(edges); i < j; {
+		h := i + (j-i)/2
+		if edges[h].letter < letter {
+			i = h + 1
+		} else if edges[h].letter > letter {
+			j = h
+		} else {
+			return edges[h].node
+		}
+	}
+	return 0
+}
+
+// apply merges the values of the patterns matching `word` into `values`,
+// which has length len(word)+1, keeping the maximum values.
+func (t *trie) apply(word []rune, values []uint8) {
+	for start := range word {
+		node := uint32(0)
+		for _, r := range word[start:] {
+			if node = t.child(node, r); node == 0 {
+				break
+			}
+			n := t.nodes[node]
+			for k, v := range t.values[n.valuesStart:n.valuesEnd] {
+				if v > values[start+k] {
+					values[start+k] = v
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package itemizer
+
+import (
+	"unicode"
+
+	"github.com/benoitkugler/textlayout/fonts"
+	"github.com/benoitkugler/textlayout/segmenter"
+	"github.com/benoitkugler/textlayout/unicodedata"
+)
+
+const (
+	textPresentationSelector  = 0xFE0E
+	emojiPresentationSelector = 0xFE0F
+)
+
+// FaceRun is a part of a text assigned to one face.
+type FaceRun struct {
+	Start, End int // indices of the run in the text
+	Face       fonts.Face
+}
+
+// variationFace is implemented by faces supporting Unicode
+// variation sequences, like *truetype.Font
+type variationFace interface {
+	VariationGlyph(ch, varSelector rune) (fonts.GID, bool)
+}
+
+// SplitByFace splits text[start:end] into runs assigned to the first face of `faces`
+// supporting all the runes of each grapheme cluster, so that clusters are never split
+// across faces. Variation selectors and joiners are not required to be supported,
+// since the shaper hides them. However, a face providing a glyph for a
+// whole variation sequence is preferred.
+//
+// The emoji presentation of a cluster is resolved from its presentation selector (U+FE0E or U+FE0F)
+// if any, or else from the Emoji_Presentation property of its first rune.
+// For clusters with emoji presentation, the first face using a color (bitmap or SVG) glyph is preferred;
+// for emoji clusters with text presentation, the first face using an outline is preferred.
+//
+// Clusters not supported by any face are assigned the first face supporting
+// their first rune, or the first face.
+// `faces` must not be empty.
+func SplitByFace(text []rune, start, end int, faces []fonts.Face) []FaceRun {
+	var runs []FaceRun
+	it := segmenter.NewGraphemeIterator(text[start:end])
+	for it.Next() {
+		cluster := it.Segment()
+		face := selectFace(cluster.Text, faces)
+		clusterStart := start + cluster.Offset
+		clusterEnd := clusterStart + len(cluster.Text)
+		if L := len(runs); L != 0 && runs[L-1].Face == face {
+			runs[L-1].End = clusterEnd
+			continue
+		}
+		runs = append(runs, FaceRun{Start: clusterStart, End: clusterEnd, Face: face})
+	}
+	return runs
+}
+
+type presentation uint8
+
+const (
+	noPresentation presentation = iota // not an emoji
+	textPresentation
+	emojiPresentation
+)
+
+// selectFace returns the face to use for the grapheme cluster `cluster`.
+func selectFace(cluster []rune, faces []fonts.Face) fonts.Face {
+	base := cluster[0]
+	var selector rune
+	for _, r := range cluster[1:] {
+		if unicode.Is(unicode.Variation_Selector, r) {
+			selector = r
+			break
+		}
+	}
+
+	pres := noPresentation
+	switch {
+	case selector == emojiPresentationSelector:
+		pres = emojiPresentation
+	case selector == textPresentationSelector:
+		pres = textPresentation
+	case unicode.Is(unicodedata.Emoji_Presentation, base):
+		pres = emojiPresentation
+	case unicode.Is(unicodedata.Emoji, base) && base > 0x7F: // ASCII digits and signs are not presented as emojis
+		pres = textPresentation
+	}
+
+	var (
+		covering         fonts.Face // first face covering the cluster
+		withPresentation fonts.Face // first face covering the cluster with the requested presentation
+	)
+	for _, face := range faces {
+		if !coversCluster(face, cluster) {
+			continue
+		}
+		if selector != 0 {
+			if vf, ok := face.(variationFace); ok {
+				if _, ok := vf.VariationGlyph(base, selector); ok {
+					return face
+				}
+			}
+		}
+		if covering == nil {
+			covering = face
+		}
+		if withPresentation == nil && pres != noPresentation && hasPresentation(face, base, pres) {
+			withPresentation = face
+		}
+	}
+
+	if withPresentation != nil {
+		return withPresentation
+	}
+	if covering != nil {
+		return covering
+	}
+	for _, face := range faces {
+		if _, ok := face.NominalGlyph(base); ok {
+			return face
+		}
+	}
+	return faces[0]
+}
+
+// coversCluster returns true if `face` has a glyph for every
+// rune of `cluster`, except the ones hidden by the shaper
+func coversCluster(face fonts.Face, cluster []rune) bool {
+	for _, r := range cluster {
+		if unicode.In(r, unicode.Variation_Selector, unicode.Join_Control) {
+			continue
+		}
+		if _, ok := face.NominalGlyph(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPresentation returns true if the glyph used for `r` is drawn
+// according to `pres`
+func hasPresentation(face fonts.Face, r rune, pres presentation) bool {
+	gid, _ := face.NominalGlyph(r)
+	switch face.GlyphData(gid, 0, 0).(type) {
+	case fonts.GlyphBitmap, fonts.GlyphSVG:
+		return pres == emojiPresentation
+	case fonts.GlyphOutline:
+		return pres == textPresentation
+	default:
+		return false
+	}
+}
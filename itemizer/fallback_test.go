@@ -0,0 +1,91 @@
+package itemizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/fonts"
+)
+
+// testFace supports a fixed set of runes, drawn as outlines,
+// or bitmaps if `color` is true
+type testFace struct {
+	fonts.Face
+
+	name       string
+	runes      string
+	variations map[[2]rune]bool
+	color      bool
+}
+
+func (f *testFace) NominalGlyph(r rune) (fonts.GID, bool) {
+	for i, c := range []rune(f.runes) {
+		if c == r {
+			return fonts.GID(i + 1), true
+		}
+	}
+	return 0, false
+}
+
+func (f *testFace) VariationGlyph(r, selector rune) (fonts.GID, bool) {
+	if f.variations[[2]rune{r, selector}] {
+		return f.NominalGlyph(r)
+	}
+	return 0, false
+}
+
+func (f *testFace) GlyphData(gid fonts.GID, _, _ uint16) fonts.GlyphData {
+	if f.color {
+		return fonts.GlyphBitmap{}
+	}
+	return fonts.GlyphOutline{}
+}
+
+func TestSplitByFace(t *testing.T) {
+	latin := &testFace{name: "latin", runes: "abcde\u0301 ☺"}
+	symbols := &testFace{name: "symbols", runes: "❤☺", variations: map[[2]rune]bool{{0x2764, 0xFE0E}: true}}
+	emoji := &testFace{name: "emoji", runes: "❤☺\U0001F600\U0001F468\U0001F469‍", color: true}
+	faces := []fonts.Face{latin, symbols, emoji}
+
+	type run struct {
+		start, end int
+		face       string
+	}
+	for _, test := range []struct {
+		text     string
+		expected []run
+	}{
+		{"", nil},
+		{"abc", []run{{0, 3, "latin"}}},
+		// the combining mark is kept with its base
+		{"ae\u0301b", []run{{0, 4, "latin"}}},
+		// unsupported runes are assigned to the first face
+		{"a一b", []run{{0, 3, "latin"}}},
+		{"a\U0001F600 b", []run{{0, 1, "latin"}, {1, 2, "emoji"}, {2, 4, "latin"}}},
+		// ZWJ sequences are kept together
+		{"a\U0001F468‍\U0001F469", []run{{0, 1, "latin"}, {1, 4, "emoji"}}},
+		// text presentation by default, supported by the first face
+		{"a☺", []run{{0, 2, "latin"}}},
+		// emoji presentation selector
+		{"a☺️", []run{{0, 1, "latin"}, {1, 3, "emoji"}}},
+		// variation sequence explicitly supported
+		{"❤︎❤️", []run{{0, 2, "symbols"}, {2, 4, "emoji"}}},
+	} {
+		text := []rune(test.text)
+		var got []run
+		for _, r := range SplitByFace(text, 0, len(text), faces) {
+			got = append(got, run{r.Start, r.End, r.Face.(*testFace).name})
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("for %q, expected %v, got %v", test.text, test.expected, got)
+		}
+	}
+
+	// sub runs use indices in the whole text
+	text := []rune("ab\U0001F600c")
+	got := SplitByFace(text, 1, 3, faces)
+	expected := []FaceRun{{Start: 1, End: 2, Face: latin}, {Start: 2, End: 3, Face: emoji}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,185 @@
+// Package itemizer splits a paragraph into runs of text which may
+// be shaped independently, that is runs with a common script,
+// direction and language, ready to be used as input for `harfbuzz.Buffer.Shape`.
+//
+// Runs may be further split according to the fonts supporting
+// their content (see `SplitByFace`).
+package itemizer
+
+import (
+	"github.com/benoitkugler/textlayout/bidi"
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textlayout/language"
+	"github.com/benoitkugler/textlayout/unicodedata"
+	xbidi "golang.org/x/text/unicode/bidi"
+)
+
+// Run is a part of a paragraph text with uniform
+// shaping properties.
+type Run struct {
+	Start, End int // indices of the run in the paragraph text
+
+	// Props is suitable to be used as `harfbuzz.Buffer.Props`.
+	// Its `Direction` is derived from `Level`, and is thus
+	// always horizontal.
+	Props harfbuzz.SegmentProperties
+
+	// Level is the embedding level of the run, before applying
+	// the line level rules (see `bidi.Paragraph.Runs`).
+	Level bidi.Level
+}
+
+// LanguageRange sets the language of the runes text[Start:End].
+type LanguageRange struct {
+	Start, End int
+	Language   language.Language
+}
+
+// Itemize splits `paragraph` into runs with the same script, embedding level and language,
+// returned in logical order.
+//
+// The language of the text is `lang`, except for the `languages` ranges, which are applied in order
+// (so that the last range containing a rune wins).
+//
+// The script of characters shared by several scripts, like spaces or punctuation marks,
+// is resolved from their neighbours, according to their Script_Extensions property,
+// and paired brackets are given the same script.
+// A paragraph containing only such characters is assigned the Common script.
+func Itemize(paragraph *bidi.Paragraph, lang language.Language, languages []LanguageRange) []Run {
+	text := paragraph.Text()
+	scripts := resolveScripts(text)
+	levels := paragraph.Levels()
+
+	langs := make([]language.Language, len(text))
+	for i := range langs {
+		langs[i] = lang
+	}
+	for _, ra := range languages {
+		for i := ra.Start; i < ra.End && i < len(text); i++ {
+			langs[i] = ra.Language
+		}
+	}
+
+	var runs []Run
+	for i := range text {
+		props := harfbuzz.SegmentProperties{
+			Script:    scripts[i],
+			Language:  langs[i],
+			Direction: levels[i].Direction(),
+		}
+		if L := len(runs); L != 0 && runs[L-1].Props == props && runs[L-1].Level == levels[i] {
+			runs[L-1].End = i + 1
+			continue
+		}
+		runs = append(runs, Run{Start: i, End: i + 1, Props: props, Level: levels[i]})
+	}
+	return runs
+}
+
+// maximum depth of the bracket stack, as in BD16
+const maxBracketDepth = 63
+
+// scriptRun is a run of text sharing at least one script
+type scriptRun struct {
+	start int
+	// candidates are the possible scripts for the run,
+	// ordered by preference; nil means any script
+	candidates []language.Script
+}
+
+type openBracket struct {
+	id  rune // the opening bracket
+	run int  // index of the script run containing the bracket
+}
+
+// resolveScripts returns the script of each rune in `text`.
+func resolveScripts(text []rune) []language.Script {
+	var (
+		runs  []scriptRun
+		stack []openBracket
+	)
+	for i, r := range text {
+		candidates := scriptCandidates(r)
+
+		props, _ := xbidi.LookupRune(r)
+		isOpening := props.IsBracket() && props.IsOpeningBracket()
+		if props.IsBracket() && !props.IsOpeningBracket() {
+			// use the script of the matching opening bracket, if any
+			opening, _ := unicodedata.LookupMirrorChar(r)
+			for j := len(stack) - 1; j >= 0; j-- {
+				if stack[j].id == opening {
+					candidates = runs[stack[j].run].candidates
+					stack = stack[:j]
+					break
+				}
+			}
+		}
+
+		if len(runs) == 0 {
+			runs = append(runs, scriptRun{start: i, candidates: candidates})
+		} else if current := &runs[len(runs)-1]; current.candidates == nil {
+			current.candidates = candidates
+		} else if candidates != nil {
+			if common := intersect(current.candidates, candidates); len(common) != 0 {
+				current.candidates = common
+			} else {
+				runs = append(runs, scriptRun{start: i, candidates: candidates})
+			}
+		}
+
+		if isOpening && len(stack) < maxBracketDepth {
+			stack = append(stack, openBracket{id: r, run: len(runs) - 1})
+		}
+	}
+
+	out := make([]language.Script, len(text))
+	for i, run := range runs {
+		end := len(text)
+		if i+1 < len(runs) {
+			end = runs[i+1].start
+		}
+		script := language.Common
+		if len(run.candidates) != 0 {
+			script = run.candidates[0]
+		}
+		for j := run.start; j < end; j++ {
+			out[j] = script
+		}
+	}
+	return out
+}
+
+// scriptCandidates returns the possible scripts of `r`, with its
+// Script property first, or nil if `r` may be used with any script.
+func scriptCandidates(r rune) []language.Script {
+	script := language.LookupScript(r)
+	extensions := language.LookupScriptExtensions(r)
+	if len(extensions) == 1 && !extensions[0].IsRealScript() {
+		return nil
+	}
+	if !script.IsRealScript() {
+		return extensions
+	}
+	out := []language.Script{script}
+	for _, s := range extensions {
+		if s != script {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// intersect returns the scripts of `current` also in `other`, keeping
+// the order of `current`
+func intersect(current, other []language.Script) []language.Script {
+	var out []language.Script
+	for _, s := range current {
+		for _, o := range other {
+			if s == o {
+				out = append(out, s)
+				break
+			}
+		}
+	}
+	return out
+}
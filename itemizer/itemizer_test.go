@@ -0,0 +1,75 @@
+package itemizer
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/benoitkugler/textlayout/bidi"
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textlayout/language"
+)
+
+func TestItemize(t *testing.T) {
+	en, fr := language.NewLanguage("en"), language.NewLanguage("fr")
+	ltr, rtl := harfbuzz.LeftToRight, harfbuzz.RightToLeft
+
+	type expectedRun struct {
+		start, end int
+		script     language.Script
+		dir        harfbuzz.Direction
+		lang       language.Language
+	}
+	for _, test := range []struct {
+		text      string
+		dir       bidi.Direction
+		languages []LanguageRange
+		expected  []expectedRun
+	}{
+		{"", bidi.Auto, nil, nil},
+		{"123 abc.", bidi.Auto, nil, []expectedRun{{0, 8, language.Latin, ltr, en}}},
+		{"...", bidi.Auto, nil, []expectedRun{{0, 3, language.Common, ltr, en}}},
+		{"abc (אבג) def", bidi.Auto, nil, []expectedRun{
+			{0, 5, language.Latin, ltr, en},
+			{5, 8, language.Hebrew, rtl, en},
+			{8, 13, language.Latin, ltr, en},
+		}},
+		{"(אבג) abc", bidi.Auto, nil, []expectedRun{
+			{0, 6, language.Hebrew, rtl, en},
+			{6, 9, language.Latin, ltr, en},
+		}},
+		{"日本語、ひらがな。カタカナ", bidi.Auto, nil, []expectedRun{
+			{0, 4, language.Han, ltr, en},
+			{4, 9, language.Hiragana, ltr, en},
+			{9, 13, language.Katakana, ltr, en},
+		}},
+		{"سلام 123", bidi.Auto, nil, []expectedRun{
+			{0, 5, language.Arabic, rtl, en},
+			{5, 8, language.Arabic, ltr, en},
+		}},
+		{"hello world", bidi.Auto, []LanguageRange{{6, 11, fr}}, []expectedRun{
+			{0, 6, language.Latin, ltr, en},
+			{6, 11, language.Latin, ltr, fr},
+		}},
+	} {
+		text := []rune(test.text)
+		runs := Itemize(bidi.NewParagraph(text, test.dir), en, test.languages)
+		var got []expectedRun
+		for _, run := range runs {
+			got = append(got, expectedRun{run.Start, run.End, run.Props.Script, run.Props.Direction, run.Props.Language})
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("for %q, expected %v, got %v", test.text, test.expected, got)
+		}
+	}
+}
+
+func TestResolveScripts(t *testing.T) {
+	// the tatweel is shared by Arabic and Syriac (among others),
+	// and U+30FC by Hiragana and Katakana
+	text := []rune("ܐـܐ カー")
+	got := resolveScripts(text)
+	expected := []language.Script{language.Syriac, language.Syriac, language.Syriac, language.Syriac, language.Katakana, language.Katakana}
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+}
@@ -37,6 +37,28 @@ func LookupScript(r rune) Script {
 	return Unknown
 }
 
+// LookupScriptExtensions returns the Script_Extensions property of `r`,
+// that is the scripts with which `r` is commonly used, as defined by
+// Unicode Standard Annex #24. For most runes, it is the same as the script returned
+// by `LookupScript`, but for some Common or Inherited characters (like punctuation
+// marks shared by a few scripts), it provides a more precise information.
+// The returned slice must not be modified.
+func LookupScriptExtensions(r rune) []Script {
+	// binary search
+	for i, j := 0, len(scriptExtensionsRanges); i < j; {
+		h := i + (j-i)/2
+		entry := &scriptExtensionsRanges[h]
+		if r < entry.start {
+			j = h
+		} else if entry.end < r {
+			i = h + 1
+		} else {
+			return entry.scripts
+		}
+	}
+	return []Script{LookupScript(r)}
+}
+
 func (s Script) String() string {
 	for k, v := range scriptToTag {
 		if v == s {
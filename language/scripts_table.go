@@ -1243,3 +1243,130 @@ var scriptRanges = [...]scriptItem{
 	{start: 0xe0020, end: 0xe007f, script: 0x7a797979},
 	{start: 0xe0100, end: 0xe01ef, script: 0x7a696e68},
 }
+
+type scriptExtensionsItem struct {
+	start, end rune
+	scripts    []Script
+}
+
+// sorted ranges of the runes whose Script_Extensions property
+// is not the same as their Script property
+var scriptExtensionsRanges = [...]scriptExtensionsItem{
+	{start: 0x342, end: 0x342, scripts: []Script{0x6772656b}},
+	{start: 0x345, end: 0x345, scripts: []Script{0x6772656b}},
+	{start: 0x363, end: 0x36f, scripts: []Script{0x6c61746e}},
+	{start: 0x483, end: 0x483, scripts: []Script{0x6379726c, 0x7065726d}},
+	{start: 0x484, end: 0x484, scripts: []Script{0x6379726c, 0x676c6167}},
+	{start: 0x485, end: 0x486, scripts: []Script{0x6379726c, 0x6c61746e}},
+	{start: 0x487, end: 0x487, scripts: []Script{0x6379726c, 0x676c6167}},
+	{start: 0x60c, end: 0x60c, scripts: []Script{0x61726162, 0x726f6867, 0x73797263, 0x74686161, 0x79657a69}},
+	{start: 0x61b, end: 0x61b, scripts: []Script{0x61726162, 0x726f6867, 0x73797263, 0x74686161, 0x79657a69}},
+	{start: 0x61c, end: 0x61c, scripts: []Script{0x61726162, 0x73797263, 0x74686161}},
+	{start: 0x61f, end: 0x61f, scripts: []Script{0x61726162, 0x726f6867, 0x73797263, 0x74686161, 0x79657a69}},
+	{start: 0x640, end: 0x640, scripts: []Script{0x61646c6d, 0x61726162, 0x6d616e64, 0x6d616e69, 0x70686c70, 0x726f6867, 0x736f6764, 0x73797263}},
+	{start: 0x64b, end: 0x655, scripts: []Script{0x61726162, 0x73797263}},
+	{start: 0x660, end: 0x669, scripts: []Script{0x61726162, 0x74686161, 0x79657a69}},
+	{start: 0x670, end: 0x670, scripts: []Script{0x61726162, 0x73797263}},
+	{start: 0x6d4, end: 0x6d4, scripts: []Script{0x61726162, 0x726f6867}},
+	{start: 0x951, end: 0x951, scripts: []Script{0x62656e67, 0x64657661, 0x6772616e, 0x67756a72, 0x67757275, 0x6b6e6461, 0x6c61746e, 0x6d6c796d, 0x6f727961, 0x73687264, 0x74616d6c, 0x74656c75, 0x74697268}},
+	{start: 0x952, end: 0x952, scripts: []Script{0x62656e67, 0x64657661, 0x6772616e, 0x67756a72, 0x67757275, 0x6b6e6461, 0x6c61746e, 0x6d6c796d, 0x6f727961, 0x74616d6c, 0x74656c75, 0x74697268}},
+	{start: 0x964, end: 0x964, scripts: []Script{0x62656e67, 0x64657661, 0x646f6772, 0x676f6e67, 0x676f6e6d, 0x6772616e, 0x67756a72, 0x67757275, 0x6b6e6461, 0x6d61686a, 0x6d6c796d, 0x6e616e64, 0x6f727961, 0x73696e64, 0x73696e68, 0x73796c6f, 0x74616b72, 0x74616d6c, 0x74656c75, 0x74697268}},
+	{start: 0x965, end: 0x965, scripts: []Script{0x62656e67, 0x64657661, 0x646f6772, 0x676f6e67, 0x676f6e6d, 0x6772616e, 0x67756a72, 0x67757275, 0x6b6e6461, 0x6c696d62, 0x6d61686a, 0x6d6c796d, 0x6e616e64, 0x6f727961, 0x73696e64, 0x73696e68, 0x73796c6f, 0x74616b72, 0x74616d6c, 0x74656c75, 0x74697268}},
+	{start: 0x966, end: 0x96f, scripts: []Script{0x64657661, 0x646f6772, 0x6b746869, 0x6d61686a}},
+	{start: 0x9e6, end: 0x9ef, scripts: []Script{0x62656e67, 0x63616b6d, 0x73796c6f}},
+	{start: 0xa66, end: 0xa6f, scripts: []Script{0x67757275, 0x6d756c74}},
+	{start: 0xae6, end: 0xaef, scripts: []Script{0x67756a72, 0x6b686f6a}},
+	{start: 0xbe6, end: 0xbf3, scripts: []Script{0x6772616e, 0x74616d6c}},
+	{start: 0xce6, end: 0xcef, scripts: []Script{0x6b6e6461, 0x6e616e64}},
+	{start: 0x1040, end: 0x1049, scripts: []Script{0x63616b6d, 0x6d796d72, 0x74616c65}},
+	{start: 0x10fb, end: 0x10fb, scripts: []Script{0x67656f72, 0x6c61746e}},
+	{start: 0x1735, end: 0x1736, scripts: []Script{0x62756864, 0x68616e6f, 0x74616762, 0x74676c67}},
+	{start: 0x1802, end: 0x1803, scripts: []Script{0x6d6f6e67, 0x70686167}},
+	{start: 0x1805, end: 0x1805, scripts: []Script{0x6d6f6e67, 0x70686167}},
+	{start: 0x1cd0, end: 0x1cd0, scripts: []Script{0x62656e67, 0x64657661, 0x6772616e, 0x6b6e6461}},
+	{start: 0x1cd1, end: 0x1cd1, scripts: []Script{0x64657661}},
+	{start: 0x1cd2, end: 0x1cd2, scripts: []Script{0x62656e67, 0x64657661, 0x6772616e, 0x6b6e6461}},
+	{start: 0x1cd3, end: 0x1cd3, scripts: []Script{0x64657661, 0x6772616e}},
+	{start: 0x1cd4, end: 0x1cd4, scripts: []Script{0x64657661}},
+	{start: 0x1cd5, end: 0x1cd6, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0x1cd7, end: 0x1cd7, scripts: []Script{0x64657661, 0x73687264}},
+	{start: 0x1cd8, end: 0x1cd8, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0x1cd9, end: 0x1cd9, scripts: []Script{0x64657661, 0x73687264}},
+	{start: 0x1cda, end: 0x1cda, scripts: []Script{0x64657661, 0x6b6e6461, 0x6d6c796d, 0x6f727961, 0x74616d6c, 0x74656c75}},
+	{start: 0x1cdb, end: 0x1cdb, scripts: []Script{0x64657661}},
+	{start: 0x1cdc, end: 0x1cdd, scripts: []Script{0x64657661, 0x73687264}},
+	{start: 0x1cde, end: 0x1cdf, scripts: []Script{0x64657661}},
+	{start: 0x1ce0, end: 0x1ce0, scripts: []Script{0x64657661, 0x73687264}},
+	{start: 0x1ce1, end: 0x1ce1, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0x1ce2, end: 0x1ce8, scripts: []Script{0x64657661}},
+	{start: 0x1ce9, end: 0x1ce9, scripts: []Script{0x64657661, 0x6e616e64}},
+	{start: 0x1cea, end: 0x1cea, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0x1ceb, end: 0x1cec, scripts: []Script{0x64657661}},
+	{start: 0x1ced, end: 0x1ced, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0x1cee, end: 0x1cf1, scripts: []Script{0x64657661}},
+	{start: 0x1cf2, end: 0x1cf2, scripts: []Script{0x62656e67, 0x64657661, 0x6772616e, 0x6b6e6461, 0x6e616e64, 0x6f727961, 0x74656c75, 0x74697268}},
+	{start: 0x1cf3, end: 0x1cf3, scripts: []Script{0x64657661, 0x6772616e}},
+	{start: 0x1cf4, end: 0x1cf4, scripts: []Script{0x64657661, 0x6772616e, 0x6b6e6461}},
+	{start: 0x1cf5, end: 0x1cf6, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0x1cf7, end: 0x1cf7, scripts: []Script{0x62656e67}},
+	{start: 0x1cf8, end: 0x1cf9, scripts: []Script{0x64657661, 0x6772616e}},
+	{start: 0x1cfa, end: 0x1cfa, scripts: []Script{0x6e616e64}},
+	{start: 0x1dc0, end: 0x1dc1, scripts: []Script{0x6772656b}},
+	{start: 0x1df8, end: 0x1df8, scripts: []Script{0x6379726c, 0x73797263}},
+	{start: 0x202f, end: 0x202f, scripts: []Script{0x6c61746e, 0x6d6f6e67}},
+	{start: 0x20f0, end: 0x20f0, scripts: []Script{0x64657661, 0x6772616e, 0x6c61746e}},
+	{start: 0x2e43, end: 0x2e43, scripts: []Script{0x6379726c, 0x676c6167}},
+	{start: 0x3001, end: 0x3002, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61, 0x79696969}},
+	{start: 0x3003, end: 0x3003, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0x3006, end: 0x3006, scripts: []Script{0x68616e69}},
+	{start: 0x3008, end: 0x3011, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61, 0x79696969}},
+	{start: 0x3013, end: 0x3013, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0x3014, end: 0x301b, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61, 0x79696969}},
+	{start: 0x301c, end: 0x301f, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0x302a, end: 0x302d, scripts: []Script{0x626f706f, 0x68616e69}},
+	{start: 0x3030, end: 0x3030, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0x3031, end: 0x3035, scripts: []Script{0x68697261, 0x6b616e61}},
+	{start: 0x3037, end: 0x3037, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0x303c, end: 0x303d, scripts: []Script{0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0x303e, end: 0x303f, scripts: []Script{0x68616e69}},
+	{start: 0x3099, end: 0x309c, scripts: []Script{0x68697261, 0x6b616e61}},
+	{start: 0x30a0, end: 0x30a0, scripts: []Script{0x68697261, 0x6b616e61}},
+	{start: 0x30fb, end: 0x30fb, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61, 0x79696969}},
+	{start: 0x30fc, end: 0x30fc, scripts: []Script{0x68697261, 0x6b616e61}},
+	{start: 0x3190, end: 0x319f, scripts: []Script{0x68616e69}},
+	{start: 0x31c0, end: 0x31e3, scripts: []Script{0x68616e69}},
+	{start: 0x3220, end: 0x3247, scripts: []Script{0x68616e69}},
+	{start: 0x3280, end: 0x32b0, scripts: []Script{0x68616e69}},
+	{start: 0x32c0, end: 0x32cb, scripts: []Script{0x68616e69}},
+	{start: 0x32ff, end: 0x32ff, scripts: []Script{0x68616e69}},
+	{start: 0x3358, end: 0x3370, scripts: []Script{0x68616e69}},
+	{start: 0x337b, end: 0x337f, scripts: []Script{0x68616e69}},
+	{start: 0x33e0, end: 0x33fe, scripts: []Script{0x68616e69}},
+	{start: 0xa66f, end: 0xa66f, scripts: []Script{0x6379726c, 0x676c6167}},
+	{start: 0xa700, end: 0xa707, scripts: []Script{0x68616e69, 0x6c61746e}},
+	{start: 0xa830, end: 0xa832, scripts: []Script{0x64657661, 0x646f6772, 0x67756a72, 0x67757275, 0x6b686f6a, 0x6b6e6461, 0x6b746869, 0x6d61686a, 0x6d6c796d, 0x6d6f6469, 0x6e616e64, 0x73696e64, 0x74616b72, 0x74697268}},
+	{start: 0xa833, end: 0xa835, scripts: []Script{0x64657661, 0x646f6772, 0x67756a72, 0x67757275, 0x6b686f6a, 0x6b6e6461, 0x6b746869, 0x6d61686a, 0x6d6f6469, 0x6e616e64, 0x73696e64, 0x74616b72, 0x74697268}},
+	{start: 0xa836, end: 0xa839, scripts: []Script{0x64657661, 0x646f6772, 0x67756a72, 0x67757275, 0x6b686f6a, 0x6b746869, 0x6d61686a, 0x6d6f6469, 0x73696e64, 0x74616b72, 0x74697268}},
+	{start: 0xa8f1, end: 0xa8f1, scripts: []Script{0x62656e67, 0x64657661}},
+	{start: 0xa8f3, end: 0xa8f3, scripts: []Script{0x64657661, 0x74616d6c}},
+	{start: 0xa92e, end: 0xa92e, scripts: []Script{0x6b616c69, 0x6c61746e, 0x6d796d72}},
+	{start: 0xa9cf, end: 0xa9cf, scripts: []Script{0x62756769, 0x6a617661}},
+	{start: 0xfdf2, end: 0xfdf2, scripts: []Script{0x61726162, 0x74686161}},
+	{start: 0xfdfd, end: 0xfdfd, scripts: []Script{0x61726162, 0x74686161}},
+	{start: 0xfe45, end: 0xfe46, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61}},
+	{start: 0xff61, end: 0xff65, scripts: []Script{0x626f706f, 0x68616e67, 0x68616e69, 0x68697261, 0x6b616e61, 0x79696969}},
+	{start: 0xff70, end: 0xff70, scripts: []Script{0x68697261, 0x6b616e61}},
+	{start: 0xff9e, end: 0xff9f, scripts: []Script{0x68697261, 0x6b616e61}},
+	{start: 0x10100, end: 0x10102, scripts: []Script{0x63707274, 0x6c696e62}},
+	{start: 0x10107, end: 0x10133, scripts: []Script{0x63707274, 0x6c696e61, 0x6c696e62}},
+	{start: 0x10137, end: 0x1013f, scripts: []Script{0x63707274, 0x6c696e62}},
+	{start: 0x102e0, end: 0x102fb, scripts: []Script{0x61726162, 0x636f7074}},
+	{start: 0x11301, end: 0x11301, scripts: []Script{0x6772616e, 0x74616d6c}},
+	{start: 0x11303, end: 0x11303, scripts: []Script{0x6772616e, 0x74616d6c}},
+	{start: 0x1133b, end: 0x1133c, scripts: []Script{0x6772616e, 0x74616d6c}},
+	{start: 0x11fd0, end: 0x11fd1, scripts: []Script{0x6772616e, 0x74616d6c}},
+	{start: 0x11fd3, end: 0x11fd3, scripts: []Script{0x6772616e, 0x74616d6c}},
+	{start: 0x1bca0, end: 0x1bca3, scripts: []Script{0x6475706c}},
+	{start: 0x1d360, end: 0x1d371, scripts: []Script{0x68616e69}},
+	{start: 0x1f250, end: 0x1f251, scripts: []Script{0x68616e69}},
+}
@@ -2,6 +2,7 @@ package language
 
 import (
 	"os"
+	"reflect"
 	"testing"
 	"unicode"
 )
@@ -33,6 +34,25 @@ func TestFastLookup(t *testing.T) {
 	}
 }
 
+func TestLookupScriptExtensions(t *testing.T) {
+	for _, test := range []struct {
+		r        rune
+		expected []Script
+	}{
+		{'a', []Script{Latin}},
+		{' ', []Script{Common}},
+		{0x0300, []Script{Inherited}},
+		{0x0640, []Script{Adlam, Arabic, Mandaic, Manichaean, Psalter_Pahlavi, Hanifi_Rohingya, Sogdian, Syriac}},
+		{0x3001, []Script{Bopomofo, Hangul, Han, Hiragana, Katakana, Yi}},
+		{0x30FC, []Script{Hiragana, Katakana}},
+		{0x0363, []Script{Latin}},
+	} {
+		if got := LookupScriptExtensions(test.r); !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("for rune 0x%x, expected %v, got %v", test.r, test.expected, got)
+		}
+	}
+}
+
 func BenchmarkLookupScript(b *testing.B) {
 	sample := loadSample(b)
 	b.Run("Map with unicode tables", func(b *testing.B) {
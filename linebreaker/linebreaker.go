@@ -0,0 +1,684 @@
+// Package linebreaker implements the optimal fit paragraph line breaking
+// algorithm described by D. E. Knuth and M. F. Plass in
+// "Breaking Paragraphs into Lines" (1981), on top of shaped text.
+//
+// The input is a paragraph already shaped with `harfbuzz` (typically one
+// buffer per item returned by the itemizer), the line break opportunities
+// (see the segmenter package) and optional hyphenation points (see the hyphenation package).
+// Spaces provide the stretchability and shrinkability of the lines, and
+// the chosen lines are returned as glyph runs, justified to the requested widths.
+// The text is only reshaped around the chosen breaks, when the glyphs
+// there are flagged as unsafe to break or to concatenate (see `harfbuzz.Buffer.BreakRanges`),
+// and before the inserted hyphens.
+//
+// Only horizontal text is supported.
+package linebreaker
+
+import (
+	"math"
+	"sort"
+	"unicode"
+
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textlayout/segmenter"
+)
+
+// Run is a shaped run of text, input of the line breaker.
+type Run struct {
+	// Buffer stores the shaped glyphs. Its clusters must be indices in the
+	// paragraph text, as produced by `Buffer.AddRunes(text, Start, End-Start)`,
+	// and must be monotone (`ClusterLevel` must not be `harfbuzz.Characters`).
+	// The `harfbuzz.ProduceUnsafeToConcat` flag should be set before shaping,
+	// so that the text around the line boundaries is reliably reshaped.
+	Buffer *harfbuzz.Buffer
+	// Font and Features are the shaping parameters used to fill `Buffer`,
+	// needed to reshape the text around the line boundaries.
+	Font     *harfbuzz.Font
+	Features []harfbuzz.Feature
+	// Start and End delimit the run in the paragraph text.
+	Start, End int
+}
+
+// Params tunes the line breaking.
+// The zero value is not useful: see `DefaultParams`.
+type Params struct {
+	// LineWidths are the available widths, line by line.
+	// The last value is used for the remaining lines.
+	LineWidths []harfbuzz.Position
+
+	// Tolerance is the maximum adjustment ratio of a line:
+	// stretching the spaces by more than this factor of their stretchability
+	// is not allowed, unless no other solution is possible.
+	Tolerance float64
+
+	// SpaceStretch and SpaceShrink are the stretchability and shrinkability
+	// of a space, as a fraction of its width.
+	SpaceStretch, SpaceShrink float64
+
+	// LinePenalty is added to the badness of each line (the 'l' parameter of the paper),
+	// so that paragraphs with less lines are preferred.
+	LinePenalty float64
+	// HyphenPenalty is the penalty of breaking at a hyphenation point.
+	HyphenPenalty float64
+	// DoubleHyphenDemerits are added when two consecutive lines are hyphenated.
+	DoubleHyphenDemerits float64
+	// FitnessDemerits are added when two consecutive lines have
+	// non adjacent fitness classes (for instance a tight line followed by a loose one).
+	FitnessDemerits float64
+
+	// Looseness is the requested difference between the number of lines of the
+	// paragraph and the optimal one: a positive value asks for a longer paragraph,
+	// a negative value for a shorter one. The closest feasible solution is used.
+	Looseness int
+}
+
+// DefaultParams returns the usual parameters, for lines of the given width.
+func DefaultParams(lineWidth harfbuzz.Position) Params {
+	return Params{
+		LineWidths:           []harfbuzz.Position{lineWidth},
+		Tolerance:            2,
+		SpaceStretch:         1. / 2,
+		SpaceShrink:          1. / 3,
+		LinePenalty:          10,
+		HyphenPenalty:        50,
+		DoubleHyphenDemerits: 3000,
+		FitnessDemerits:      100,
+	}
+}
+
+func (p *Params) lineWidth(line int) float64 {
+	if len(p.LineWidths) == 0 {
+		return 0
+	}
+	if line >= len(p.LineWidths) {
+		line = len(p.LineWidths) - 1
+	}
+	return float64(p.LineWidths[line])
+}
+
+// LineRun is the part of an input run displayed in a line.
+type LineRun struct {
+	// Run is the index of the input run.
+	Run int
+	// Start and End delimit the text of the fragment.
+	Start, End int
+	// Glyphs and Positions are the glyphs of the fragment,
+	// in visual order (as in a `harfbuzz.Buffer`), with the
+	// justification adjustments applied to the spaces.
+	Glyphs    []harfbuzz.GlyphInfo
+	Positions []harfbuzz.GlyphPosition
+}
+
+// Line is one line of the broken paragraph.
+type Line struct {
+	// Start and End delimit the text of the line. End is the start
+	// of the next line, so that trailing whitespaces are included,
+	// even if they have no glyphs in `Runs`.
+	Start, End int
+	// Runs are the fragments of the input runs in the line,
+	// in logical order. Visual reordering of bidirectional text is left to the caller
+	// (see `bidi.Paragraph.Runs`).
+	Runs []LineRun
+	// Hyphenated is true if the line ends at a hyphenation point, in which case
+	// a hyphen glyph has been appended to the last run.
+	Hyphenated bool
+	// Ratio is the adjustment ratio applied to the spaces:
+	// positive values for stretched lines, negative ones for shrunk lines.
+	Ratio float64
+}
+
+// Width returns the total advance of the line.
+func (l Line) Width() harfbuzz.Position {
+	var out harfbuzz.Position
+	for _, run := range l.Runs {
+		for _, pos := range run.Positions {
+			out += pos.XAdvance
+		}
+	}
+	return out
+}
+
+// breakpoint is a feasible break, between two lines
+type breakpoint struct {
+	next      int     // start of the next line
+	end       int     // end of the line, trailing whitespaces excluded
+	penalty   float64 // only used for hyphens
+	width     float64 // width added at the end of the line (hyphen)
+	hyphen    bool
+	mandatory bool
+}
+
+// paragraph stores the cumulated metrics of the text
+type paragraph struct {
+	text []rune
+	runs []Run
+
+	// cumulated widths, stretchabilities and shrinkabilities,
+	// with length len(text) + 1
+	widths, stretches, shrinks []float64
+
+	breakpoints []breakpoint
+}
+
+// Break splits the paragraph `text`, shaped in `runs`, into lines, using the
+// break opportunities `breaks` (see `segmenter.LineBreaks`) and the hyphenation points
+// `hyphens` (indices into `text`, which may be obtained with `hyphenation.Hyphenator`).
+//
+// The runs must be sorted and must cover the whole text.
+// The end of the text is always considered as a mandatory break.
+// When no solution respects `params.Tolerance`, loose lines are accepted,
+// and if a word does not fit in a line, an overfull line is used.
+func Break(text []rune, runs []Run, breaks []segmenter.LineBreak, hyphens []int, params Params) []Line {
+	if len(text) == 0 {
+		return nil
+	}
+	p := newParagraph(text, runs, breaks, hyphens, params)
+
+	last := p.solve(params, params.Tolerance, false)
+	if last == nil {
+		last = p.solve(params, math.Inf(1), true)
+	}
+
+	var nodes []*node
+	for n := last; n.prev != nil; n = n.prev {
+		nodes = append(nodes, n)
+	}
+	lines := make([]Line, len(nodes))
+	for i := range nodes {
+		n := nodes[len(nodes)-1-i]
+		start := 0
+		if n.prev.bp != -1 {
+			start = p.breakpoints[n.prev.bp].next
+		}
+		lines[i] = p.buildLine(start, p.breakpoints[n.bp], n.ratio)
+	}
+	return lines
+}
+
+func newParagraph(text []rune, runs []Run, breaks []segmenter.LineBreak, hyphens []int, params Params) *paragraph {
+	p := &paragraph{
+		text:      text,
+		runs:      runs,
+		widths:    make([]float64, len(text)+1),
+		stretches: make([]float64, len(text)+1),
+		shrinks:   make([]float64, len(text)+1),
+	}
+
+	// width of each character (the width of a cluster is attributed to its first character)
+	for _, run := range runs {
+		for i, glyph := range run.Buffer.Info {
+			if glyph.Cluster >= 0 && glyph.Cluster < len(text) {
+				p.widths[glyph.Cluster+1] += float64(run.Buffer.Pos[i].XAdvance)
+			}
+		}
+	}
+	for i, r := range text {
+		w := p.widths[i+1]
+		if isGlue(r) {
+			p.stretches[i+1] = w * params.SpaceStretch
+			p.shrinks[i+1] = w * params.SpaceShrink
+		}
+		p.widths[i+1] += p.widths[i]
+		p.stretches[i+1] += p.stretches[i]
+		p.shrinks[i+1] += p.shrinks[i]
+	}
+
+	// merge the breaks and the hyphenation points
+	isBreak := make(map[int]bool, len(breaks))
+	for _, b := range breaks {
+		if b.Offset <= 0 || b.Offset > len(text) {
+			continue
+		}
+		isBreak[b.Offset] = true
+		p.breakpoints = append(p.breakpoints, breakpoint{
+			next:      b.Offset,
+			end:       p.trimSpaces(b.Offset),
+			mandatory: b.IsMandatory || b.Offset == len(text),
+		})
+	}
+	for _, h := range hyphens {
+		if h <= 0 || h >= len(text) || isBreak[h] {
+			continue
+		}
+		isBreak[h] = true
+		var width float64
+		if run := p.runAt(h - 1); run != -1 {
+			font := p.runs[run].Font
+			gid, _ := font.NominalGlyph(hyphenRune(font))
+			width = float64(font.GlyphHAdvance(gid))
+		}
+		p.breakpoints = append(p.breakpoints, breakpoint{
+			next: h, end: h, hyphen: true,
+			width: width, penalty: params.HyphenPenalty,
+		})
+	}
+	if !isBreak[len(text)] {
+		p.breakpoints = append(p.breakpoints, breakpoint{next: len(text), end: p.trimSpaces(len(text)), mandatory: true})
+	}
+	sort.Slice(p.breakpoints, func(i, j int) bool { return p.breakpoints[i].next < p.breakpoints[j].next })
+
+	return p
+}
+
+// isGlue returns true for the characters providing stretchability
+func isGlue(r rune) bool { return unicode.Is(unicode.Zs, r) }
+
+// hyphenRune returns the character used to display
+// hyphenation: U+2010 HYPHEN if supported by `font`, or HYPHEN-MINUS.
+func hyphenRune(font *harfbuzz.Font) rune {
+	if _, ok := font.NominalGlyph(0x2010); ok {
+		return 0x2010
+	}
+	return '-'
+}
+
+// trimSpaces returns the end of the line content,
+// for a line ending before `end`.
+func (p *paragraph) trimSpaces(end int) int {
+	for end > 0 && unicode.IsSpace(p.text[end-1]) {
+		end--
+	}
+	return end
+}
+
+// runAt returns the index of the run containing `index`, or -1
+func (p *paragraph) runAt(index int) int {
+	for i, run := range p.runs {
+		if run.Start <= index && index < run.End {
+			return i
+		}
+	}
+	return -1
+}
+
+// ratio returns the adjustment ratio of the line text[start:bp.end]
+func (p *paragraph) ratio(start int, bp breakpoint, lineWidth float64) float64 {
+	end := bp.end
+	if end < start { // empty line
+		end = start
+	}
+	natural := p.widths[end] - p.widths[start] + bp.width
+	switch {
+	case natural < lineWidth:
+		if bp.mandatory { // the last line is filled by an infinite glue
+			return 0
+		}
+		stretch := p.stretches[end] - p.stretches[start]
+		if stretch <= 0 {
+			return math.Inf(1)
+		}
+		return (lineWidth - natural) / stretch
+	case natural > lineWidth:
+		shrink := p.shrinks[end] - p.shrinks[start]
+		if shrink <= 0 {
+			return math.Inf(-1)
+		}
+		return (lineWidth - natural) / shrink
+	default:
+		return 0
+	}
+}
+
+// node is a feasible break, in the graph of the solutions
+type node struct {
+	prev     *node
+	bp       int // index in breakpoints, -1 for the start of the paragraph
+	line     int // number of lines up to this break
+	fitness  int
+	demerits float64
+	ratio    float64 // of the line ending here
+}
+
+// fitnessClass returns the fitness class of a line
+// with adjustment ratio `r`: tight, decent, loose or very loose.
+func fitnessClass(r float64) int {
+	switch {
+	case r < -0.5:
+		return 0
+	case r <= 0.5:
+		return 1
+	case r <= 1:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// badness returns the badness of a line with adjustment ratio `r`.
+// It is not capped, so that very loose lines may still be compared
+// when no solution respects the tolerance.
+func badness(r float64) float64 {
+	const infBad = 1e6 // lines without stretchability
+	if math.IsInf(r, 0) {
+		return infBad
+	}
+	return math.Min(100*math.Abs(r*r*r), infBad)
+}
+
+// solve runs the main loop of the algorithm and returns the
+// last node of the chosen solution, or nil if no solution respects `tolerance`.
+// If `emergency` is true, overfull lines are accepted when no other choice
+// is possible.
+func (p *paragraph) solve(params Params, tolerance float64, emergency bool) *node {
+	active := []*node{{bp: -1, fitness: 1}}
+	type key struct{ line, fitness int }
+
+	for j, bp := range p.breakpoints {
+		var (
+			remaining  []*node
+			candidates = map[key]*node{}
+			overfull   *node // most recent deactivated node
+		)
+		for _, a := range active {
+			start := 0
+			if a.bp != -1 {
+				start = p.breakpoints[a.bp].next
+			}
+			r := p.ratio(start, bp, params.lineWidth(a.line))
+			if r < -1 || bp.mandatory {
+				if r < -1 && (overfull == nil || a.bp > overfull.bp) {
+					overfull = a
+				}
+			} else {
+				remaining = append(remaining, a)
+			}
+			if r < -1 || r > tolerance {
+				continue
+			}
+
+			d := params.LinePenalty + badness(r)
+			d *= d
+			if bp.hyphen {
+				d += bp.penalty * bp.penalty
+				if a.bp != -1 && p.breakpoints[a.bp].hyphen {
+					d += params.DoubleHyphenDemerits
+				}
+			}
+			c := fitnessClass(r)
+			if c-a.fitness > 1 || a.fitness-c > 1 {
+				d += params.FitnessDemerits
+			}
+
+			n := &node{prev: a, bp: j, line: a.line + 1, fitness: c, demerits: a.demerits + d, ratio: r}
+			k := key{n.line, c}
+			if other := candidates[k]; other == nil || n.demerits < other.demerits {
+				candidates[k] = n
+			}
+		}
+
+		if len(candidates) == 0 && len(remaining) == 0 {
+			if !emergency || overfull == nil {
+				return nil
+			}
+			// a word is wider than the line: accept an overfull line
+			n := &node{prev: overfull, bp: j, line: overfull.line + 1, fitness: 0, demerits: overfull.demerits, ratio: -1}
+			candidates[key{n.line, 0}] = n
+		}
+
+		keys := make([]key, 0, len(candidates))
+		for k := range candidates {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].line != keys[j].line {
+				return keys[i].line < keys[j].line
+			}
+			return keys[i].fitness < keys[j].fitness
+		})
+		for _, k := range keys {
+			remaining = append(remaining, candidates[k])
+		}
+		active = remaining
+	}
+
+	// the last breakpoint is mandatory, so that only nodes ending the paragraph are active
+	var best *node
+	for _, n := range active {
+		if best == nil || n.demerits < best.demerits {
+			best = n
+		}
+	}
+	if best == nil || params.Looseness == 0 {
+		return best
+	}
+	target := best.line + params.Looseness
+	chosen := best
+	for _, n := range active {
+		dn, dc := abs(n.line-target), abs(chosen.line-target)
+		if dn < dc || (dn == dc && n.demerits < chosen.demerits) {
+			chosen = n
+		}
+	}
+	return chosen
+}
+
+func abs(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// buildLine returns the glyphs for the line text[start:bp.end],
+// justified with the adjustment ratio `r`.
+func (p *paragraph) buildLine(start int, bp breakpoint, r float64) Line {
+	line := Line{Start: start, End: bp.next, Hyphenated: bp.hyphen}
+	end := bp.end
+	if end < start {
+		end = start
+	}
+	if math.IsInf(r, 0) || math.IsNaN(r) {
+		r = 0
+	}
+	if r < -1 {
+		r = -1
+	}
+	line.Ratio = r
+
+	for i, run := range p.runs {
+		fragStart, fragEnd := max(run.Start, start), min(run.End, end)
+		isLast := run.Start < end && end <= run.End
+		if fragStart >= fragEnd && !(bp.hyphen && isLast) {
+			continue
+		}
+		glyphs, positions := p.fragment(run, fragStart, fragEnd, bp.hyphen && isLast)
+		line.Runs = append(line.Runs, LineRun{Run: i, Start: fragStart, End: fragEnd, Glyphs: glyphs, Positions: positions})
+	}
+
+	p.justify(line, r)
+	return line
+}
+
+// fragment returns the glyphs for text[start:end], which is part of `run`,
+// reusing the shaped glyphs when possible.
+// If `hyphen` is true, a hyphen is added at the end.
+func (p *paragraph) fragment(run Run, start, end int, hyphen bool) ([]harfbuzz.GlyphInfo, []harfbuzz.GlyphPosition) {
+	// [reuseStart, reuseEnd) is the text whose glyphs are kept
+	reuseStart, reuseEnd := start, end
+	if start > run.Start {
+		if br := run.Buffer.BreakRanges(start, run.End); br.ReshapeAfter.End > br.ReshapeAfter.Start {
+			reuseStart = br.ReshapeAfter.End
+		}
+	}
+	if end < run.End {
+		if br := run.Buffer.BreakRanges(end, run.End); br.ReshapeBefore.End > br.ReshapeBefore.Start {
+			reuseEnd = br.ReshapeBefore.Start
+		}
+	}
+	if hyphen && reuseEnd == end && end > start {
+		// the hyphen may interact with the last glyphs (kerning, joining)
+		reuseEnd = previousBoundary(run, end)
+	}
+
+	rtl := run.Buffer.Props.Direction == harfbuzz.RightToLeft
+	for {
+		if reuseStart >= reuseEnd { // the reshaped ranges meet: reshape the whole fragment
+			return p.reshape(run, start, end, hyphen)
+		}
+
+		var before, after piece
+		if start < reuseStart {
+			before.glyphs, before.positions = p.reshape(run, start, reuseStart, false)
+		}
+		if reuseEnd < end || hyphen {
+			after.glyphs, after.positions = p.reshape(run, reuseEnd, end, hyphen)
+		}
+
+		// the reshaped pieces may not be joined with the kept glyphs:
+		// extend the reshaped ranges and try again
+		extended := false
+		if len(before.glyphs) != 0 && before.logicalGlyph(rtl, len(before.glyphs)-1).Mask&harfbuzz.GlyphUnsafeToConcat != 0 {
+			reuseStart = nextBoundary(run, reuseStart)
+			extended = true
+		}
+		if len(after.glyphs) != 0 && after.logicalGlyph(rtl, 0).Mask&harfbuzz.GlyphUnsafeToConcat != 0 {
+			reuseEnd = previousBoundary(run, reuseEnd)
+			extended = true
+		}
+		if extended {
+			continue
+		}
+
+		var kept piece
+		kept.glyphs, kept.positions = extractClusters(run.Buffer, reuseStart, reuseEnd)
+		// in logical order
+		pieces := []piece{before, kept, after}
+		if rtl { // visual order is reversed
+			pieces[0], pieces[2] = pieces[2], pieces[0]
+		}
+		var (
+			glyphs    []harfbuzz.GlyphInfo
+			positions []harfbuzz.GlyphPosition
+		)
+		for _, pi := range pieces {
+			glyphs = append(glyphs, pi.glyphs...)
+			positions = append(positions, pi.positions...)
+		}
+		return glyphs, positions
+	}
+}
+
+// piece is a fragment of a line run
+type piece struct {
+	glyphs    []harfbuzz.GlyphInfo
+	positions []harfbuzz.GlyphPosition
+}
+
+// logicalGlyph returns the i-th glyph of the piece, in logical order
+func (pi piece) logicalGlyph(rtl bool, i int) harfbuzz.GlyphInfo {
+	if rtl {
+		return pi.glyphs[len(pi.glyphs)-1-i]
+	}
+	return pi.glyphs[i]
+}
+
+// nextBoundary returns the first cluster of `run` after `cluster`
+// where its glyphs may be split without reshaping the text after it.
+func nextBoundary(run Run, cluster int) int {
+	next := run.End
+	for _, glyph := range run.Buffer.Info {
+		if cluster < glyph.Cluster && glyph.Cluster < next {
+			next = glyph.Cluster
+		}
+	}
+	if next < run.End {
+		if br := run.Buffer.BreakRanges(next, run.End); br.ReshapeAfter.End > br.ReshapeAfter.Start {
+			next = br.ReshapeAfter.End
+		}
+	}
+	return next
+}
+
+// previousBoundary returns the last cluster of `run` before `cluster`
+// where its glyphs may be split without reshaping the text before it.
+func previousBoundary(run Run, cluster int) int {
+	previous := run.Start
+	for _, glyph := range run.Buffer.Info {
+		if previous < glyph.Cluster && glyph.Cluster < cluster {
+			previous = glyph.Cluster
+		}
+	}
+	if previous > run.Start {
+		if br := run.Buffer.BreakRanges(previous, run.End); br.ReshapeBefore.End > br.ReshapeBefore.Start {
+			previous = br.ReshapeBefore.Start
+		}
+	}
+	return previous
+}
+
+// extractClusters returns a copy of the glyphs of `buf` whose cluster
+// is in [start, end)
+func extractClusters(buf *harfbuzz.Buffer, start, end int) ([]harfbuzz.GlyphInfo, []harfbuzz.GlyphPosition) {
+	var (
+		glyphs    []harfbuzz.GlyphInfo
+		positions []harfbuzz.GlyphPosition
+	)
+	for i, glyph := range buf.Info {
+		if start <= glyph.Cluster && glyph.Cluster < end {
+			glyphs = append(glyphs, glyph)
+			positions = append(positions, buf.Pos[i])
+		}
+	}
+	return glyphs, positions
+}
+
+// reshape shapes text[start:end] with the properties of `run`,
+// optionally followed by a hyphen, whose cluster is `end`.
+func (p *paragraph) reshape(run Run, start, end int, hyphen bool) ([]harfbuzz.GlyphInfo, []harfbuzz.GlyphPosition) {
+	text, length := p.text, end-start
+	if hyphen {
+		text = append(append([]rune(nil), p.text[:end]...), hyphenRune(run.Font))
+		length++
+	}
+	buf := harfbuzz.NewBuffer()
+	buf.Props = run.Buffer.Props
+	// required to check the junctions with the kept glyphs
+	buf.Flags = run.Buffer.Flags | harfbuzz.ProduceUnsafeToConcat
+	buf.ClusterLevel = run.Buffer.ClusterLevel
+	buf.AddRunes(text, start, length)
+	buf.Shape(run.Font, run.Features)
+	return buf.Info, buf.Pos
+}
+
+// justify adjusts the advance of the spaces of `line`, using the ratio `r`.
+// The adjustments are rounded cumulatively, so that the total matches the
+// unrounded one.
+func (p *paragraph) justify(line Line, r float64) {
+	if r == 0 {
+		return
+	}
+	var total, applied float64
+	for _, run := range line.Runs {
+		for i, glyph := range run.Glyphs {
+			c := glyph.Cluster
+			if c < 0 || c >= len(p.text) || !isGlue(p.text[c]) {
+				continue
+			}
+			if i > 0 && run.Glyphs[i-1].Cluster == c { // only adjust one glyph per space
+				continue
+			}
+			if r > 0 {
+				total += r * (p.stretches[c+1] - p.stretches[c])
+			} else {
+				total += r * (p.shrinks[c+1] - p.shrinks[c])
+			}
+			adjust := math.Round(total) - applied
+			applied += adjust
+			run.Positions[i].XAdvance += harfbuzz.Position(adjust)
+		}
+	}
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,290 @@
+package linebreaker
+
+import (
+	"bytes"
+	"testing"
+
+	testdata "github.com/benoitkugler/textlayout-testdata/truetype"
+	"github.com/benoitkugler/textlayout/fonts/truetype"
+	"github.com/benoitkugler/textlayout/harfbuzz"
+	"github.com/benoitkugler/textlayout/language"
+	"github.com/benoitkugler/textlayout/segmenter"
+)
+
+func loadFont(t *testing.T, filename string) *harfbuzz.Font {
+	t.Helper()
+	b, err := testdata.Files.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	face, err := truetype.Parse(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return harfbuzz.NewFont(face)
+}
+
+// shape returns one run covering the whole text
+func shape(text []rune, font *harfbuzz.Font, dir harfbuzz.Direction) []Run {
+	buf := harfbuzz.NewBuffer()
+	buf.Props = harfbuzz.SegmentProperties{Direction: dir, Script: language.Latin, Language: "en"}
+	if dir == harfbuzz.RightToLeft {
+		buf.Props.Script = language.Arabic
+		buf.Props.Language = "ar"
+	}
+	buf.Flags = harfbuzz.ProduceUnsafeToConcat
+	buf.AddRunes(text, 0, len(text))
+	buf.Shape(font, nil)
+	return []Run{{Buffer: buf, Font: font, Start: 0, End: len(text)}}
+}
+
+const lorem = "Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat."
+
+// checkLines verifies that the lines cover the text, that they are justified, and
+// that the glyphs are in the lines.
+func checkLines(t *testing.T, text []rune, lines []Line, params Params) {
+	t.Helper()
+	if len(lines) == 0 {
+		t.Fatal("no lines")
+	}
+	start := 0
+	for i, line := range lines {
+		if line.Start != start {
+			t.Fatalf("line %d: expected start %d, got %d", i, start, line.Start)
+		}
+		start = line.End
+		for _, run := range line.Runs {
+			for _, glyph := range run.Glyphs {
+				if glyph.Cluster < line.Start || glyph.Cluster > line.End {
+					t.Fatalf("line %d: glyph with cluster %d outside [%d, %d]", i, glyph.Cluster, line.Start, line.End)
+				}
+			}
+		}
+		if i == len(lines)-1 {
+			continue
+		}
+		if line.Ratio < -1 || line.Ratio > params.Tolerance {
+			t.Fatalf("line %d: invalid ratio %f", i, line.Ratio)
+		}
+		if w, exp := line.Width(), params.LineWidths[min(i, len(params.LineWidths)-1)]; w != exp {
+			t.Fatalf("line %d (%s): expected width %d, got %d", i, string(text[line.Start:line.End]), exp, w)
+		}
+	}
+	if start != len(text) {
+		t.Fatalf("expected end %d, got %d", len(text), start)
+	}
+}
+
+func TestBreak(t *testing.T) {
+	font := loadFont(t, "DejaVuSerif.ttf")
+	text := []rune(lorem)
+	runs := shape(text, font, harfbuzz.LeftToRight)
+	breaks := segmenter.LineBreaks(text)
+
+	params := DefaultParams(60000)
+	lines := Break(text, runs, breaks, nil, params)
+	checkLines(t, text, lines, params)
+
+	// wider lines give less lines
+	params = DefaultParams(120000)
+	wide := Break(text, runs, breaks, nil, params)
+	checkLines(t, text, wide, params)
+	if len(wide) >= len(lines) {
+		t.Fatalf("expected less than %d lines, got %d", len(lines), len(wide))
+	}
+
+	// per line widths
+	params = DefaultParams(60000)
+	params.LineWidths = []harfbuzz.Position{40000, 50000, 60000}
+	params.Tolerance = 10 // the first line is too short for the default tolerance
+	lines = Break(text, runs, breaks, nil, params)
+	checkLines(t, text, lines, params)
+}
+
+func TestLooseness(t *testing.T) {
+	font := loadFont(t, "DejaVuSerif.ttf")
+	text := []rune(lorem)
+	runs := shape(text, font, harfbuzz.LeftToRight)
+	breaks := segmenter.LineBreaks(text)
+
+	params := DefaultParams(80000)
+	optimal := Break(text, runs, breaks, nil, params)
+
+	params.Looseness = 1
+	params.Tolerance = 10
+	loose := Break(text, runs, breaks, nil, params)
+	checkLines(t, text, loose, params)
+	if len(loose) != len(optimal)+1 {
+		t.Fatalf("expected %d lines, got %d", len(optimal)+1, len(loose))
+	}
+}
+
+func TestHyphenation(t *testing.T) {
+	font := loadFont(t, "DejaVuSerif.ttf")
+	text := []rune("aaa hyphenation")
+	runs := shape(text, font, harfbuzz.LeftToRight)
+	breaks := segmenter.LineBreaks(text)
+
+	// "aaa hyphen-" fits, but "aaa hyphenation" does not
+	natural := func(start, end int) harfbuzz.Position {
+		var w harfbuzz.Position
+		for i, g := range runs[0].Buffer.Info {
+			if start <= g.Cluster && g.Cluster < end {
+				w += runs[0].Buffer.Pos[i].XAdvance
+			}
+		}
+		return w
+	}
+	gid, _ := font.NominalGlyph(hyphenRune(font))
+	width := natural(0, 10) + font.GlyphHAdvance(gid)
+
+	params := DefaultParams(width)
+	lines := Break(text, runs, breaks, nil, params)
+	if len(lines) != 2 || lines[0].End != 4 {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+
+	lines = Break(text, runs, breaks, []int{6, 10}, params)
+	if len(lines) != 2 || lines[0].End != 10 || !lines[0].Hyphenated {
+		t.Fatalf("unexpected lines %v", lines)
+	}
+	checkLines(t, text, lines, params)
+	first := lines[0].Runs[0]
+	if last := first.Glyphs[len(first.Glyphs)-1]; last.Glyph != gid || last.Cluster != 10 {
+		t.Fatalf("expected hyphen glyph, got %v", last)
+	}
+}
+
+func TestOverfull(t *testing.T) {
+	font := loadFont(t, "DejaVuSerif.ttf")
+	text := []rune("a verylongwordindeed b")
+	runs := shape(text, font, harfbuzz.LeftToRight)
+	breaks := segmenter.LineBreaks(text)
+
+	lines := Break(text, runs, breaks, nil, DefaultParams(3000))
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got %d", len(lines))
+	}
+	if lines[1].Start != 2 || lines[1].End != 21 {
+		t.Fatalf("unexpected overfull line %v", lines[1])
+	}
+}
+
+func TestRightToLeft(t *testing.T) {
+	font := loadFont(t, "NotoSansArabic.ttf")
+	text := []rune("مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم مرحبا بالعالم")
+	runs := shape(text, font, harfbuzz.RightToLeft)
+	breaks := segmenter.LineBreaks(text)
+
+	params := DefaultParams(12000)
+	lines := Break(text, runs, breaks, nil, params)
+	if len(lines) < 2 {
+		t.Fatalf("expected several lines, got %d", len(lines))
+	}
+	checkLines(t, text, lines, params)
+	for _, line := range lines {
+		glyphs := line.Runs[0].Glyphs
+		// visual order: clusters are decreasing
+		for i := 1; i < len(glyphs); i++ {
+			if glyphs[i].Cluster > glyphs[i-1].Cluster {
+				t.Fatalf("glyphs not in visual order: %v", glyphs)
+			}
+		}
+	}
+}
+
+// shapeLine shapes the text of each run of `line` from scratch
+func shapeLine(text []rune, runs []Run, line Line) []LineRun {
+	var out []LineRun
+	for i, lr := range line.Runs {
+		run := runs[lr.Run]
+		content, length := text, lr.End-lr.Start
+		if line.Hyphenated && i == len(line.Runs)-1 {
+			content = append(append([]rune(nil), text[:lr.End]...), hyphenRune(run.Font))
+			length++
+		}
+		buf := harfbuzz.NewBuffer()
+		buf.Props = run.Buffer.Props
+		buf.AddRunes(content, lr.Start, length)
+		buf.Shape(run.Font, run.Features)
+		out = append(out, LineRun{Run: lr.Run, Start: lr.Start, End: lr.End, Glyphs: buf.Info, Positions: buf.Pos})
+	}
+	return out
+}
+
+func TestReshapeAtBreaks(t *testing.T) {
+	for _, test := range []struct {
+		font string
+		text string
+		dir  harfbuzz.Direction
+		// the unsafe junctions are detected when reshaping,
+		// even if the flags are missing in the input
+		noConcatFlag bool
+	}{
+		{"DejaVuSerif.ttf", "office affluent waffle AVATAR Taverns Yo-yo", harfbuzz.LeftToRight, false},
+		{"Castoro-Regular.ttf", "office affluent waffle AVATAR Taverns Yo-yo fiffi official", harfbuzz.LeftToRight, false},
+		{"Castoro-Regular.ttf", "office affluent waffle AVATAR Taverns Yo-yo fiffi official", harfbuzz.LeftToRight, true},
+		{"NotoSansArabic.ttf", "مرحبا بالعالم مستشفيات المستقبلية لله الله بلا", harfbuzz.RightToLeft, false},
+		{"NotoSansArabic.ttf", "مرحبا بالعالم مستشفيات المستقبلية لله الله بلا", harfbuzz.RightToLeft, true},
+	} {
+		font := loadFont(t, test.font)
+		text := []rune(test.text)
+		runs := shape(text, font, test.dir)
+		if test.noConcatFlag {
+			buf, props := runs[0].Buffer, runs[0].Buffer.Props
+			buf.Clear()
+			buf.Props = props
+			buf.AddRunes(text, 0, len(text))
+			buf.Shape(font, nil)
+		}
+		breaks := segmenter.LineBreaks(text)
+		var hyphens []int // allow breaking everywhere inside the words
+		for i := 1; i < len(text); i++ {
+			if text[i-1] != ' ' && text[i] != ' ' {
+				hyphens = append(hyphens, i)
+			}
+		}
+
+		reshaped := 0
+		for width := harfbuzz.Position(1000); width <= 12000; width += 250 {
+			params := DefaultParams(width)
+			params.HyphenPenalty = 0
+			params.Tolerance = 100
+			for _, line := range Break(text, runs, breaks, hyphens, params) {
+				if line.Ratio != 0 {
+					// compare before justification
+					for _, run := range line.Runs {
+						for i, glyph := range run.Glyphs {
+							if isGlue(text[glyph.Cluster]) {
+								run.Positions[i].XAdvance = 0
+							}
+						}
+					}
+				}
+				expected := shapeLine(text, runs, line)
+				for i, run := range line.Runs {
+					exp := expected[i]
+					if len(run.Glyphs) != len(exp.Glyphs) {
+						t.Fatalf("line %q: expected %d glyphs, got %d", string(text[line.Start:line.End]), len(exp.Glyphs), len(run.Glyphs))
+					}
+					for j, glyph := range run.Glyphs {
+						pos, expPos := run.Positions[j], exp.Positions[j]
+						if isGlue(text[glyph.Cluster]) {
+							expPos.XAdvance = pos.XAdvance
+						}
+						if glyph.Glyph != exp.Glyphs[j].Glyph || glyph.Cluster != exp.Glyphs[j].Cluster || pos != expPos {
+							t.Fatalf("%s: line %q, glyph %d: expected %v %v, got %v %v", test.font, string(text[line.Start:line.End]), j,
+								exp.Glyphs[j], expPos, glyph, pos)
+						}
+					}
+				}
+				if line.Hyphenated {
+					reshaped++
+				}
+			}
+		}
+		if reshaped == 0 {
+			t.Fatalf("%s: no line broken inside a word", test.font)
+		}
+	}
+}
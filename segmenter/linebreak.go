@@ -0,0 +1,377 @@
+// Package segmenter implements the Unicode algorithms used to
+// split text into segments: line break opportunities (UAX #14),
+// and grapheme clusters, words and sentences (UAX #29).
+package segmenter
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	ucd "github.com/benoitkugler/textlayout/unicodedata"
+)
+
+// lineClass is a line break class, resolved according to rule LB1.
+// The first values are the ones used in the pair table.
+type lineClass uint8
+
+const (
+	lbOP lineClass = iota
+	lbCL
+	lbCP
+	lbQU
+	lbGL
+	lbNS
+	lbEX
+	lbSY
+	lbIS
+	lbPR
+	lbPO
+	lbNU
+	lbAL
+	lbHL
+	lbID
+	lbIN
+	lbHY
+	lbBA
+	lbBB
+	lbB2
+	lbZW
+	lbCM
+	lbWJ
+	lbH2
+	lbH3
+	lbJL
+	lbJV
+	lbJT
+	lbRI
+	lbEB
+	lbEM
+	lbZWJ
+	lbCB
+
+	numPairClasses
+
+	// the following classes are handled before using the pair table
+	lbBK
+	lbCR
+	lbLF
+	lbNL
+	lbSP
+)
+
+var lineClasses = map[*unicode.RangeTable]lineClass{
+	ucd.BreakOP: lbOP, ucd.BreakCL: lbCL, ucd.BreakCP: lbCP, ucd.BreakQU: lbQU,
+	ucd.BreakGL: lbGL, ucd.BreakNS: lbNS, ucd.BreakEX: lbEX, ucd.BreakSY: lbSY,
+	ucd.BreakIS: lbIS, ucd.BreakPR: lbPR, ucd.BreakPO: lbPO, ucd.BreakNU: lbNU,
+	ucd.BreakAL: lbAL, ucd.BreakHL: lbHL, ucd.BreakID: lbID, ucd.BreakIN: lbIN,
+	ucd.BreakHY: lbHY, ucd.BreakBA: lbBA, ucd.BreakBB: lbBB, ucd.BreakB2: lbB2,
+	ucd.BreakZW: lbZW, ucd.BreakCM: lbCM, ucd.BreakWJ: lbWJ, ucd.BreakH2: lbH2,
+	ucd.BreakH3: lbH3, ucd.BreakJL: lbJL, ucd.BreakJV: lbJV, ucd.BreakJT: lbJT,
+	ucd.BreakRI: lbRI, ucd.BreakEB: lbEB, ucd.BreakEM: lbEM, ucd.BreakZWJ: lbZWJ,
+	ucd.BreakCB: lbCB, ucd.BreakBK: lbBK, ucd.BreakCR: lbCR, ucd.BreakLF: lbLF,
+	ucd.BreakNL: lbNL, ucd.BreakSP: lbSP,
+}
+
+// lookupLineClass returns the line break class of `r`,
+// resolved according to rule LB1.
+func lookupLineClass(r rune) lineClass {
+	table := ucd.LookupLineBreakClass(r)
+	if class, ok := lineClasses[table]; ok {
+		return class
+	}
+	switch table {
+	case ucd.BreakSA:
+		if unicode.In(r, unicode.Mn, unicode.Mc) {
+			return lbCM
+		}
+		return lbAL
+	case ucd.BreakCJ:
+		return lbNS
+	default: // AI, SG, XX
+		return lbAL
+	}
+}
+
+// breakAction is the action stored in the pair table
+type breakAction uint8
+
+const (
+	db breakAction = iota // direct break: B ÷ A
+	ib                    // indirect break: B × A, but B SP+ ÷ A
+	pb                    // prohibited break: B SP* × A
+)
+
+// LineBreak is a line break opportunity, located
+// before the rune at index `Offset`.
+type LineBreak struct {
+	// Offset is the index of the rune following the break,
+	// so that text[:Offset] is the text before the break.
+	// It is suitable to be compared with the clusters
+	// of a `harfbuzz.Buffer` filled with `AddRunes`.
+	Offset int
+	// ByteOffset is the same as `Offset`, in the UTF-8 encoded text,
+	// suitable to be compared with the clusters of a `harfbuzz.Buffer`
+	// filled with `AddUTF8`.
+	ByteOffset int
+	// IsMandatory is true for hard line breaks (after BK, CR, LF and NL characters,
+	// and at the end of the text).
+	IsMandatory bool
+}
+
+// LineBreakIterator iterates over the line break opportunities
+// of a text, as defined by UAX #14 (https://www.unicode.org/reports/tr14/).
+//
+// Rule LB25 is tailored as described in example 7 of section 8.2,
+// which is the behavior expected by the conformance test file LineBreakTest.txt.
+type LineBreakIterator struct {
+	text []rune
+
+	current LineBreak
+
+	pos        int // index of the next rune to process
+	byteOffset int // byte offset of `pos`
+
+	// class of the last character, before resolution by rule LB9 and LB10
+	rawPrev lineClass
+	// class of the last non space character, before resolution by LB9 and LB10
+	rawPrevNonSpace lineClass
+
+	// class of the last non space character, after resolution by LB9 and LB10,
+	// and its (base) rune; hasBefore is false after a mandatory break or
+	// at the start of the text
+	before     lineClass
+	beforeRune rune
+	hasBefore  bool
+	// class before `before`, valid if hasBeforeBefore is true
+	beforeBefore    lineClass
+	hasBeforeBefore bool
+
+	// state for rule LB25 : 1 if we are in a NU (NU|SY|IS)* sequence,
+	// 2 if we are after a NU (NU|SY|IS)* (CL|CP) sequence, 0 otherwise
+	numberState uint8
+	// number of consecutive RI ending with `before`
+	riCount int
+}
+
+// NewLineBreakIterator returns an iterator over the line break
+// opportunities in `text`.
+func NewLineBreakIterator(text []rune) *LineBreakIterator {
+	return &LineBreakIterator{text: text}
+}
+
+// LineBreaks returns all the line break opportunities in `text`.
+// The end of the text is always a (mandatory) break, so that the
+// returned slice is empty only for empty input.
+func LineBreaks(text []rune) []LineBreak {
+	var out []LineBreak
+	it := NewLineBreakIterator(text)
+	for it.Next() {
+		out = append(out, it.Break())
+	}
+	return out
+}
+
+// Break returns the current break opportunity, valid after a call
+// to `Next` returning true.
+func (it *LineBreakIterator) Break() LineBreak { return it.current }
+
+// Next advances to the next break opportunity and returns
+// false when the end of the text is reached.
+func (it *LineBreakIterator) Next() bool {
+	for it.pos < len(it.text) {
+		r := it.text[it.pos]
+		class := lookupLineClass(r)
+		isBreak, isMandatory := false, false
+		if it.pos != 0 { // LB2: never break at the start of text
+			isBreak, isMandatory = it.isBreak(r, class)
+		}
+		it.update(r, class, isMandatory)
+
+		it.pos++
+		it.byteOffset += utf8.RuneLen(r)
+
+		if isBreak {
+			it.current = LineBreak{
+				Offset:      it.pos - 1,
+				ByteOffset:  it.byteOffset - utf8.RuneLen(r),
+				IsMandatory: isMandatory,
+			}
+			return true
+		}
+	}
+
+	if it.pos == len(it.text) && len(it.text) != 0 { // LB3: always break at the end of text
+		it.pos++ // mark the end as processed
+		it.current = LineBreak{Offset: len(it.text), ByteOffset: it.byteOffset, IsMandatory: true}
+		return true
+	}
+	return false
+}
+
+// isBreak applies the rules to the boundary before the rune `r`,
+// with class `class`, at index `it.pos`.
+func (it *LineBreakIterator) isBreak(r rune, class lineClass) (isBreak, isMandatory bool) {
+	// LB4, LB5: hard line breaks
+	switch it.rawPrev {
+	case lbBK, lbLF, lbNL:
+		return true, true
+	case lbCR:
+		if class != lbLF {
+			return true, true
+		}
+	}
+
+	switch class {
+	case lbBK, lbCR, lbLF, lbNL: // LB6
+		return false, false
+	case lbSP, lbZW: // LB7
+		return false, false
+	}
+
+	if it.rawPrevNonSpace == lbZW { // LB8: ZW SP* ÷
+		return true, false
+	}
+
+	if it.rawPrev == lbZWJ { // LB8a
+		return false, false
+	}
+
+	if class == lbCM || class == lbZWJ {
+		switch it.rawPrev {
+		case lbBK, lbCR, lbLF, lbNL, lbSP, lbZW:
+			class = lbAL // LB10
+		default:
+			return false, false // LB9
+		}
+	}
+
+	spaces := it.rawPrev == lbSP
+
+	if !it.hasBefore { // only spaces since the start of the text
+		switch class {
+		case lbWJ, lbCL, lbCP, lbEX, lbIS, lbSY: // LB11, LB13
+			return false, false
+		}
+		return true, false // LB18
+	}
+
+	switch pairTable[it.before][class] {
+	case pb:
+		return false, false
+	case ib:
+		if spaces {
+			return true, false
+		}
+		return false, false
+	}
+
+	// direct break, unless one of the contextual rules applies;
+	// all of them require adjacent characters
+	if spaces {
+		return true, false
+	}
+
+	// LB21a: HL (HY | BA) ×, which does not override LB20
+	if (it.before == lbHY || it.before == lbBA) && it.hasBeforeBefore && it.beforeBefore == lbHL && class != lbCB {
+		return false, false
+	}
+
+	// LB25 (tailored)
+	switch {
+	case (it.before == lbPR || it.before == lbPO) && (class == lbOP || class == lbHY):
+		// (PR | PO) × (OP | HY) NU
+		if it.pos+1 < len(it.text) && lookupLineClass(it.text[it.pos+1]) == lbNU {
+			return false, false
+		}
+	case it.numberState == 1 && (class == lbNU || class == lbSY || class == lbIS || class == lbCL || class == lbCP):
+		// NU (NU | SY | IS)* × (NU | SY | IS | CL | CP)
+		return false, false
+	case it.numberState != 0 && (class == lbPO || class == lbPR):
+		// NU (NU | SY | IS)* (CL | CP)? × (PO | PR)
+		return false, false
+	}
+
+	// LB30
+	if (it.before == lbAL || it.before == lbHL || it.before == lbNU) && class == lbOP && !unicode.Is(ucd.LargeEastAsian, r) {
+		return false, false
+	}
+	if it.before == lbCP && !unicode.Is(ucd.LargeEastAsian, it.beforeRune) && (class == lbAL || class == lbHL || class == lbNU) {
+		return false, false
+	}
+
+	// LB30a: break between pairs of regional indicators
+	if it.before == lbRI && class == lbRI && it.riCount%2 == 1 {
+		return false, false
+	}
+
+	// LB30b: [\p{Extended_Pictographic}&\p{Cn}] × EM
+	if class == lbEM && unicode.Is(ucd.Extended_Pictographic, it.beforeRune) && isUnassigned(it.beforeRune) {
+		return false, false
+	}
+
+	return true, false // LB31
+}
+
+// update the state of the iterator after processing `r`
+func (it *LineBreakIterator) update(r rune, class lineClass, afterMandatoryBreak bool) {
+	rawPrev := it.rawPrev
+	if it.pos == 0 {
+		rawPrev = lbBK // so that LB9 does not apply
+	}
+	if afterMandatoryBreak {
+		it.hasBefore = false
+	}
+
+	it.rawPrev = class
+	if class != lbSP {
+		it.rawPrevNonSpace = class
+	}
+
+	switch class {
+	case lbSP:
+		return
+	case lbBK, lbCR, lbLF, lbNL, lbZW:
+		it.hasBefore, it.hasBeforeBefore = false, false
+		it.numberState, it.riCount = 0, 0
+		return
+	case lbCM, lbZWJ:
+		switch rawPrev {
+		case lbBK, lbCR, lbLF, lbNL, lbSP, lbZW:
+			class = lbAL // LB10
+		default:
+			return // LB9: the previous class is kept
+		}
+	}
+
+	spaces := rawPrev == lbSP
+	hasBefore := it.hasBefore && !spaces
+
+	// LB25 state
+	switch {
+	case class == lbNU:
+		it.numberState = 1
+	case hasBefore && it.numberState == 1 && (class == lbSY || class == lbIS):
+		// stay in the number
+	case hasBefore && it.numberState == 1 && (class == lbCL || class == lbCP):
+		it.numberState = 2
+	default:
+		it.numberState = 0
+	}
+
+	// LB30a state
+	if class == lbRI && hasBefore && it.before == lbRI {
+		it.riCount++
+	} else if class == lbRI {
+		it.riCount = 1
+	} else {
+		it.riCount = 0
+	}
+
+	it.beforeBefore, it.hasBeforeBefore = it.before, hasBefore
+	it.before, it.beforeRune, it.hasBefore = class, r, true
+}
+
+// isUnassigned returns true for runes with General_Category Cn
+func isUnassigned(r rune) bool {
+	return !unicode.In(r, unicode.L, unicode.M, unicode.N, unicode.P, unicode.S, unicode.Z, unicode.C)
+}
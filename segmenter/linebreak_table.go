@@ -0,0 +1,46 @@
+package segmenter
+
+// pairTable stores, for each pair of (resolved) line break classes
+// (before, after), the break action to apply.
+// It implements the pair-wise rules LB11 to LB31 of UAX #14 (version 13.0),
+// as explained in section 7 ("Pair Table-Based Implementation").
+// The CM and ZWJ entries are only given for completeness, since these
+// classes are resolved by rules LB9 and LB10 before using the table.
+// The contextual rules (LB21a, LB25, LB30, LB30a, LB30b) are handled
+// by the line break iterator.
+var pairTable = [numPairClasses][numPairClasses]breakAction{
+	//   OP  CL  CP  QU  GL  NS  EX  SY  IS  PR  PO  NU  AL  HL  ID  IN  HY  BA  BB  B2  ZW  CM  WJ  H2  H3  JL  JV  JT  RI  EB  EM  ZWJ CB
+	{pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb, pb}, // OP
+	{db, pb, pb, ib, ib, pb, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // CL
+	{db, pb, pb, ib, ib, pb, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // CP
+	{pb, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib}, // QU
+	{ib, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib}, // GL
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // NS
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // EX
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, ib, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // SY
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // IS
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, ib, ib, ib, ib, ib, ib, ib, db, db, db, ib, pb, ib, ib, ib, ib, ib, db, ib, ib, ib, db}, // PR
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, ib, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // PO
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, ib, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // NU
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // AL
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // HL
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // ID
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // IN
+	{db, pb, pb, ib, db, ib, pb, pb, pb, db, db, ib, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // HY
+	{db, pb, pb, ib, db, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // BA
+	{ib, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, db}, // BB
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, pb, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // B2
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // ZW
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // CM
+	{ib, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib, pb, ib, ib, ib, ib, ib, ib, ib, ib, ib, ib}, // WJ
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, ib, ib, db, db, db, db, db}, // H2
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, ib, db, db, db, db, db}, // H3
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, ib, ib, ib, ib, db, db, db, db, db, db}, // JL
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, ib, ib, db, db, db, db, db}, // JV
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, ib, db, db, db, db, db}, // JT
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, db, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // RI
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, ib, db, db}, // EB
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, db, ib, db, db, db, db, ib, ib, ib, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // EM
+	{db, pb, pb, ib, ib, ib, pb, pb, pb, ib, ib, ib, ib, ib, db, ib, ib, ib, db, db, db, ib, pb, db, db, db, db, db, db, db, db, ib, db}, // ZWJ
+	{db, pb, pb, ib, ib, db, pb, pb, pb, db, db, db, db, db, db, db, db, db, db, db, db, db, pb, db, db, db, db, db, db, db, db, db, db}, // CB
+}
@@ -0,0 +1,147 @@
+package segmenter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The official conformance files (LineBreakTest.txt, and the ones in the auxiliary directory)
+// are not part of the repository: they must be downloaded from
+// https://www.unicode.org/Public/13.0.0/ucd/auxiliary/ and stored in the testdata directory
+// (see .github/workflows/test.yml). Without them, the conformance tests are skipped,
+// and only the (small) samples, using the same format, are checked.
+
+const lineBreakTestSample = `
+# sample tests, with the format of LineBreakTest.txt
+× 0041 × 0020 ÷ 0042 ÷	# A SP B
+× 0041 × 000D × 000A ÷ 0042 ÷	# CR LF is a single mandatory break
+× 0041 × 000D ÷ 0042 ÷	# CR alone
+× 0061 × 002D ÷ 0062 ÷	# break after hyphen
+× 0020 × 0020 ÷ 0061 ÷	# leading spaces
+× 0020 × 0029 ÷	# LB13 applies after spaces
+× 0028 × 0020 × 0061 ÷	# LB14
+× 0022 × 0020 × 0028 ÷	# LB15
+× 0029 × 0020 × 3005 ÷	# LB16
+× 2014 × 0020 × 2014 ÷	# LB17
+× 200B ÷ 0061 ÷	# LB8
+× 200B × 0020 ÷ 0061 ÷	# LB8
+× 0061 × 0308 × 0062 ÷	# LB9
+× 0020 ÷ 0308 ÷	# LB10
+× 0061 × 200D × 4E00 ÷	# LB8a
+× 05D0 × 002D × 05D1 ÷	# LB21a
+× 0024 × 0028 × 0031 × 0032 × 002E × 0035 × 0029 × 0025 ÷	# LB25, tailored
+× 0031 × 002F × 0032 ÷	# LB25, tailored
+× 0061 × 0028 × 0062 × 0029 ÷	# LB30
+× 0061 ÷ 3008 × 0062 × 3009 ÷	# LB30 does not apply to wide brackets
+× 1F1EB × 1F1F7 ÷ 1F1EB × 1F1F7 ÷ 1F1EB ÷	# LB30a
+× 1F44D × 1F3FD × 0020 ÷	# LB30b
+× 4E00 ÷ 4E01 × 3002 ÷ 4E02 ÷	# ideographs
+× 0061 × 0062 × 00A0 × 0063 ÷	# LB12
+`
+
+// openTestFile opens the given conformance file, skipping the test
+// if it has not been downloaded
+func openTestFile(t *testing.T, name string) io.Reader {
+	f, err := os.Open("testdata/" + name)
+	if os.IsNotExist(err) {
+		t.Skipf("SKIPPED: testdata/%s is missing, download it from https://www.unicode.org/Public/13.0.0/ucd/auxiliary/%s", name, name)
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+type breakTest struct {
+	text   []rune
+	breaks []int // rune offsets of the boundaries, excluding the start of text
+}
+
+// parseBreakTests parses the format shared by the conformance files
+// LineBreakTest.txt, GraphemeBreakTest.txt, WordBreakTest.txt and SentenceBreakTest.txt
+func parseBreakTests(r io.Reader) ([]breakTest, error) {
+	var out []breakTest
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		var test breakTest
+		for i, field := range fields {
+			if i%2 == 0 { // boundary marker
+				if field != "÷" && field != "×" {
+					return nil, fmt.Errorf("line %d: invalid boundary %s", lineNumber, field)
+				}
+				if field == "÷" && i != 0 {
+					test.breaks = append(test.breaks, len(test.text))
+				}
+				continue
+			}
+			r, err := strconv.ParseUint(field, 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", lineNumber, err)
+			}
+			test.text = append(test.text, rune(r))
+		}
+		out = append(out, test)
+	}
+	return out, scanner.Err()
+}
+
+func TestLineBreakTest(t *testing.T) {
+	testLineBreaks(t, openTestFile(t, "LineBreakTest.txt"))
+}
+
+func TestLineBreakTestSample(t *testing.T) {
+	testLineBreaks(t, strings.NewReader(lineBreakTestSample))
+}
+
+// testLineBreaks checks the content of `input`, using the format of LineBreakTest.txt
+func testLineBreaks(t *testing.T, input io.Reader) {
+	tests, err := parseBreakTests(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		var got []int
+		for _, br := range LineBreaks(test.text) {
+			got = append(got, br.Offset)
+		}
+		if !reflect.DeepEqual(got, test.breaks) {
+			t.Errorf("for %q (%U): expected %v, got %v", string(test.text), test.text, test.breaks, got)
+		}
+	}
+	t.Logf("%d tests", len(tests))
+}
+
+func TestLineBreakOffsets(t *testing.T) {
+	text := []rune("Été\r\nà l'ouest")
+	expected := []LineBreak{
+		{Offset: 5, ByteOffset: 7, IsMandatory: true},
+		{Offset: 7, ByteOffset: 10},
+		{Offset: 14, ByteOffset: 17, IsMandatory: true},
+	}
+	got := LineBreaks(text)
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected %v, got %v", expected, got)
+	}
+	for _, br := range got {
+		if b := len(string(text[:br.Offset])); b != br.ByteOffset {
+			t.Fatalf("invalid byte offset %d (expected %d)", br.ByteOffset, b)
+		}
+	}
+	if LineBreaks(nil) != nil {
+		t.Fatal("expected no break for empty text")
+	}
+}
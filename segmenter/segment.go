@@ -0,0 +1,444 @@
+package segmenter
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	ucd "github.com/benoitkugler/textlayout/unicodedata"
+)
+
+// This file implements the text segmentation algorithms
+// defined in UAX #29 (https://www.unicode.org/reports/tr29/),
+// for extended grapheme clusters, words and sentences.
+
+// Segment is a part of the input text, between two boundaries.
+type Segment struct {
+	// Text is the content of the segment, sharing
+	// memory with the input text.
+	Text []rune
+	// Offset is the index of the first rune of the segment,
+	// in the input text.
+	Offset int
+	// ByteOffset is the same as `Offset`, in the UTF-8 encoded text.
+	ByteOffset int
+}
+
+// SegmentIterator iterates over the segments of a text.
+// See `NewGraphemeIterator`, `NewWordIterator` and `NewSentenceIterator`.
+type SegmentIterator struct {
+	text       []rune
+	isBoundary func(text []rune, i int) bool
+
+	current    Segment
+	pos        int // start of the next segment
+	byteOffset int // byte offset of `pos`
+}
+
+// NewGraphemeIterator returns an iterator over the extended
+// grapheme clusters of `text`.
+func NewGraphemeIterator(text []rune) *SegmentIterator {
+	return &SegmentIterator{text: text, isBoundary: newGraphemeBoundaries(text)}
+}
+
+// NewWordIterator returns an iterator over the segments of `text`
+// delimited by word boundaries.
+// Note that, as defined in UAX #29, the spaces and punctuation
+// between words are also returned as segments.
+func NewWordIterator(text []rune) *SegmentIterator {
+	return &SegmentIterator{text: text, isBoundary: newWordBoundaries(text)}
+}
+
+// NewSentenceIterator returns an iterator over the sentences of `text`.
+func NewSentenceIterator(text []rune) *SegmentIterator {
+	return &SegmentIterator{text: text, isBoundary: newSentenceBoundaries(text)}
+}
+
+// Next advances to the next segment and returns
+// false when the end of the text is reached.
+func (it *SegmentIterator) Next() bool {
+	if it.pos >= len(it.text) {
+		return false
+	}
+	start, byteStart := it.pos, it.byteOffset
+	for {
+		it.byteOffset += utf8.RuneLen(it.text[it.pos])
+		it.pos++
+		if it.pos == len(it.text) || it.isBoundary(it.text, it.pos) {
+			break
+		}
+	}
+	it.current = Segment{Text: it.text[start:it.pos], Offset: start, ByteOffset: byteStart}
+	return true
+}
+
+// Segment returns the current segment, valid after a call
+// to `Next` returning true.
+func (it *SegmentIterator) Segment() Segment { return it.current }
+
+// lookupClasses resolves the classes of each rune, as indices into `tables`,
+// using -1 for runes without class.
+func lookupClasses(text []rune, lookup func(rune) *unicode.RangeTable, tables []*unicode.RangeTable) []int {
+	out := make([]int, len(text))
+	for i, r := range text {
+		out[i] = -1
+		table := lookup(r)
+		if table == nil {
+			continue
+		}
+		for j, t := range tables {
+			if t == table {
+				out[i] = j
+				break
+			}
+		}
+	}
+	return out
+}
+
+// ------------------------------ Grapheme clusters ------------------------------
+
+const (
+	gbOther = -1 + iota
+	gbCR
+	gbLF
+	gbControl
+	gbExtend
+	gbZWJ
+	gbRegionalIndicator
+	gbPrepend
+	gbSpacingMark
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+)
+
+var graphemeTables = []*unicode.RangeTable{
+	ucd.GraphemeBreakCR, ucd.GraphemeBreakLF, ucd.GraphemeBreakControl,
+	ucd.GraphemeBreakExtend, ucd.GraphemeBreakZWJ, ucd.GraphemeBreakRegional_Indicator,
+	ucd.GraphemeBreakPrepend, ucd.GraphemeBreakSpacingMark, ucd.GraphemeBreakL,
+	ucd.GraphemeBreakV, ucd.GraphemeBreakT, ucd.GraphemeBreakLV, ucd.GraphemeBreakLVT,
+}
+
+func newGraphemeBoundaries(text []rune) func(text []rune, i int) bool {
+	classes := lookupClasses(text, ucd.LookupGraphemeBreakClass, graphemeTables)
+	return func(text []rune, i int) bool { return isGraphemeBoundary(text, classes, i) }
+}
+
+// isGraphemeBoundary returns true if there is a boundary between
+// text[i-1] and text[i], with 0 < i < len(text)
+func isGraphemeBoundary(text []rune, classes []int, i int) bool {
+	prev, cur := classes[i-1], classes[i]
+	switch {
+	case prev == gbCR && cur == gbLF: // GB3
+		return false
+	case prev == gbControl || prev == gbCR || prev == gbLF: // GB4
+		return true
+	case cur == gbControl || cur == gbCR || cur == gbLF: // GB5
+		return true
+	case prev == gbL && (cur == gbL || cur == gbV || cur == gbLV || cur == gbLVT): // GB6
+		return false
+	case (prev == gbLV || prev == gbV) && (cur == gbV || cur == gbT): // GB7
+		return false
+	case (prev == gbLVT || prev == gbT) && cur == gbT: // GB8
+		return false
+	case cur == gbExtend || cur == gbZWJ: // GB9
+		return false
+	case cur == gbSpacingMark: // GB9a
+		return false
+	case prev == gbPrepend: // GB9b
+		return false
+	case prev == gbZWJ && unicode.Is(ucd.Extended_Pictographic, text[i]): // GB11
+		j := i - 2
+		for j >= 0 && classes[j] == gbExtend {
+			j--
+		}
+		if j >= 0 && unicode.Is(ucd.Extended_Pictographic, text[j]) {
+			return false
+		}
+	case prev == gbRegionalIndicator && cur == gbRegionalIndicator: // GB12, GB13
+		count := 0
+		for j := i - 1; j >= 0 && classes[j] == gbRegionalIndicator; j-- {
+			count++
+		}
+		return count%2 == 0
+	}
+	return true // GB999
+}
+
+// ------------------------------ Words ------------------------------
+
+const (
+	wbOther = -1 + iota
+	wbCR
+	wbLF
+	wbNewline
+	wbExtend
+	wbZWJ
+	wbRegionalIndicator
+	wbFormat
+	wbKatakana
+	wbHebrewLetter
+	wbALetter
+	wbSingleQuote
+	wbDoubleQuote
+	wbMidNumLet
+	wbMidLetter
+	wbMidNum
+	wbNumeric
+	wbExtendNumLet
+	wbWSegSpace
+)
+
+var wordTables = []*unicode.RangeTable{
+	ucd.WordBreakCR, ucd.WordBreakLF, ucd.WordBreakNewline, ucd.WordBreakExtend,
+	ucd.WordBreakZWJ, ucd.WordBreakRegional_Indicator, ucd.WordBreakFormat,
+	ucd.WordBreakKatakana, ucd.WordBreakHebrew_Letter, ucd.WordBreakALetter,
+	ucd.WordBreakSingle_Quote, ucd.WordBreakDouble_Quote, ucd.WordBreakMidNumLet,
+	ucd.WordBreakMidLetter, ucd.WordBreakMidNum, ucd.WordBreakNumeric,
+	ucd.WordBreakExtendNumLet, ucd.WordBreakWSegSpace,
+}
+
+func newWordBoundaries(text []rune) func(text []rune, i int) bool {
+	classes := lookupClasses(text, ucd.LookupWordBreakClass, wordTables)
+	return func(text []rune, i int) bool { return isWordBoundary(text, classes, i) }
+}
+
+func isAHLetter(c int) bool { return c == wbALetter || c == wbHebrewLetter }
+
+func isMidNumLetQ(c int) bool { return c == wbMidNumLet || c == wbSingleQuote }
+
+// isWordIgnored returns true for the classes skipped by rule WB4
+func isWordIgnored(c int) bool { return c == wbExtend || c == wbFormat || c == wbZWJ }
+
+// wordClassBefore returns the class of the character before index `i`, ignoring
+// the characters matched by rule WB4, and its index, or -1.
+func wordClassBefore(classes []int, i int) (int, int) {
+	j := i - 1
+	for j >= 0 && isWordIgnored(classes[j]) {
+		j--
+	}
+	if j < 0 {
+		return wbOther, -1
+	}
+	if c := classes[j]; c == wbCR || c == wbLF || c == wbNewline {
+		// WB4 does not apply after these classes
+		if j == i-1 {
+			return c, j
+		}
+		return classes[i-1], i - 1
+	}
+	return classes[j], j
+}
+
+// wordClassAfter returns the class of the character after index `i`,
+// ignoring the characters matched by rule WB4.
+func wordClassAfter(classes []int, i int) int {
+	j := i + 1
+	for j < len(classes) && isWordIgnored(classes[j]) {
+		j++
+	}
+	if j == len(classes) {
+		return wbOther
+	}
+	return classes[j]
+}
+
+// isWordBoundary returns true if there is a boundary between
+// text[i-1] and text[i], with 0 < i < len(text)
+func isWordBoundary(text []rune, classes []int, i int) bool {
+	prev, cur := classes[i-1], classes[i]
+	switch {
+	case prev == wbCR && cur == wbLF: // WB3
+		return false
+	case prev == wbNewline || prev == wbCR || prev == wbLF: // WB3a
+		return true
+	case cur == wbNewline || cur == wbCR || cur == wbLF: // WB3b
+		return true
+	case prev == wbZWJ && unicode.Is(ucd.Extended_Pictographic, text[i]): // WB3c
+		return false
+	case prev == wbWSegSpace && cur == wbWSegSpace: // WB3d
+		return false
+	case isWordIgnored(cur): // WB4
+		return false
+	}
+
+	prev, prevIndex := wordClassBefore(classes, i)
+	switch {
+	case isAHLetter(prev) && isAHLetter(cur): // WB5
+		return false
+	case isAHLetter(prev) && (cur == wbMidLetter || isMidNumLetQ(cur)) && isAHLetter(wordClassAfter(classes, i)): // WB6
+		return false
+	case prev == wbHebrewLetter && cur == wbSingleQuote: // WB7a
+		return false
+	case prev == wbHebrewLetter && cur == wbDoubleQuote && wordClassAfter(classes, i) == wbHebrewLetter: // WB7b
+		return false
+	case prev == wbNumeric && cur == wbNumeric: // WB8
+		return false
+	case isAHLetter(prev) && cur == wbNumeric: // WB9
+		return false
+	case prev == wbNumeric && isAHLetter(cur): // WB10
+		return false
+	case prev == wbNumeric && (cur == wbMidNum || isMidNumLetQ(cur)) && wordClassAfter(classes, i) == wbNumeric: // WB12
+		return false
+	case prev == wbKatakana && cur == wbKatakana: // WB13
+		return false
+	case (isAHLetter(prev) || prev == wbNumeric || prev == wbKatakana || prev == wbExtendNumLet) && cur == wbExtendNumLet: // WB13a
+		return false
+	case prev == wbExtendNumLet && (isAHLetter(cur) || cur == wbNumeric || cur == wbKatakana): // WB13b
+		return false
+	}
+
+	if prevIndex > 0 {
+		prevPrev, _ := wordClassBefore(classes, prevIndex)
+		switch {
+		case isAHLetter(prevPrev) && (prev == wbMidLetter || isMidNumLetQ(prev)) && isAHLetter(cur): // WB7
+			return false
+		case prevPrev == wbHebrewLetter && prev == wbDoubleQuote && cur == wbHebrewLetter: // WB7c
+			return false
+		case prevPrev == wbNumeric && (prev == wbMidNum || isMidNumLetQ(prev)) && cur == wbNumeric: // WB11
+			return false
+		}
+	}
+
+	if prev == wbRegionalIndicator && cur == wbRegionalIndicator { // WB15, WB16
+		count := 0
+		for j := prevIndex; j >= 0; {
+			if classes[j] != wbRegionalIndicator {
+				break
+			}
+			count++
+			_, j = wordClassBefore(classes, j)
+		}
+		return count%2 == 0
+	}
+
+	return true // WB999
+}
+
+// ------------------------------ Sentences ------------------------------
+
+const (
+	sbOther = -1 + iota
+	sbCR
+	sbLF
+	sbSep
+	sbExtend
+	sbFormat
+	sbSp
+	sbLower
+	sbUpper
+	sbOLetter
+	sbNumeric
+	sbATerm
+	sbSContinue
+	sbSTerm
+	sbClose
+)
+
+var sentenceTables = []*unicode.RangeTable{
+	ucd.SentenceBreakCR, ucd.SentenceBreakLF, ucd.SentenceBreakSep, ucd.SentenceBreakExtend,
+	ucd.SentenceBreakFormat, ucd.SentenceBreakSp, ucd.SentenceBreakLower, ucd.SentenceBreakUpper,
+	ucd.SentenceBreakOLetter, ucd.SentenceBreakNumeric, ucd.SentenceBreakATerm,
+	ucd.SentenceBreakSContinue, ucd.SentenceBreakSTerm, ucd.SentenceBreakClose,
+}
+
+func newSentenceBoundaries(text []rune) func(text []rune, i int) bool {
+	classes := lookupClasses(text, ucd.LookupSentenceBreakClass, sentenceTables)
+	return func(text []rune, i int) bool { return isSentenceBoundary(classes, i) }
+}
+
+func isParaSep(c int) bool { return c == sbSep || c == sbCR || c == sbLF }
+
+func isSATerm(c int) bool { return c == sbSTerm || c == sbATerm }
+
+// sentenceClassBefore returns the class of the character before index `i`, ignoring
+// the characters matched by rule SB5, and its index, or -1.
+func sentenceClassBefore(classes []int, i int) (int, int) {
+	j := i - 1
+	for j >= 0 && (classes[j] == sbExtend || classes[j] == sbFormat) {
+		j--
+	}
+	if j < 0 {
+		return sbOther, -1
+	}
+	if isParaSep(classes[j]) && j != i-1 { // SB5 does not apply after ParaSep
+		return classes[i-1], i - 1
+	}
+	return classes[j], j
+}
+
+// sentenceTerm looks backward from the index `j` (included) for
+// a sequence SATerm Close* Sp*, and returns the class of the terminator (or sbOther),
+// and whether spaces and closing punctuation were found.
+func sentenceTerm(classes []int, j int) (term int, hasClose, hasSp bool) {
+	c := sbOther
+	if j >= 0 {
+		c = classes[j]
+	}
+	for c == sbSp {
+		hasSp = true
+		c, j = sentenceClassBefore(classes, j)
+	}
+	for c == sbClose {
+		hasClose = true
+		c, j = sentenceClassBefore(classes, j)
+	}
+	if isSATerm(c) {
+		return c, hasClose, hasSp
+	}
+	return sbOther, hasClose, hasSp
+}
+
+// isSentenceBoundary returns true if there is a boundary between
+// text[i-1] and text[i], with 0 < i < len(text)
+func isSentenceBoundary(classes []int, i int) bool {
+	prev, cur := classes[i-1], classes[i]
+	switch {
+	case prev == sbCR && cur == sbLF: // SB3
+		return false
+	case isParaSep(prev): // SB4
+		return true
+	case cur == sbExtend || cur == sbFormat: // SB5
+		return false
+	}
+
+	prev, prevIndex := sentenceClassBefore(classes, i)
+
+	if prev == sbATerm && cur == sbNumeric { // SB6
+		return false
+	}
+	if prev == sbATerm && cur == sbUpper && prevIndex > 0 { // SB7
+		if prevPrev, _ := sentenceClassBefore(classes, prevIndex); prevPrev == sbUpper || prevPrev == sbLower {
+			return false
+		}
+	}
+
+	term, _, hasSp := sentenceTerm(classes, prevIndex)
+	if term == sbATerm { // SB8
+		for j := i; j < len(classes); j++ {
+			c := classes[j]
+			if c == sbLower {
+				return false
+			}
+			if c == sbOLetter || c == sbUpper || isParaSep(c) || isSATerm(c) {
+				break
+			}
+		}
+	}
+	if term != sbOther {
+		if cur == sbSContinue || isSATerm(cur) { // SB8a
+			return false
+		}
+		if !hasSp && (cur == sbClose || cur == sbSp || isParaSep(cur)) { // SB9
+			return false
+		}
+		if cur == sbSp || isParaSep(cur) { // SB10
+			return false
+		}
+		return true // SB11
+	}
+
+	return false // SB998
+}
@@ -0,0 +1,126 @@
+package segmenter
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const graphemeBreakTestSample = `
+# sample tests, with the format of GraphemeBreakTest.txt
+÷ 0020 ÷ 0020 ÷	# Other Other
+÷ 000D × 000A ÷ 0061 ÷	# GB3, GB4
+÷ 0061 × 0308 ÷ 0062 ÷	# GB9
+÷ 0061 × 0903 ÷	# GB9a
+÷ 0600 × 0061 ÷	# GB9b
+÷ 1100 × 1161 × 11A8 ÷ 1100 ÷	# Hangul
+÷ AC00 × 11A8 ÷ AC01 ÷	# Hangul
+÷ 1F468 × 200D × 1F469 × 200D × 1F467 ÷	# GB11
+÷ 0061 × 200D ÷ 1F469 ÷	# GB11 requires a pictographic base
+÷ 1F1EB × 1F1F7 ÷ 1F1EB × 1F1F7 ÷ 1F1EB ÷	# GB12, GB13
+÷ 0061 ÷ 1F1EB × 1F1F7 ÷ 1F1EB ÷	# GB13
+÷ 0308 ÷ 000A ÷	# GB5
+`
+
+const wordBreakTestSample = `
+# sample tests, with the format of WordBreakTest.txt
+÷ 0048 × 0065 × 006C × 006C × 006F ÷ 002C ÷ 0020 ÷ 0077 × 006F ÷	# Hello, wo
+÷ 0063 × 0061 × 006E × 0027 × 0074 ÷	# WB6, WB7
+÷ 0063 × 0061 × 006E ÷ 0027 ÷	# no letter after the quote
+÷ 0033 × 002E × 0031 × 0034 ÷	# WB11, WB12
+÷ 0031 × 002C × 0030 × 0030 × 0030 ÷ 002C ÷	# WB12
+÷ 0061 × 0031 × 0062 ÷	# WB9, WB10
+÷ 30AB × 30BF × 30AB × 30CA ÷ 3042 ÷ 3044 ÷	# WB13
+÷ 0061 × 005F × 0062 ÷	# WB13a, WB13b
+÷ 05D0 × 0027 ÷	# WB7a
+÷ 05D0 × 0022 × 05D1 ÷	# WB7b, WB7c
+÷ 0020 × 0020 ÷ 0061 ÷	# WB3d
+÷ 0061 × 0308 × 0062 ÷	# WB4
+÷ 0061 × 00AD × 0062 ÷	# WB4
+÷ 000D × 000A ÷ 0308 ÷	# WB3, WB3a
+÷ 0061 × 200D × 2764 ÷	# WB3c
+÷ 1F1EB × 1F1F7 ÷ 1F1EB ÷	# WB15
+`
+
+const sentenceBreakTestSample = `
+# sample tests, with the format of SentenceBreakTest.txt
+÷ 0048 × 0069 × 002E × 0020 ÷ 0059 × 006F ÷	# Hi. Yo
+÷ 0065 × 0074 × 0063 × 002E × 0020 × 0074 × 0068 × 0065 ÷	# SB8: etc. the
+÷ 0055 × 002E × 0053 × 002E × 0041 ÷	# SB7
+÷ 0033 × 002E × 0034 ÷	# SB6
+÷ 0061 × 0021 × 0029 × 0020 × 0020 ÷ 0042 ÷	# SB9, SB10, SB11
+÷ 0061 × 002E × 0022 × 000A ÷ 0062 ÷	# SB9, SB4
+÷ 0061 × 000D × 000A ÷ 0062 ÷	# SB3, SB4
+÷ 0061 × 003F × 002C × 0020 × 0062 ÷	# SB8a
+÷ 0061 × 0020 × 0062 ÷	# SB998
+`
+
+// testSegmentation checks the content of `input`, using the format of the
+// GraphemeBreakTest.txt, WordBreakTest.txt and SentenceBreakTest.txt files
+func testSegmentation(t *testing.T, input io.Reader, newIterator func([]rune) *SegmentIterator) {
+	tests, err := parseBreakTests(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		var got []int
+		it := newIterator(test.text)
+		for it.Next() {
+			seg := it.Segment()
+			got = append(got, seg.Offset+len(seg.Text))
+		}
+		if !reflect.DeepEqual(got, test.breaks) {
+			t.Errorf("for %q (%U): expected %v, got %v", string(test.text), test.text, test.breaks, got)
+		}
+	}
+	t.Logf("%d tests", len(tests))
+}
+
+func TestGraphemeBreakTest(t *testing.T) {
+	testSegmentation(t, openTestFile(t, "GraphemeBreakTest.txt"), NewGraphemeIterator)
+}
+
+func TestGraphemeBreakTestSample(t *testing.T) {
+	testSegmentation(t, strings.NewReader(graphemeBreakTestSample), NewGraphemeIterator)
+}
+
+func TestWordBreakTest(t *testing.T) {
+	testSegmentation(t, openTestFile(t, "WordBreakTest.txt"), NewWordIterator)
+}
+
+func TestWordBreakTestSample(t *testing.T) {
+	testSegmentation(t, strings.NewReader(wordBreakTestSample), NewWordIterator)
+}
+
+func TestSentenceBreakTest(t *testing.T) {
+	testSegmentation(t, openTestFile(t, "SentenceBreakTest.txt"), NewSentenceIterator)
+}
+
+func TestSentenceBreakTestSample(t *testing.T) {
+	testSegmentation(t, strings.NewReader(sentenceBreakTestSample), NewSentenceIterator)
+}
+
+func TestSegmentOffsets(t *testing.T) {
+	text := []rune("Été à 5€.")
+	it := NewWordIterator(text)
+	var got []Segment
+	for it.Next() {
+		got = append(got, it.Segment())
+	}
+	if len(got) != 7 {
+		t.Fatalf("unexpected segments %v", got)
+	}
+	for _, seg := range got {
+		if b := len(string(text[:seg.Offset])); b != seg.ByteOffset {
+			t.Fatalf("invalid byte offset %d (expected %d)", seg.ByteOffset, b)
+		}
+	}
+	if last := got[6]; string(last.Text) != "." || last.Offset != 8 || last.ByteOffset != 13 {
+		t.Fatalf("unexpected last segment %v", last)
+	}
+
+	if NewSentenceIterator(nil).Next() {
+		t.Fatal("expected no segment for empty text")
+	}
+}
@@ -26,7 +26,9 @@ const (
 	urlEastAsianWidth = "https://unicode.org/Public/" + version + "/ucd/EastAsianWidth.txt"
 	urlSentenceBreak  = "https://unicode.org/Public/" + version + "/ucd/auxiliary/SentenceBreakProperty.txt"
 	urlGraphemeBreak  = "https://unicode.org/Public/" + version + "/ucd/auxiliary/GraphemeBreakProperty.txt"
+	urlWordBreak      = "https://unicode.org/Public/" + version + "/ucd/auxiliary/WordBreakProperty.txt"
 	urlDerivedCore    = "https://unicode.org/Public/" + version + "/ucd/DerivedCoreProperties.txt"
+	urlScriptExt      = "https://unicode.org/Public/" + version + "/ucd/ScriptExtensions.txt"
 )
 
 func fetchData(url string) {
@@ -37,7 +37,9 @@ func main() {
 		fetchData(urlEastAsianWidth)
 		fetchData(urlSentenceBreak)
 		fetchData(urlGraphemeBreak)
+		fetchData(urlWordBreak)
 		fetchData(urlDerivedCore)
+		fetchData(urlScriptExt)
 	}
 
 	// parse
@@ -122,11 +124,21 @@ func main() {
 	graphemeBreaks, err := parseAnnexTables(b)
 	check(err)
 
+	b, err = ioutil.ReadFile("WordBreakProperty.txt")
+	check(err)
+	wordBreaks, err := parseAnnexTables(b)
+	check(err)
+
 	b, err = ioutil.ReadFile("Scripts.txt")
 	check(err)
 	scriptsRanges, err := parseAnnexTablesAsRanges(b)
 	check(err)
 
+	b, err = ioutil.ReadFile("ScriptExtensions.txt")
+	check(err)
+	scriptExtensions, err := parseAnnexTablesAsRanges(b)
+	check(err)
+
 	b, err = ioutil.ReadFile("Scripts-iso15924.txt")
 	check(err)
 	scriptNames, err := parseScriptNames(b)
@@ -176,13 +188,17 @@ func main() {
 		generateIndicCategories(indicS, w)
 	})
 	process("../sentenceBreak.go", func(w io.Writer) {
-		generateSTermProperty(sentenceBreaks, w)
+		generateSentenceBreakProperty(sentenceBreaks, w)
 	})
 	process("../graphemeBreak.go", func(w io.Writer) {
 		generateGraphemeBreakProperty(graphemeBreaks, w)
 	})
+	process("../wordBreak.go", func(w io.Writer) {
+		generateWordBreakProperty(wordBreaks, w)
+	})
 	process("../../language/scripts_table.go", func(w io.Writer) {
 		generateScriptLookupTable(scriptsRanges, scriptNames, w)
+		generateScriptExtensionsTable(scriptExtensions, scriptNames, w)
 	})
 	fmt.Println("Done.")
 }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"io"
@@ -383,18 +384,24 @@ func generateIndicCategories(datas map[string][]rune, w io.Writer) {
 	}
 }
 
-// only generate the table for the STerm property
-func generateSTermProperty(datas map[string][]rune, w io.Writer) {
-	fmt.Fprint(w, header)
+func generateSentenceBreakProperty(datas map[string][]rune, w io.Writer) {
+	generateBreakProperty(datas, "SentenceBreak", w)
 
-	className := "STerm"
-	table := rangetable.New(datas[className]...)
-	s := printTable(table, false)
-	fmt.Fprintf(w, "// SentenceBreakProperty: STerm\n")
-	fmt.Fprintf(w, "var %s = %s\n\n", className, s)
+	fmt.Fprintf(w, "// STerm is the same as SentenceBreakSTerm\n")
+	fmt.Fprintf(w, "var STerm = SentenceBreakSTerm\n\n")
 }
 
 func generateGraphemeBreakProperty(datas map[string][]rune, w io.Writer) {
+	generateBreakProperty(datas, "GraphemeBreak", w)
+}
+
+func generateWordBreakProperty(datas map[string][]rune, w io.Writer) {
+	generateBreakProperty(datas, "WordBreak", w)
+}
+
+// generateBreakProperty writes one table for each class, named <prefix><class>,
+// a table containing all the classified runes (<prefix>All) and the list of classes.
+func generateBreakProperty(datas map[string][]rune, prefix string, w io.Writer) {
 	fmt.Fprint(w, header)
 
 	var sortedClasses []string
@@ -404,26 +411,28 @@ func generateGraphemeBreakProperty(datas map[string][]rune, w io.Writer) {
 	sort.Strings(sortedClasses)
 
 	list := ""
-	var allGraphemes []*unicode.RangeTable
+	var allTables []*unicode.RangeTable
 	for _, className := range sortedClasses {
 		runes := datas[className]
 		table := rangetable.New(runes...)
 		s := printTable(table, false)
-		fmt.Fprintf(w, "// GraphemeBreakProperty: %s\n", className)
-		fmt.Fprintf(w, "var GraphemeBreak%s = %s\n\n", className, s)
+		fmt.Fprintf(w, "// %sProperty: %s\n", prefix, className)
+		fmt.Fprintf(w, "var %s%s = %s\n\n", prefix, className, s)
 
-		list += fmt.Sprintf("GraphemeBreak%s, // %s \n", className, className)
-		allGraphemes = append(allGraphemes, table)
+		list += fmt.Sprintf("%s%s, // %s \n", prefix, className, className)
+		allTables = append(allTables, table)
 	}
 
+	unexported := strings.ToLower(prefix[:1]) + prefix[1:]
+
 	// generate a union table to speed up lookup
-	allTable := rangetable.Merge(allGraphemes...)
-	fmt.Fprintf(w, "// contains all the runes having a non nil grapheme break property\n")
-	fmt.Fprintf(w, "var graphemeBreakAll = %s\n\n", printTable(allTable, false))
+	allTable := rangetable.Merge(allTables...)
+	fmt.Fprintf(w, "// contains all the runes having a non nil %s property\n", strings.ToLower(strings.TrimSuffix(prefix, "Break"))+" break")
+	fmt.Fprintf(w, "var %sAll = %s\n\n", unexported, printTable(allTable, false))
 
-	fmt.Fprintf(w, `var graphemeBreaks = [...]*unicode.RangeTable{
+	fmt.Fprintf(w, `var %ss = [...]*unicode.RangeTable{
 	%s}
-	`, list)
+	`, unexported, list)
 }
 
 func generateEmojisTest(sequences [][]rune, w io.Writer) {
@@ -534,3 +543,48 @@ func generateScriptLookupTable(scripts map[string][]runeRange, scriptNames map[s
 	}
 	fmt.Fprintln(w, "}")
 }
+
+// scripts are given by their ISO 15924 code, separated by spaces
+func generateScriptExtensionsTable(extensions map[string][]runeRange, scriptNames map[string]uint32, w io.Writer) {
+	knownTags := map[uint32]bool{}
+	for _, tag := range scriptNames {
+		knownTags[tag] = true
+	}
+
+	type extensionItem struct {
+		runeRange
+		scripts []uint32
+	}
+	var items []extensionItem
+	for codes, runes := range extensions {
+		var scripts []uint32
+		for _, code := range strings.Fields(codes) {
+			tag := binary.BigEndian.Uint32([]byte(strings.ToLower(code)))
+			if !knownTags[tag] {
+				check(fmt.Errorf("unknown script code %s", code))
+			}
+			scripts = append(scripts, tag)
+		}
+		for _, ra := range runes {
+			items = append(items, extensionItem{runeRange: ra, scripts: scripts})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Start < items[j].Start })
+
+	fmt.Fprintln(w, `type scriptExtensionsItem struct {
+		start, end rune
+		scripts    []Script
+	}
+
+	// sorted ranges of the runes whose Script_Extensions property
+	// is not the same as their Script property
+	var scriptExtensionsRanges = [...]scriptExtensionsItem{`)
+	for _, item := range items {
+		fmt.Fprintf(w, "{start: 0x%x, end: 0x%x, scripts: []Script{", item.Start, item.End)
+		for _, tag := range item.scripts {
+			fmt.Fprintf(w, "0x%08x,", tag)
+		}
+		fmt.Fprintln(w, "}},")
+	}
+	fmt.Fprintln(w, "}")
+}
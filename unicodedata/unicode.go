@@ -73,6 +73,36 @@ func LookupGraphemeBreakClass(ch rune) *unicode.RangeTable {
 	return nil
 }
 
+// LookupWordBreakClass returns the word break property for the rune (see the constants WordBreakXXX),
+// or nil
+func LookupWordBreakClass(ch rune) *unicode.RangeTable {
+	// a lot of runes do not have a word break property :
+	// avoid testing all the wordBreaks classes for them
+	if !unicode.Is(wordBreakAll, ch) {
+		return nil
+	}
+	for _, class := range wordBreaks {
+		if unicode.Is(class, ch) {
+			return class
+		}
+	}
+	return nil
+}
+
+// LookupSentenceBreakClass returns the sentence break property for the rune (see the constants SentenceBreakXXX),
+// or nil
+func LookupSentenceBreakClass(ch rune) *unicode.RangeTable {
+	if !unicode.Is(sentenceBreakAll, ch) {
+		return nil
+	}
+	for _, class := range sentenceBreaks {
+		if unicode.Is(class, ch) {
+			return class
+		}
+	}
+	return nil
+}
+
 // LookupMirrorChar finds the mirrored equivalent of a character as defined in
 // the file BidiMirroring.txt of the Unicode Character Database available at
 // http://www.unicode.org/Public/UNIDATA/BidiMirroring.txt.
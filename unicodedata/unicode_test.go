@@ -1,6 +1,9 @@
 package unicodedata
 
-import "testing"
+import (
+	"testing"
+	"unicode"
+)
 
 func TestUnicodeNormalization(t *testing.T) {
 	assertCompose := func(a, b rune, okExp bool, abExp rune) {
@@ -83,3 +86,22 @@ func TestBreakClass(t *testing.T) {
 		t.Fatal("invalid break class for 0x2024")
 	}
 }
+
+func TestLookupBreakClasses(t *testing.T) {
+	for r, class := range map[rune]*unicode.RangeTable{
+		'a': WordBreakALetter, '1': WordBreakNumeric, '\'': WordBreakSingle_Quote,
+		'.': WordBreakMidNumLet, ' ': WordBreakWSegSpace, 'カ': WordBreakKatakana, '!': nil,
+	} {
+		if got := LookupWordBreakClass(r); got != class {
+			t.Errorf("invalid word break class for %q", r)
+		}
+	}
+	for r, class := range map[rune]*unicode.RangeTable{
+		'a': SentenceBreakLower, 'A': SentenceBreakUpper, '.': SentenceBreakATerm,
+		'!': SentenceBreakSTerm, ')': SentenceBreakClose, '\n': SentenceBreakLF, '#': nil,
+	} {
+		if got := LookupSentenceBreakClass(r); got != class {
+			t.Errorf("invalid sentence break class for %q", r)
+		}
+	}
+}